@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/complete"
+)
+
+func TestComplete_FlagOperands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "c.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"ab": 1, "abc": 2}`), 0o644))
+
+	for _, flag := range []string{
+		"--to yaml",
+		"--delimiter ';'",
+		"--parallel 2",
+		"--reduce-every 10",
+		"--reduce 0 '(acc, x) => acc'",
+		"--arg name value",
+		"--jq .",
+		"--infer go",
+	} {
+		t.Run(flag, func(t *testing.T) {
+			t.Setenv("COMP_FISH", "fx "+flag+" "+file+" .ab")
+			assert.Equal(t, []string{".ab", ".abc"}, completeOutput(t))
+		})
+	}
+}
+
+// completeOutput runs the shell completion and returns the printed replies.
+func completeOutput(t *testing.T) []string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	ok := complete.Complete()
+	os.Stdout = stdout
+	require.NoError(t, w.Close())
+	require.True(t, ok)
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return strings.Split(string(out), "\n")
+}
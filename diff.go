@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+
+	"github.com/antonmedv/fx/internal/config"
+	"github.com/antonmedv/fx/internal/diff"
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonpath"
+	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/pretty"
+	"github.com/antonmedv/fx/internal/theme"
+	"github.com/antonmedv/fx/internal/toml"
+)
+
+// runDiff implements fx diff a.json b.json. It shows the changes in the viewer, or prints
+// them as a summary or as JSON Patch and returns 1 if the documents differ, like diff(1).
+func runDiff(args []string, cfg *config.Config) int {
+	var files []string
+	var opts diff.Options
+	var jsonPatch, summary bool
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "--id":
+			if i+1 >= len(args) {
+				println("Error: --id requires a field")
+				return 2
+			}
+			i++
+			opts.ID = args[i]
+		case "--json-patch":
+			jsonPatch = true
+		case "--summary":
+			summary = true
+		default:
+			files = append(files, arg)
+		}
+	}
+	if len(files) != 2 {
+		println("Error: fx diff requires two files")
+		return 2
+	}
+	if jsonPatch && summary {
+		println("Error: can't use --json-patch and --summary flags together")
+		return 2
+	}
+
+	a, err := readDocument(files[0])
+	if err != nil {
+		println("Error: " + files[0] + ": " + err.Error())
+		return 2
+	}
+	b, err := readDocument(files[1])
+	if err != nil {
+		println("Error: " + files[1] + ": " + err.Error())
+		return 2
+	}
+
+	fd := os.Stdout.Fd()
+	if !jsonPatch && !summary && (isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)) {
+		docs, marks, err := diff.Merge(a, b, opts)
+		if err != nil {
+			println("Error: " + err.Error())
+			return 2
+		}
+		runViewer(&nodesParser{nodes: docs}, files[0]+" → "+files[1], "json", cfg, marks)
+		return 0
+	}
+
+	changes := diff.Compare(a, b, opts)
+	if jsonPatch {
+		fmt.Println(diffPatch(changes, !flagNoInline))
+	} else {
+		for _, c := range changes {
+			fmt.Println(diffLine(c))
+		}
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readDocument parses the single document of a JSON, YAML, TOML or XML file.
+func readDocument(filePath string) (*Node, error) {
+	var isYaml, isToml, isCsv, isTsv, isXml bool
+	src, _ := open(filePath, false, &isYaml, &isToml, &isCsv, &isTsv, &isXml)
+	if isXml {
+		return single(NewXmlParser(src))
+	}
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case isYaml:
+		b, err = parseYAML(b)
+	case isToml:
+		b, err = toml.ToJSON(b)
+	case isCsv, isTsv:
+		err = fmt.Errorf("can't diff CSV or TSV files")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return single(NewJsonParser(bytes.NewReader(b), flagStrict))
+}
+
+// single returns the only document of parser.
+func single(parser engine.Parser) (*Node, error) {
+	node, err := parser.Parse()
+	if err == io.EOF {
+		return nil, fmt.Errorf("no JSON document")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parser.Parse(); err != io.EOF {
+		return nil, fmt.Errorf("more than one JSON document")
+	}
+	return node, nil
+}
+
+// diffLine prints a change as "+ path: value", "- path: value" or "~ path: old → new".
+func diffLine(c diff.Change) string {
+	path := jsonpath.Join(c.Path)
+	if path == "" {
+		path = "."
+	}
+	switch c.Kind {
+	case diff.Added:
+		return theme.CurrentTheme.Added("+ " + path + ": " + Compact(c.New))
+	case diff.Removed:
+		return theme.CurrentTheme.Removed("- " + path + ": " + Compact(c.Old))
+	default:
+		return theme.CurrentTheme.Changed("~ " + path + ": " + Compact(c.Old) + " → " + Compact(c.New))
+	}
+}
+
+// diffPatch prints changes as JSON Patch operations.
+func diffPatch(changes []diff.Change, withInline bool) string {
+	ops := make([]string, len(changes))
+	for i, c := range changes {
+		path, err := json.Marshal(jsonpath.JoinPointer(c.Path))
+		if err != nil {
+			panic(err)
+		}
+		switch c.Kind {
+		case diff.Added:
+			ops[i] = `{"op":"add","path":` + string(path) + `,"value":` + Compact(c.New) + `}`
+		case diff.Removed:
+			ops[i] = `{"op":"remove","path":` + string(path) + `}`
+		default:
+			ops[i] = `{"op":"replace","path":` + string(path) + `,"value":` + Compact(c.New) + `}`
+		}
+	}
+	node, err := Parse([]byte("[" + strings.Join(ops, ",") + "]"))
+	if err != nil {
+		panic(err)
+	}
+	return pretty.Print(node, withInline)
+}
+
+// nodesParser returns already parsed documents.
+type nodesParser struct {
+	nodes []*Node
+}
+
+func (p *nodesParser) Parse() (*Node, error) {
+	if len(p.nodes) == 0 {
+		return nil, io.EOF
+	}
+	node := p.nodes[0]
+	p.nodes = p.nodes[1:]
+	return node, nil
+}
+
+func (p *nodesParser) Recover() *Node {
+	return nil
+}
+
+// diffKind returns how the node differs in fx diff: as marked, or as the added or
+// removed value it is a part of.
+func (m *model) diffKind(n *Node) diff.Kind {
+	if kind, ok := m.diff[n]; ok {
+		return kind
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if kind := m.diff[p]; kind == diff.Added || kind == diff.Removed {
+			return kind
+		}
+	}
+	return diff.Equal
+}
+
+func (m *model) diffMarker(n *Node) string {
+	switch kind := m.diffKind(n); kind {
+	case diff.Added:
+		return diffColor(kind)("+ ")
+	case diff.Removed:
+		return diffColor(kind)("- ")
+	case diff.Changed:
+		return diffColor(kind)("~ ")
+	default:
+		return "  "
+	}
+}
+
+func diffColor(kind diff.Kind) theme.Color {
+	switch kind {
+	case diff.Added:
+		return theme.CurrentTheme.Added
+	case diff.Removed:
+		return theme.CurrentTheme.Removed
+	default:
+		return theme.CurrentTheme.Changed
+	}
+}
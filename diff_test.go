@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/diff"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func parsePair(t *testing.T, a, b string) (*jsonx.Node, *jsonx.Node) {
+	aNode, err := jsonx.Parse([]byte(a))
+	require.NoError(t, err)
+	bNode, err := jsonx.Parse([]byte(b))
+	require.NoError(t, err)
+	return aNode, bNode
+}
+
+func TestDiffView(t *testing.T) {
+	a, b := parsePair(t,
+		`{"name": "fx", "tags": ["json", "cli"], "meta": {"stars": 1, "old": true}}`,
+		`{"name": "fx", "tags": ["json", "tui"], "meta": {"stars": 2}, "new": [1]}`)
+	docs, marks, err := diff.Merge(a, b, diff.Options{})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	m := &model{
+		top:          docs[0],
+		head:         docs[0],
+		bottom:       docs[0],
+		totalLines:   docs[0].Bottom().LineNumber,
+		eof:          true,
+		wrap:         true,
+		showCursor:   true,
+		searchInput:  textinput.New(),
+		search:       newSearch(),
+		commandInput: textinput.New(),
+		diff:         marks,
+	}
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(80, 20))
+
+	teatest.RequireEqualOutput(t, read(t, tm))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	tm.WaitFinished(t, teatest.WithFinalTimeout(time.Second))
+}
+
+func TestDiffPatch(t *testing.T) {
+	a, b := parsePair(t, `{"a": 1, "b/c": [1, 2], "d": 0}`, `{"a": 2, "b/c": [1], "e": null}`)
+	assert.Equal(t, `[
+  { "op": "remove", "path": "/d" },
+  { "op": "replace", "path": "/a", "value": 2 },
+  { "op": "remove", "path": "/b~1c/1" },
+  { "op": "add", "path": "/e", "value": null }
+]`, diffPatch(diff.Compare(a, b, diff.Options{}), true))
+}
+
+func TestDiffLine(t *testing.T) {
+	a, b := parsePair(t, `{"a": [1], "b": 1}`, `{"a": [2], "c": "x"}`)
+	var lines []string
+	for _, c := range diff.Compare(a, b, diff.Options{}) {
+		lines = append(lines, diffLine(c))
+	}
+	assert.Equal(t, []string{
+		`- .b: 1`,
+		`~ .a[0]: 1 → 2`,
+		`+ .c: "x"`,
+	}, lines)
+}
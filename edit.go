@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	. "github.com/antonmedv/fx/internal/jsonx"
+)
+
+type editMode int
+
+const (
+	editNone editMode = iota
+	editValue
+	editKey
+	insertKey
+	insertValue
+)
+
+// edit holds the state of an inline edit started from the viewer.
+type edit struct {
+	mode  editMode
+	input textinput.Model
+	node  *Node  // Node being edited, or the node to insert after.
+	first bool   // Insert as the first child of node.
+	key   string // Key of a new object member.
+	err   error
+}
+
+func (m *model) startEdit(mode editMode) {
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return
+	}
+	if at.IsWrap() {
+		at = at.Parent
+	}
+	closing := at.Parent != nil && at.Parent.End == at
+
+	e := edit{mode: mode, node: at}
+	switch mode {
+	case editValue:
+		if at.Parent == nil || closing {
+			return
+		}
+		e.input = newEditInput("value: ", Compact(at))
+
+	case editKey:
+		if at.Key == "" {
+			return
+		}
+		e.input = newEditInput("key: ", m.cursorKey())
+
+	case insertValue:
+		var parent *Node
+		switch {
+		case closing:
+			e.node = at.Parent
+			parent = e.node.Parent
+		case isContainer(at) && (!at.HasChildren() || !at.IsCollapsed()):
+			e.first = true
+			parent = at
+		default:
+			parent = at.Parent
+		}
+		if parent == nil {
+			return
+		}
+		if parent.Kind == Object {
+			e.mode = insertKey
+			e.input = newEditInput("new key: ", "")
+		} else {
+			e.input = newEditInput("new value: ", "")
+		}
+	}
+
+	e.input.Width = m.termWidth - len(e.input.Prompt) - 1
+	e.input.Focus()
+	m.edit = e
+}
+
+func newEditInput(prompt, value string) textinput.Model {
+	input := textinput.New()
+	input.Prompt = prompt
+	input.SetValue(value)
+	input.CursorEnd()
+	return input
+}
+
+func isContainer(n *Node) bool {
+	return n.Kind == Object || n.Kind == Array
+}
+
+func (m *model) handleEditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.Type {
+	case tea.KeyEscape:
+		m.edit = edit{}
+
+	case tea.KeyEnter:
+		m.applyEdit()
+
+	default:
+		m.edit.input, cmd = m.edit.input.Update(msg)
+		m.edit.err = nil
+	}
+	return m, cmd
+}
+
+func (m *model) applyEdit() {
+	e := &m.edit
+	input := e.input.Value()
+
+	var n *Node
+	switch e.mode {
+	case editKey:
+		if m.history.RenameKey(e.node, input) {
+			n = e.node
+		}
+
+	case insertKey:
+		e.key = input
+		e.mode = insertValue
+		e.input = newEditInput("new value: ", "")
+		e.input.Width = m.termWidth - len(e.input.Prompt) - 1
+		e.input.Focus()
+		return
+
+	case editValue, insertValue:
+		value, err := ParseValue(input)
+		if err != nil {
+			e.err = err
+			return
+		}
+		switch {
+		case e.mode == editValue:
+			n, _ = m.history.ReplaceValue(e.node, value)
+		case e.first:
+			n, _ = m.history.InsertFirst(e.node, e.key, value)
+		default:
+			n, _ = m.history.InsertAfter(e.node, e.key, value)
+		}
+	}
+
+	m.edit = edit{}
+	m.edited(n)
+}
+
+func (m *model) duplicateAtCursor() {
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return
+	}
+	if n, ok := m.history.Duplicate(at); ok {
+		m.edited(n)
+	}
+}
+
+// edited re-wraps and selects the changed node.
+func (m *model) edited(n *Node) {
+	if n == nil {
+		return
+	}
+	if m.wrap {
+		Wrap(n, m.viewWidth())
+	}
+	m.revalidate()
+	m.selectNode(n)
+	m.recordHistory()
+}
+
+func (m *model) editView() string {
+	view := m.edit.input.View()
+	if m.edit.err != nil {
+		message, _, _ := strings.Cut(m.edit.err.Error(), "\n")
+		return view + strings.Repeat(" ", max(1, m.termWidth-lipgloss.Width(view)-len(message))) + message
+	}
+	return view
+}
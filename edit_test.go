@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func newEditModel(t *testing.T, input string) *model {
+	head, err := jsonx.Parse([]byte(input))
+	require.NoError(t, err)
+	m := &model{
+		top:          head,
+		head:         head,
+		bottom:       head,
+		totalLines:   head.Bottom().LineNumber,
+		eof:          true,
+		wrap:         true,
+		showCursor:   true,
+		termWidth:    80,
+		termHeight:   40,
+		searchInput:  textinput.New(),
+		search:       newSearch(),
+		commandInput: newCommandInput(),
+	}
+	return m
+}
+
+func send(m *model, keys ...string) {
+	for _, k := range keys {
+		switch k {
+		case "enter":
+			m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			m.Update(tea.KeyMsg{Type: tea.KeyEscape})
+		case "up":
+			m.Update(tea.KeyMsg{Type: tea.KeyUp})
+		case "down":
+			m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		case "tab":
+			m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		case "ctrl+u":
+			m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+		default:
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+	}
+}
+
+func TestEditValue(t *testing.T) {
+	m := newEditModel(t, `{"a":1,"b":2}`)
+
+	send(m, "down", "i", "ctrl+u", `[true, "x"]`, "enter")
+	assert.Equal(t, editNone, m.edit.mode)
+	assert.Equal(t, `{"a":[true,"x"],"b":2}`, m.top.String())
+}
+
+func TestEditValue_Invalid(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	send(m, "down", "i", "ctrl+u", "{oops", "enter")
+	assert.Equal(t, editValue, m.edit.mode)
+	assert.Error(t, m.edit.err)
+
+	send(m, "esc")
+	assert.Equal(t, editNone, m.edit.mode)
+	assert.Equal(t, `{"a":1}`, m.top.String())
+}
+
+func TestRenameKey(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	send(m, "down", "r", "ctrl+u", "new", "enter")
+	assert.Equal(t, `{"new":1}`, m.top.String())
+}
+
+func TestInsert(t *testing.T) {
+	m := newEditModel(t, `{"a":[1],"b":{}}`)
+
+	// On an opening bracket, insert as the first child.
+	send(m, "down", "o", "2", "enter")
+	assert.Equal(t, `{"a":[2,1],"b":{}}`, m.top.String())
+
+	// Otherwise, insert after the cursor.
+	send(m, "o", "3", "enter")
+	assert.Equal(t, `{"a":[2,3,1],"b":{}}`, m.top.String())
+
+	// Objects ask for a key first.
+	send(m, "down", "down", "o", "c", "enter", `"x"`, "enter")
+	assert.Equal(t, `{"a":[2,3,1],"c":"x","b":{}}`, m.top.String())
+
+	send(m, "down", "o", "k", "enter", "null", "enter")
+	assert.Equal(t, `{"a":[2,3,1],"c":"x","b":{"k":null}}`, m.top.String())
+}
+
+func TestDuplicate(t *testing.T) {
+	m := newEditModel(t, `[{"a":1}]`)
+
+	send(m, "down", "D")
+	assert.Equal(t, `[{"a":1},{"a":1}]`, m.top.String())
+
+	at, ok := m.cursorPointsTo()
+	require.True(t, ok)
+	assert.Equal(t, 1, at.Index)
+}
+
+func TestUndoRedo(t *testing.T) {
+	m := newEditModel(t, `{"a":1,"b":[2,3]}`)
+
+	send(m, "down", "d", "d", "r", "ctrl+u", "c", "enter")
+	assert.Equal(t, `{"c":[2,3]}`, m.top.String())
+
+	send(m, "u")
+	assert.Equal(t, `{"b":[2,3]}`, m.top.String())
+	send(m, "u")
+	assert.Equal(t, `{"a":1,"b":[2,3]}`, m.top.String())
+
+	at, ok := m.cursorPointsTo()
+	require.True(t, ok)
+	assert.Equal(t, `"a"`, at.Key)
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, `{"b":[2,3]}`, m.top.String())
+}
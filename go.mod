@@ -13,6 +13,7 @@ require (
 	github.com/charmbracelet/x/term v0.2.1
 	github.com/dop251/goja v0.0.0-20250630131328-58d95d85e994
 	github.com/goccy/go-yaml v1.18.0
+	github.com/klauspost/compress v1.18.0
 	github.com/mattn/go-isatty v0.0.20
 	github.com/mattn/go-runewidth v0.0.16
 	github.com/muesli/termenv v0.16.0
@@ -20,6 +20,13 @@ func usage() string {
   %v
     fx data.json
     fx data.json .field
+    fx data.json --jsonpath '$..price'
+    fx data.json --jq '.items[] | select(.price > 10) | .name'
+    fx --patch ops.json data.json save
+    fx --schema schema.json data.json
+    fx --infer go samples.ndjson
+    fx data.json --flatten | grep name | fx --unflatten
+    fx diff a.json b.json
     curl ... | fx
 
   %v
@@ -29,12 +36,44 @@ func usage() string {
     --comp <shell>        print completion script
     -r, --raw             treat input as a raw string
     -s, --slurp           read all inputs into an array
+    --reduce <init> <fn>  fold all inputs with fn(acc, x), starting from init
+    --reduce-every <n>    also print the accumulator every n inputs
+    --parallel <n>        evaluate inputs on n threads, keeping their order
+    --unordered           with --parallel, print results as soon as ready
+    --jsonpath <query>    print nodes selected by an RFC 9535 JSONPath query
+    --jq <filter>         print outputs of a jq filter (a subset of jq)
+    --patch <file>        apply a JSON Patch, or a JSON Merge Patch if it is an object
+    --schema <file>       validate against a JSON Schema, print errors if not a TTY
+    --infer <format>      print the shape of inputs as schema, go or typescript types
+    --flatten             print one "path = value" line per value
+    --unflatten           parse input as lines printed by --flatten
     --yaml                parse input as YAML
     --toml                parse input as TOML
+    --csv                 parse input as CSV
+    --tsv                 parse input as TSV
+    --xml                 parse input as XML
+    --no-header           CSV/TSV input has no header line
+    --delimiter <char>    CSV/TSV field delimiter
+    --infer-types         convert CSV/TSV numbers and booleans
+    --to <format>         output as json, ndjson, yaml, toml, csv, tsv or xml
+    --arg <name> <value>  set variable name to a string
+    --argjson <name> <json>
+                          set variable name to a JSON value
+    --rawfile <name> <file>
+                          set variable name to the file contents
+    --slurpfile <name> <file>
+                          set variable name to an array of JSON values in the file
     --strict              strict mode
+    --warn-precision      warn when a script reads a number float64 can't hold exactly
     --no-inline           disable inlining in output
+    --print-config        print settings and where they come from
     --game-of-life        play the game of life
 
+  %v
+    --id <field>          match elements of arrays of objects by field
+    --summary             print changes instead of viewing them, exit 1 if any
+    --json-patch          print changes as JSON Patch, exit 1 if any
+
   %v
     https://fx.wtf
 
@@ -44,6 +83,7 @@ func usage() string {
 		title.Render("fx "+version),
 		title.Render("Usage"),
 		title.Render("Flags"),
+		title.Render("Diff flags"),
 		title.Render("More info"),
 		title.Render("Author"),
 	)
@@ -55,6 +95,8 @@ var categoryOrder = []string{
 	"Search",
 	"Actions",
 	"View",
+	"Yank",
+	"Show",
 	"Other",
 }
 
@@ -85,6 +127,9 @@ func help(keyMap KeyMap) string {
 			continue
 		}
 		binding := v.FieldByName(field.Name).Interface().(key.Binding)
+		if !binding.Enabled() {
+			continue
+		}
 		categories[category] = append(categories[category], binding)
 	}
 
@@ -128,7 +173,13 @@ func help(keyMap KeyMap) string {
 	sb.WriteString("\n")
 	sb.WriteString(dimStyle.Render("  ─────────────────────────────────────────"))
 	sb.WriteString("\n")
-	sb.WriteString(dimStyle.Render("  Press q or ? to close"))
+	var closeKeys []string
+	for _, binding := range []key.Binding{keyMap.Quit, keyMap.Help} {
+		if binding.Enabled() {
+			closeKeys = append(closeKeys, binding.Keys()[0])
+		}
+	}
+	sb.WriteString(dimStyle.Render("  Press " + strings.Join(closeKeys, " or ") + " to close"))
 	sb.WriteString("\n")
 
 	return sb.String()
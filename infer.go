@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/infer"
+	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/pretty"
+)
+
+var inferFormats = []string{"schema", "go", "typescript"}
+
+// runInfer prints the merged shape of all documents as a JSON Schema, Go types or
+// TypeScript types. With slurp, the root is an array of the documents.
+func runInfer(parser engine.Parser, format string, slurp bool, w io.Writer) int {
+	var shape infer.Shape
+	for {
+		node, err := parser.Parse()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(w, err.Error())
+			return 1
+		}
+		shape.Add(node)
+	}
+	if slurp {
+		shape = infer.ArrayOf(shape)
+	}
+
+	switch format {
+	case "go":
+		_, _ = fmt.Fprint(w, infer.Go(&shape, "Root"))
+	case "typescript":
+		_, _ = fmt.Fprint(w, infer.TypeScript(&shape, "Root"))
+	default:
+		node, err := Parse([]byte(infer.Schema(&shape)))
+		if err != nil {
+			panic(err)
+		}
+		_, _ = fmt.Fprintln(w, pretty.Print(node, !flagNoInline))
+	}
+	return 0
+}
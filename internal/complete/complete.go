@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/pelletier/go-toml/v2"
 
+	"github.com/antonmedv/fx/internal/compress"
 	"github.com/antonmedv/fx/internal/engine"
 	"github.com/antonmedv/fx/internal/jsonx"
 	"github.com/antonmedv/fx/internal/shlex"
@@ -27,6 +29,9 @@ type Reply struct {
 
 var Flags []Reply
 
+// FlagOperands is the number of values following flags which take them.
+var FlagOperands map[string]int
+
 //go:embed complete.bash
 var Bash string
 
@@ -85,6 +90,9 @@ func doComplete(compLine string, compWord string, withDisplay bool) {
 		}
 	}
 
+	// Variables are known by name in expressions.
+	engine.Vars = varsOf(args)
+
 	// Remove Flags from args.
 	args = filterArgs(args)
 
@@ -111,8 +119,8 @@ func doComplete(compLine string, compWord string, withDisplay bool) {
 	if isSecondArgIsFile {
 		file := args[1]
 
-		hasYamlExt, _ := regexp.MatchString(`(?i)\.ya?ml$`, file)
-		hasTomlExt, _ := regexp.MatchString(`(?i)\.toml$`, file)
+		hasYamlExt, _ := regexp.MatchString(`(?i)\.ya?ml$`, compress.TrimExt(file))
+		hasTomlExt, _ := regexp.MatchString(`(?i)\.toml$`, compress.TrimExt(file))
 		if !flagYaml && hasYamlExt {
 			flagYaml = true
 		}
@@ -130,7 +138,7 @@ func doComplete(compLine string, compWord string, withDisplay bool) {
 		resultCh := make(chan []Reply, 1)
 
 		go func() {
-			input, err := os.ReadFile(file)
+			input, err := readFile(file)
 			if err != nil {
 				resultCh <- []Reply{}
 				return
@@ -193,6 +201,9 @@ func globalsComplete() []Reply {
 	code.WriteString("\n__autocomplete()\n")
 
 	vm := goja.New()
+	if err := engine.DefineGlobals(vm, engine.Vars); err != nil {
+		return nil
+	}
 	value, err := vm.RunString(code.String())
 	if err != nil {
 		return nil
@@ -234,6 +245,9 @@ func KeysComplete(input *jsonx.Node, args []string, compWord string) []Reply {
 	code.WriteString("\n__main__(json)\n__keys\n")
 
 	vm := goja.New()
+	if err := engine.DefineGlobals(vm, engine.Vars); err != nil {
+		return nil
+	}
 	if err := vm.Set("json", input.ToValue(vm)); err != nil {
 		return nil
 	}
@@ -271,9 +285,29 @@ func join(prefix, key string) string {
 	}
 }
 
+// varsOf returns variables defined with engine.VarFlags, skipping invalid ones.
+func varsOf(args []string) []engine.Var {
+	var vars []engine.Var
+	for i := 0; i+2 < len(args); i++ {
+		if slices.Contains(engine.VarFlags, args[i]) {
+			v, err := engine.NewVar(args[i], args[i+1], args[i+2])
+			if err == nil {
+				vars = append(vars, v)
+			}
+			i += 2
+		}
+	}
+	return vars
+}
+
 func filterArgs(args []string) []string {
 	filtered := make([]string, 0, len(args))
-	for _, arg := range args {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if n, ok := FlagOperands[arg]; ok {
+			i += n // Skip the operands.
+			continue
+		}
 		found := false
 		for _, flag := range Flags {
 			if arg == flag.Value {
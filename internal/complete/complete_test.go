@@ -1,8 +1,10 @@
 package complete
 
 import (
+	"slices"
 	"testing"
 
+	"github.com/antonmedv/fx/internal/engine"
 	"github.com/antonmedv/fx/internal/jsonx"
 )
 
@@ -230,3 +232,44 @@ func TestKeysComplete_DisplayVsValue(t *testing.T) {
 		})
 	}
 }
+
+func TestVars(t *testing.T) {
+	args := []string{"fx", "--arg", "name", "outer", "file.json", "--argjson", "n", "1", ".foo"}
+
+	vars := varsOf(args)
+	if len(vars) != 2 || vars[0].Name != "name" || vars[1].Name != "n" {
+		t.Fatalf("varsOf() = %v", vars)
+	}
+
+	FlagOperands = map[string]int{"--arg": 2, "--argjson": 2}
+	defer func() { FlagOperands = nil }()
+	filtered := filterArgs(args)
+	want := []string{"fx", "file.json", ".foo"}
+	if !slices.Equal(filtered, want) {
+		t.Errorf("filterArgs() = %q, want %q", filtered, want)
+	}
+}
+
+func TestKeysComplete_Vars(t *testing.T) {
+	engine.Vars = varsOf([]string{"--arg", "key", "outer"})
+	defer func() { engine.Vars = nil }()
+
+	node, err := jsonx.Parse([]byte(`{"outer": {"inner": 1}}`))
+	if err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	got := KeysComplete(node, []string{"fx", "file.json", "x[key]."}, "x[key].")
+	if len(got) != 1 || got[0].Value != "x[key].inner" {
+		t.Errorf("KeysComplete() = %v", got)
+	}
+
+	var names []string
+	for _, reply := range globalsComplete() {
+		names = append(names, reply.Value)
+	}
+	for _, want := range []string{"key", "env", "env.HOME"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("globalsComplete() has no %q", want)
+		}
+	}
+}
@@ -15,6 +15,9 @@ function __autocomplete() {
     if (key.startsWith('__')) continue
     keys.push(key)
   }
+  for (const key of Object.keys(env)) {
+    keys.push('env.' + key)
+  }
   keys.push(
     'JSON.stringify',
     'JSON.parse',
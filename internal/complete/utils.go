@@ -2,9 +2,12 @@ package complete
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/antonmedv/fx/internal/compress"
 )
 
 func compReply(reply []Reply, withDisplay bool) {
@@ -43,6 +46,19 @@ func isFile(path string) bool {
 	return !info.IsDir()
 }
 
+func readFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rd, _, err := compress.Detect(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(rd)
+}
+
 func dropTail(s string) string {
 	parts := strings.Split(s, ".")
 	if len(parts) == 1 {
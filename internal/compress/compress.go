@@ -0,0 +1,130 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Kind string
+
+const (
+	None  Kind = ""
+	Gzip  Kind = "gzip"
+	Zstd  Kind = "zstd"
+	Bzip2 Kind = "bzip2"
+)
+
+var magic = []struct {
+	kind  Kind
+	bytes []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// bzip2 streams start with "BZh", a block size digit, and the magic of the first
+// block (pi) or, if empty, of the end of stream (sqrt(pi)).
+var (
+	bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	bzip2EndMagic   = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+)
+
+func isBzip2(head []byte) bool {
+	if len(head) < 10 || !bytes.HasPrefix(head, []byte("BZh")) || head[3] < '1' || head[3] > '9' {
+		return false
+	}
+	return bytes.Equal(head[4:10], bzip2BlockMagic) || bytes.Equal(head[4:10], bzip2EndMagic)
+}
+
+// Detect sniffs the magic bytes of the input and wraps it with
+// a matching decompressor. Uncompressed input is returned as is.
+func Detect(in io.Reader) (io.Reader, Kind, error) {
+	buf := bufio.NewReader(in)
+	head, _ := buf.Peek(10)
+
+	kind := None
+	for _, m := range magic {
+		if bytes.HasPrefix(head, m.bytes) {
+			kind = m.kind
+			break
+		}
+	}
+	if isBzip2(head) {
+		kind = Bzip2
+	}
+
+	switch kind {
+	case Gzip:
+		rd, err := gzip.NewReader(buf)
+		if err != nil {
+			return nil, kind, err
+		}
+		return rd, kind, nil
+	case Zstd:
+		rd, err := zstd.NewReader(buf)
+		if err != nil {
+			return nil, kind, err
+		}
+		return rd.IOReadCloser(), kind, nil
+	case Bzip2:
+		return bzip2.NewReader(buf), kind, nil
+	}
+	return buf, kind, nil
+}
+
+var extRe = regexp.MustCompile(`(?i)\.(gz|gzip|zst|zstd|bz2|bzip2)$`)
+
+// ByExt returns the compression kind matching the extension of the file name.
+func ByExt(fileName string) Kind {
+	m := extRe.FindStringSubmatch(fileName)
+	if m == nil {
+		return None
+	}
+	switch strings.ToLower(m[1]) {
+	case "gz", "gzip":
+		return Gzip
+	case "zst", "zstd":
+		return Zstd
+	}
+	return Bzip2
+}
+
+// TrimExt removes a compression extension from the file name: data.json.gz → data.json.
+func TrimExt(fileName string) string {
+	return extRe.ReplaceAllString(fileName, "")
+}
+
+// Compress compresses data with the given kind.
+func Compress(kind Kind, data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	var w io.WriteCloser
+	switch kind {
+	case None:
+		return data, nil
+	case Gzip:
+		w = gzip.NewWriter(&out)
+	case Zstd:
+		zw, err := zstd.NewWriter(&out)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("%s compression is not supported for writing", kind)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,84 @@
+package compress_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/compress"
+)
+
+func decompress(t *testing.T, data []byte) (string, compress.Kind) {
+	rd, kind, err := compress.Detect(bytes.NewReader(data))
+	require.NoError(t, err)
+	out, err := io.ReadAll(rd)
+	require.NoError(t, err)
+	return string(out), kind
+}
+
+func TestDetect(t *testing.T) {
+	input := []byte(`{"a":1}`)
+
+	out, kind := decompress(t, input)
+	assert.Equal(t, compress.None, kind)
+	assert.Equal(t, `{"a":1}`, out)
+
+	for _, k := range []compress.Kind{compress.Gzip, compress.Zstd} {
+		t.Run(string(k), func(t *testing.T) {
+			data, err := compress.Compress(k, input)
+			require.NoError(t, err)
+			out, kind := decompress(t, data)
+			assert.Equal(t, k, kind)
+			assert.Equal(t, `{"a":1}`, out)
+		})
+	}
+
+	t.Run("bzip2", func(t *testing.T) {
+		data, err := hex.DecodeString("425a68393141592653593adf03600000029980100020102000000a200021800c025b06dc5dc914e14240eb7c0d80")
+		require.NoError(t, err)
+		out, kind := decompress(t, data)
+		assert.Equal(t, compress.Bzip2, kind)
+		assert.Equal(t, `{"a":1}`, out)
+
+		empty, err := hex.DecodeString("425a683917724538509000000000")
+		require.NoError(t, err)
+		out, kind = decompress(t, empty)
+		assert.Equal(t, compress.Bzip2, kind)
+		assert.Equal(t, "", out)
+
+		_, err = compress.Compress(compress.Bzip2, input)
+		assert.Error(t, err)
+	})
+}
+
+func TestDetect_TextLikeBzip2(t *testing.T) {
+	for _, input := range []string{"BZhello\n", "BZh9 is not bzip2\n", "BZh"} {
+		out, kind := decompress(t, []byte(input))
+		assert.Equal(t, compress.None, kind)
+		assert.Equal(t, input, out)
+	}
+}
+
+func TestDetect_ShortInput(t *testing.T) {
+	out, kind := decompress(t, []byte("1"))
+	assert.Equal(t, compress.None, kind)
+	assert.Equal(t, "1", out)
+}
+
+func TestTrimExt(t *testing.T) {
+	assert.Equal(t, "data.json", compress.TrimExt("data.json.gz"))
+	assert.Equal(t, "logs.ndjson", compress.TrimExt("logs.ndjson.zst"))
+	assert.Equal(t, "config.yaml", compress.TrimExt("config.yaml.BZ2"))
+	assert.Equal(t, "data.json", compress.TrimExt("data.json"))
+}
+
+func TestByExt(t *testing.T) {
+	assert.Equal(t, compress.Gzip, compress.ByExt("data.json.gz"))
+	assert.Equal(t, compress.Zstd, compress.ByExt("logs.ndjson.ZST"))
+	assert.Equal(t, compress.Bzip2, compress.ByExt("config.yaml.bz2"))
+	assert.Equal(t, compress.None, compress.ByExt("data.json"))
+}
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// FileName is the name of the config file in each of the search Paths.
+const FileName = ".fxrc.toml"
+
+// Default is the source of settings nobody has changed.
+const Default = "default"
+
+// Setting is a config value along with where it came from:
+// Default, a config file path, an environment variable or a flag.
+type Setting[T any] struct {
+	Value  T
+	Source string
+}
+
+// Set overrides the value. It is used for layers with higher precedence.
+func (s *Setting[T]) Set(value T, source string) {
+	s.Value = value
+	s.Source = source
+}
+
+// Config is the merged configuration.
+//
+//	theme = "2"
+//	indent = 4
+//	editor = "code --goto {file}:{line}"
+//	input = "yaml"
+//	output = "json"
+//
+//	[viewer]
+//	collapsed = true
+//	line_numbers = true
+//	show_sizes = true
+//	mouse = false
+//
+//	[keys]
+//	GotoRef = "ctrl+]"
+type Config struct {
+	Theme       Setting[string]
+	Indent      Setting[string] // Number of spaces or the indent itself.
+	Editor      Setting[string] // Command, may contain {file} and {line} placeholders.
+	Input       Setting[string] // Input format if not set by flags or the file extension.
+	Output      Setting[string] // Output format, empty for pretty printing.
+	Collapsed   Setting[bool]
+	LineNumbers Setting[bool]
+	ShowSizes   Setting[bool]
+	Mouse       Setting[bool]
+	Keys        map[string]Setting[any] // Key bindings by action name.
+}
+
+var InputFormats = []string{"json", "yaml", "toml", "csv", "tsv", "xml", "raw"}
+
+var OutputFormats = []string{"json", "ndjson", "yaml", "toml", "csv", "tsv", "xml"}
+
+func New() *Config {
+	return &Config{
+		Theme:       Setting[string]{"1", Default},
+		Indent:      Setting[string]{"  ", Default},
+		Editor:      Setting[string]{"vim", Default},
+		Input:       Setting[string]{"json", Default},
+		Output:      Setting[string]{"", Default},
+		Collapsed:   Setting[bool]{false, Default},
+		LineNumbers: Setting[bool]{false, Default},
+		ShowSizes:   Setting[bool]{false, Default},
+		Mouse:       Setting[bool]{true, Default},
+		Keys:        map[string]Setting[any]{},
+	}
+}
+
+// Load reads config files from Paths and the environment.
+// Precedence from highest: env var, project config (cwd), user config,
+// the keymap file of KeyMapPath.
+func Load() (*Config, error) {
+	c := New()
+	if err := c.LoadKeyMap(KeyMapPath(os.LookupEnv)); err != nil {
+		return nil, err
+	}
+	paths := Paths(FileName)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := c.LoadFile(paths[i]); err != nil {
+			return nil, err
+		}
+	}
+	c.LoadEnv(os.LookupEnv)
+	return c, nil
+}
+
+// Paths returns candidate locations of a config file with the given name, in order
+// of precedence: cwd, home, $XDG_CONFIG_HOME/fx and $XDG_CONFIG_DIRS.
+func Paths(name string) []string {
+	var paths []string
+	if cwd, err := os.Getwd(); err == nil {
+		paths = append(paths, filepath.Join(cwd, name))
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		paths = append(paths, filepath.Join(home, name))
+	}
+
+	xdgHome := os.Getenv("XDG_CONFIG_HOME")
+	if xdgHome == "" && home != "" {
+		xdgHome = filepath.Join(home, ".config")
+	}
+	if xdgHome != "" {
+		paths = append(paths, filepath.Join(xdgHome, "fx", name))
+	}
+
+	xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if xdgDirs == "" {
+		xdgDirs = "/etc/xdg"
+	}
+	for _, dir := range strings.Split(xdgDirs, ":") {
+		paths = append(paths, filepath.Join(dir, "fx", name))
+	}
+
+	var uniq []string
+	for _, path := range paths {
+		if !slices.Contains(uniq, path) {
+			uniq = append(uniq, path)
+		}
+	}
+	return uniq
+}
+
+// KeyMapPath returns the path of the key bindings file fx read before
+// the [keys] table: $FX_KEYMAP or $XDG_CONFIG_HOME/fx/keymap.toml.
+func KeyMapPath(lookupEnv func(string) (string, bool)) string {
+	if path, ok := lookupEnv("FX_KEYMAP"); ok {
+		return path
+	}
+	dir, _ := lookupEnv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "fx", "keymap.toml")
+}
+
+// LoadKeyMap merges key bindings from a keymap file over c. The file is
+// the contents of a [keys] table. A missing file is not an error.
+func (c *Config) LoadKeyMap(path string) error {
+	if path == "" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.IsDir() {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var keys map[string]any
+	if err := toml.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for action, value := range keys {
+		c.Keys[action] = Setting[any]{value, path}
+	}
+	return nil
+}
+
+// LoadFile merges a config file over c. A missing file is not an error.
+func (c *Config) LoadFile(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.IsDir() {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.Parse(data, path)
+}
+
+// Parse merges TOML config data over c, using source for all settings in it.
+func (c *Config) Parse(data []byte, source string) error {
+	var file map[string]any
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+	for name, value := range file {
+		switch name {
+		case "theme":
+			check(setString(&c.Theme, name, value, source))
+		case "indent":
+			if n, ok := value.(int64); ok {
+				value = strconv.FormatInt(n, 10)
+			}
+			check(setString(&c.Indent, name, value, source))
+		case "editor":
+			check(setString(&c.Editor, name, value, source))
+			if strings.TrimSpace(c.Editor.Value) == "" {
+				check(fmt.Errorf("editor must not be empty"))
+			}
+		case "input":
+			check(setString(&c.Input, name, value, source))
+			if !slices.Contains(InputFormats, c.Input.Value) {
+				check(fmt.Errorf("input must be one of: %s", strings.Join(InputFormats, ", ")))
+			}
+		case "output":
+			check(setString(&c.Output, name, value, source))
+			if !slices.Contains(OutputFormats, c.Output.Value) {
+				check(fmt.Errorf("output must be one of: %s", strings.Join(OutputFormats, ", ")))
+			}
+		case "viewer":
+			viewer, ok := value.(map[string]any)
+			if !ok {
+				check(fmt.Errorf("viewer must be a table"))
+				continue
+			}
+			for name, value := range viewer {
+				switch name {
+				case "collapsed":
+					check(setBool(&c.Collapsed, name, value, source))
+				case "line_numbers":
+					check(setBool(&c.LineNumbers, name, value, source))
+				case "show_sizes":
+					check(setBool(&c.ShowSizes, name, value, source))
+				case "mouse":
+					check(setBool(&c.Mouse, name, value, source))
+				default:
+					check(fmt.Errorf("unknown setting viewer.%s", name))
+				}
+			}
+		case "keys":
+			keys, ok := value.(map[string]any)
+			if !ok {
+				check(fmt.Errorf("keys must be a table"))
+				continue
+			}
+			for action, value := range keys {
+				c.Keys[action] = Setting[any]{value, source}
+			}
+		default:
+			check(fmt.Errorf("unknown setting %s", name))
+		}
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errors.Join(errs...)
+}
+
+func setString(s *Setting[string], name string, value any, source string) error {
+	v, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a string", name)
+	}
+	s.Set(v, source)
+	return nil
+}
+
+func setBool(s *Setting[bool], name string, value any, source string) error {
+	v, ok := value.(bool)
+	if !ok {
+		return fmt.Errorf("%s must be true or false", name)
+	}
+	s.Set(v, source)
+	return nil
+}
+
+// LoadEnv merges FX_* environment variables over c.
+func (c *Config) LoadEnv(lookupEnv func(string) (string, bool)) {
+	if v, ok := lookupEnv("FX_THEME"); ok {
+		c.Theme.Set(v, "env FX_THEME")
+	}
+	if v, ok := lookupEnv("FX_INDENT"); ok {
+		c.Indent.Set(v, "env FX_INDENT")
+	}
+	if v, ok := lookupEnv("EDITOR"); ok && strings.TrimSpace(v) != "" && c.Editor.Source == Default {
+		c.Editor.Set(v, "env EDITOR") // Config files take precedence over the generic EDITOR.
+	}
+	if v, ok := lookupEnv("FX_EDITOR"); ok && strings.TrimSpace(v) != "" {
+		c.Editor.Set(v, "env FX_EDITOR")
+	}
+	if _, ok := lookupEnv("FX_COLLAPSED"); ok {
+		c.Collapsed.Set(true, "env FX_COLLAPSED")
+	}
+	if _, ok := lookupEnv("FX_LINE_NUMBERS"); ok {
+		c.LineNumbers.Set(true, "env FX_LINE_NUMBERS")
+	}
+	if v, ok := lookupEnv("FX_SHOW_SIZE"); ok {
+		v = strings.ToLower(v)
+		c.ShowSizes.Set(v == "true" || v == "yes" || v == "on" || v == "1", "env FX_SHOW_SIZE")
+	}
+	if _, ok := lookupEnv("FX_NO_MOUSE"); ok {
+		c.Mouse.Set(false, "env FX_NO_MOUSE")
+	}
+}
+
+// Print writes the settings in the config file format, with their sources as comments.
+func (c *Config) Print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	line := func(name string, value any, source string) {
+		b, _ := json.Marshal(value)
+		_, _ = fmt.Fprintf(w, "%s = %s\t# %s\n", name, b, source)
+	}
+	line("theme", c.Theme.Value, c.Theme.Source)
+	line("indent", c.Indent.Value, c.Indent.Source)
+	line("editor", c.Editor.Value, c.Editor.Source)
+	line("input", c.Input.Value, c.Input.Source)
+	line("output", c.Output.Value, c.Output.Source)
+	_, _ = fmt.Fprintln(w, "\n[viewer]")
+	line("collapsed", c.Collapsed.Value, c.Collapsed.Source)
+	line("line_numbers", c.LineNumbers.Value, c.LineNumbers.Source)
+	line("show_sizes", c.ShowSizes.Value, c.ShowSizes.Source)
+	line("mouse", c.Mouse.Value, c.Mouse.Source)
+	if len(c.Keys) > 0 {
+		_, _ = fmt.Fprintln(w, "\n[keys]")
+		actions := make([]string, 0, len(c.Keys))
+		for action := range c.Keys {
+			actions = append(actions, action)
+		}
+		slices.Sort(actions)
+		for _, action := range actions {
+			line(action, c.Keys[action].Value, c.Keys[action].Source)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,155 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	c := config.New()
+	err := c.Parse([]byte(`
+theme = "2"
+indent = 4
+editor = "code --goto {file}:{line}"
+input = "yaml"
+
+[viewer]
+collapsed = true
+mouse = false
+
+[keys]
+Quit = ["q"]
+`), "user.toml")
+	require.NoError(t, err)
+
+	assert.Equal(t, config.Setting[string]{"2", "user.toml"}, c.Theme)
+	assert.Equal(t, "4", c.Indent.Value)
+	assert.Equal(t, "yaml", c.Input.Value)
+	assert.Equal(t, config.Setting[bool]{true, "user.toml"}, c.Collapsed)
+	assert.False(t, c.Mouse.Value)
+	assert.Equal(t, config.Default, c.LineNumbers.Source)
+	assert.Equal(t, []any{"q"}, c.Keys["Quit"].Value)
+}
+
+func TestParse_Errors(t *testing.T) {
+	c := config.New()
+	err := c.Parse([]byte(`
+theme = 2
+input = "ini"
+output = "foo"
+bogus = 1
+
+[viewer]
+collapsed = "yes"
+`), "fx.toml")
+	require.Error(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"fx.toml: collapsed must be true or false",
+		"fx.toml: input must be one of: json, yaml, toml, csv, tsv, xml, raw",
+		"fx.toml: output must be one of: json, ndjson, yaml, toml, csv, tsv, xml",
+		"fx.toml: theme must be a string",
+		"fx.toml: unknown setting bogus",
+	}, "\n"), err.Error())
+}
+
+func TestParse_EmptyEditor(t *testing.T) {
+	for _, editor := range []string{`""`, `"  "`} {
+		c := config.New()
+		err := c.Parse([]byte("editor = "+editor), "fx.toml")
+		require.EqualError(t, err, "fx.toml: editor must not be empty")
+	}
+
+	c := config.New()
+	c.LoadEnv(func(name string) (string, bool) {
+		return " ", name == "FX_EDITOR" || name == "EDITOR"
+	})
+	assert.Equal(t, "vim", c.Editor.Value)
+}
+
+func TestPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	user := filepath.Join(dir, "user.toml")
+	project := filepath.Join(dir, "project.toml")
+	require.NoError(t, os.WriteFile(user, []byte("theme = \"2\"\nindent = 4\neditor = \"nano\"\n"), 0644))
+	require.NoError(t, os.WriteFile(project, []byte("theme = \"3\"\n"), 0644))
+
+	c := config.New()
+	require.NoError(t, c.LoadFile(user))
+	require.NoError(t, c.LoadFile(project))
+	require.NoError(t, c.LoadFile(filepath.Join(dir, "missing.toml")))
+	c.LoadEnv(func(name string) (string, bool) {
+		switch name {
+		case "FX_INDENT":
+			return "\t", true
+		case "EDITOR":
+			return "vim", true
+		case "FX_SHOW_SIZE":
+			return "yes", true
+		}
+		return "", false
+	})
+	c.Output.Set("yaml", "flag --to")
+
+	assert.Equal(t, config.Setting[string]{"3", project}, c.Theme)
+	assert.Equal(t, config.Setting[string]{"\t", "env FX_INDENT"}, c.Indent)
+	assert.Equal(t, config.Setting[string]{"nano", user}, c.Editor, "config files win over EDITOR")
+	assert.Equal(t, config.Setting[bool]{true, "env FX_SHOW_SIZE"}, c.ShowSizes)
+
+	var out strings.Builder
+	require.NoError(t, c.Print(&out))
+	assert.Contains(t, out.String(), `theme = "3"`)
+	assert.Contains(t, out.String(), "# "+project)
+	assert.Contains(t, out.String(), `output = "yaml"`)
+	assert.Contains(t, out.String(), "# flag --to")
+}
+
+func TestPaths(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_CONFIG_DIRS", "/etc/a:/etc/b")
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(cwd, ".fxrc.toml"),
+		"/home/u/.fxrc.toml",
+		"/home/u/.config/fx/.fxrc.toml",
+		"/etc/a/fx/.fxrc.toml",
+		"/etc/b/fx/.fxrc.toml",
+	}, config.Paths(config.FileName))
+}
+
+func TestLoadKeyMap(t *testing.T) {
+	dir := t.TempDir()
+	keymap := filepath.Join(dir, "keymap.toml")
+	user := filepath.Join(dir, "user.toml")
+	require.NoError(t, os.WriteFile(keymap, []byte("Quit = \"x\"\nHelp = [\"h\"]\n"), 0644))
+	require.NoError(t, os.WriteFile(user, []byte("[keys]\nQuit = \"Q\"\n"), 0644))
+
+	c := config.New()
+	require.NoError(t, c.LoadKeyMap(keymap))
+	require.NoError(t, c.LoadKeyMap(filepath.Join(dir, "missing.toml")))
+	require.NoError(t, c.LoadFile(user))
+
+	assert.Equal(t, config.Setting[any]{"Q", user}, c.Keys["Quit"], "config files win over the keymap file")
+	assert.Equal(t, config.Setting[any]{[]any{"h"}, keymap}, c.Keys["Help"])
+}
+
+func TestKeyMapPath(t *testing.T) {
+	env := map[string]string{"XDG_CONFIG_HOME": "/xdg"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	assert.Equal(t, "/xdg/fx/keymap.toml", config.KeyMapPath(lookupEnv))
+
+	env["FX_KEYMAP"] = "/tmp/keys.toml"
+	assert.Equal(t, "/tmp/keys.toml", config.KeyMapPath(lookupEnv))
+}
@@ -0,0 +1,235 @@
+// Package diff compares two JSON documents by object keys and array indexes.
+package diff
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+type Kind byte
+
+const (
+	Equal Kind = iota
+	Added
+	Removed
+	Changed
+)
+
+// Change is a node added, removed or replaced at Path, a list of object keys and
+// array indexes. Paths are those of JSON Patch operations applied in order.
+type Change struct {
+	Kind Kind
+	Path []any
+	Old  *jsonx.Node // Nil if added.
+	New  *jsonx.Node // Nil if removed.
+}
+
+type Options struct {
+	// ID is a field identifying elements of arrays of objects. If set, such arrays
+	// are compared by it instead of by index, ignoring the order of elements.
+	ID string
+}
+
+// Compare returns the changes turning a into b.
+func Compare(a, b *jsonx.Node, opts Options) []Change {
+	var changes []Change
+	compare(a, b, nil, opts, &changes)
+	return changes
+}
+
+func compare(a, b *jsonx.Node, path []any, opts Options, changes *[]Change) {
+	if Equals(a, b) {
+		return
+	}
+	switch {
+	case a.Kind == jsonx.Object && b.Kind == jsonx.Object:
+		aKeys, aValues := entries(a)
+		bKeys, bValues := entries(b)
+		bIndex := indexOf(bKeys)
+		aIndex := indexOf(aKeys)
+		for i, key := range aKeys {
+			if _, ok := bIndex[key]; !ok {
+				*changes = append(*changes, Change{Kind: Removed, Path: with(path, key), Old: aValues[i]})
+			}
+		}
+		for i, key := range bKeys {
+			if j, ok := aIndex[key]; ok {
+				compare(aValues[j], bValues[i], with(path, key), opts, changes)
+			} else {
+				*changes = append(*changes, Change{Kind: Added, Path: with(path, key), New: bValues[i]})
+			}
+		}
+
+	case a.Kind == jsonx.Array && b.Kind == jsonx.Array:
+		aElems, bElems := a.Elements(), b.Elements()
+		if aIDs, bIDs, ok := ids(aElems, bElems, opts.ID); ok {
+			aIndex, bIndex := indexOf(aIDs), indexOf(bIDs)
+			for i := len(aIDs) - 1; i >= 0; i-- {
+				if _, ok := bIndex[aIDs[i]]; !ok {
+					*changes = append(*changes, Change{Kind: Removed, Path: with(path, i), Old: aElems[i]})
+				}
+			}
+			index := 0 // Indexes after the removals, matched elements keep the order of a.
+			for i, id := range aIDs {
+				if j, ok := bIndex[id]; ok {
+					compare(aElems[i], bElems[j], with(path, index), opts, changes)
+					index++
+				}
+			}
+			for j, id := range bIDs {
+				if _, ok := aIndex[id]; !ok {
+					*changes = append(*changes, Change{Kind: Added, Path: with(path, index), New: bElems[j]})
+					index++
+				}
+			}
+			return
+		}
+		common := min(len(aElems), len(bElems))
+		for i := 0; i < common; i++ {
+			compare(aElems[i], bElems[i], with(path, i), opts, changes)
+		}
+		for i := len(aElems) - 1; i >= common; i-- {
+			*changes = append(*changes, Change{Kind: Removed, Path: with(path, i), Old: aElems[i]})
+		}
+		for i := common; i < len(bElems); i++ {
+			*changes = append(*changes, Change{Kind: Added, Path: with(path, i), New: bElems[i]})
+		}
+
+	default:
+		*changes = append(*changes, Change{Kind: Changed, Path: path, Old: a, New: b})
+	}
+}
+
+// Equals reports whether a and b are the same JSON value. Object keys may be in any
+// order, numbers are compared by value and strings after unescaping.
+func Equals(a, b *jsonx.Node) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case jsonx.Object:
+		aKeys, aValues := entries(a)
+		bKeys, bValues := entries(b)
+		if len(aKeys) != len(bKeys) {
+			return false
+		}
+		bIndex := indexOf(bKeys)
+		for i, key := range aKeys {
+			j, ok := bIndex[key]
+			if !ok || !Equals(aValues[i], bValues[j]) {
+				return false
+			}
+		}
+		return true
+
+	case jsonx.Array:
+		aElems, bElems := a.Elements(), b.Elements()
+		if len(aElems) != len(bElems) {
+			return false
+		}
+		for i := range aElems {
+			if !Equals(aElems[i], bElems[i]) {
+				return false
+			}
+		}
+		return true
+
+	case jsonx.Number:
+		x, xOk := new(big.Rat).SetString(a.Value)
+		y, yOk := new(big.Rat).SetString(b.Value)
+		if xOk && yOk {
+			return x.Cmp(y) == 0
+		}
+		return a.Value == b.Value
+
+	case jsonx.String:
+		return unquote(a.Value) == unquote(b.Value)
+
+	default:
+		return a.Value == b.Value
+	}
+}
+
+// entries returns the unquoted keys and the values of an object. Of duplicate keys,
+// the last one wins, like in JSON.parse.
+func entries(n *jsonx.Node) ([]string, []*jsonx.Node) {
+	var keys []string
+	var values []*jsonx.Node
+	seen := map[string]int{}
+	for _, child := range n.Elements() {
+		key := unquote(child.Key)
+		if i, ok := seen[key]; ok {
+			values[i] = child
+			continue
+		}
+		seen[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, child)
+	}
+	return keys, values
+}
+
+// ids returns the ids of elements of both arrays, if field is set and all elements
+// are objects with distinct ids.
+func ids(a, b []*jsonx.Node, field string) ([]string, []string, bool) {
+	if field == "" {
+		return nil, nil, false
+	}
+	aIDs, ok := idsOf(a, field)
+	if !ok {
+		return nil, nil, false
+	}
+	bIDs, ok := idsOf(b, field)
+	if !ok {
+		return nil, nil, false
+	}
+	return aIDs, bIDs, true
+}
+
+func idsOf(elems []*jsonx.Node, field string) ([]string, bool) {
+	out := make([]string, len(elems))
+	for i, elem := range elems {
+		if elem.Kind != jsonx.Object {
+			return nil, false
+		}
+		keys, values := entries(elem)
+		found := false
+		for j, key := range keys {
+			if key == field {
+				out[i] = jsonx.Compact(values[j])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	if len(indexOf(out)) != len(out) {
+		return nil, false
+	}
+	return out, true
+}
+
+func indexOf(keys []string) map[string]int {
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	return index
+}
+
+func with(path []any, part any) []any {
+	out := make([]any, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, part)
+}
+
+func unquote(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/diff"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+type change struct {
+	kind     diff.Kind
+	path     []any
+	old, new string
+}
+
+func compare(t *testing.T, a, b string, opts diff.Options) []change {
+	t.Helper()
+	aNode, err := jsonx.Parse([]byte(a))
+	require.NoError(t, err)
+	bNode, err := jsonx.Parse([]byte(b))
+	require.NoError(t, err)
+	out := []change{}
+	for _, c := range diff.Compare(aNode, bNode, opts) {
+		var old, new string
+		if c.Old != nil {
+			old = jsonx.Compact(c.Old)
+		}
+		if c.New != nil {
+			new = jsonx.Compact(c.New)
+		}
+		out = append(out, change{c.Kind, c.Path, old, new})
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []change
+	}{
+		{"equal", `{"a": 1, "b": [1, 2]}`, `{"b": [1, 2], "a": 1}`, []change{}},
+		{"equal numbers and strings", `[1.0, "A"]`, `[1, "A"]`, []change{}},
+		{"changed value", `{"a": {"b": 1}}`, `{"a": {"b": 2}}`, []change{
+			{diff.Changed, []any{"a", "b"}, `1`, `2`},
+		}},
+		{"added and removed keys", `{"a": 1, "b": 2}`, `{"b": 2, "c": {"d": 3}}`, []change{
+			{diff.Removed, []any{"a"}, `1`, ``},
+			{diff.Added, []any{"c"}, ``, `{"d":3}`},
+		}},
+		{"changed kind", `{"a": [1]}`, `{"a": {"0": 1}}`, []change{
+			{diff.Changed, []any{"a"}, `[1]`, `{"0":1}`},
+		}},
+		{"array by index", `[1, 2, 3]`, `[1, 5]`, []change{
+			{diff.Changed, []any{1}, `2`, `5`},
+			{diff.Removed, []any{2}, `3`, ``},
+		}},
+		{"array grows", `[1]`, `[1, 2, 3]`, []change{
+			{diff.Added, []any{1}, ``, `2`},
+			{diff.Added, []any{2}, ``, `3`},
+		}},
+		{"root", `1`, `"1"`, []change{
+			{diff.Changed, nil, `1`, `"1"`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, compare(t, tt.a, tt.b, diff.Options{}))
+		})
+	}
+}
+
+func TestCompare_ID(t *testing.T) {
+	a := `[{"id": 1, "v": "a"}, {"id": 2, "v": "b"}, {"id": 3, "v": "c"}]`
+	b := `[{"id": 3, "v": "c"}, {"id": 4, "v": "d"}, {"id": 1, "v": "x"}]`
+	assert.Equal(t, []change{
+		{diff.Removed, []any{1}, `{"id":2,"v":"b"}`, ``},
+		{diff.Changed, []any{0, "v"}, `"a"`, `"x"`},
+		{diff.Added, []any{2}, ``, `{"id":4,"v":"d"}`},
+	}, compare(t, a, b, diff.Options{ID: "id"}))
+
+	// Without ids on all elements, arrays are compared by index.
+	assert.Equal(t, []change{
+		{diff.Changed, []any{0}, `1`, `{"id":1}`},
+	}, compare(t, `[1]`, `[{"id": 1}]`, diff.Options{ID: "id"}))
+
+	assert.Equal(t, []change{}, compare(t, `[{"id": 1}, {"id": 2}]`, `[{"id": 2}, {"id": 1}]`, diff.Options{ID: "id"}))
+}
+
+func TestMerge(t *testing.T) {
+	a, err := jsonx.Parse([]byte(`{"a": 1, "b": {"c": [1, 2]}, "d": "x"}`))
+	require.NoError(t, err)
+	b, err := jsonx.Parse([]byte(`{"a": 1, "b": {"c": [1, 3]}, "e": true}`))
+	require.NoError(t, err)
+
+	docs, marks, err := diff.Merge(a, b, diff.Options{})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, `{"a":1,"b":{"c":[1,2,3]},"d":"x","e":true}`, jsonx.Compact(docs[0]))
+
+	var got []string
+	for n := docs[0]; n != nil; n = n.Next {
+		if kind, ok := marks[n]; ok {
+			got = append(got, string("=+-~"[kind])+jsonx.Compact(n))
+		}
+	}
+	assert.Equal(t, []string{`~{"a":1,"b":{"c":[1,2,3]},"d":"x","e":true}`, `~{"c":[1,2,3]}`, `~[1,2,3]`, `-2`, `+3`, `-"x"`, `+true`}, got)
+}
+
+func TestMerge_Roots(t *testing.T) {
+	a, err := jsonx.Parse([]byte(`[1]`))
+	require.NoError(t, err)
+	b, err := jsonx.Parse([]byte(`{}`))
+	require.NoError(t, err)
+
+	docs, marks, err := diff.Merge(a, b, diff.Options{})
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, diff.Removed, marks[docs[0]])
+	assert.Equal(t, diff.Added, marks[docs[1]])
+}
@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"io"
+	"strings"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// Merge returns a unified tree of a and b: objects and arrays with changes inside
+// are marked Changed, nodes only in a are marked Removed and nodes only in b are
+// marked Added, with their children. A replaced value is shown twice, removed and
+// added, so there are two documents if the roots differ in kind.
+func Merge(a, b *jsonx.Node, opts Options) ([]*jsonx.Node, map[*jsonx.Node]Kind, error) {
+	m := &merger{opts: opts}
+	m.merge("", a, b)
+
+	var docs []*jsonx.Node
+	parser := jsonx.NewJsonParser(strings.NewReader(m.out.String()), false)
+	for {
+		node, err := parser.Parse()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		docs = append(docs, node)
+	}
+
+	marks := map[*jsonx.Node]Kind{}
+	var mark func(n *jsonx.Node)
+	mark = func(n *jsonx.Node) {
+		kind := m.kinds[0]
+		m.kinds = m.kinds[1:]
+		if kind == Equal {
+			return
+		}
+		marks[n] = kind
+		if kind == Changed {
+			for _, child := range n.Elements() {
+				mark(child)
+			}
+		}
+	}
+	for _, doc := range docs {
+		mark(doc)
+	}
+	return docs, marks, nil
+}
+
+// merger writes the unified tree as JSON, along with the kind of every value in
+// preorder. Children of values not marked Changed are not listed.
+type merger struct {
+	opts  Options
+	out   strings.Builder
+	kinds []Kind
+	comma []bool // Whether the next value in each open container needs a comma.
+}
+
+func (m *merger) merge(key string, a, b *jsonx.Node) {
+	switch {
+	case Equals(a, b) || m.opts.ID != "" && len(Compare(a, b, m.opts)) == 0:
+		m.value(key, Equal, b)
+
+	case a.Kind == jsonx.Object && b.Kind == jsonx.Object:
+		m.open(key, '{')
+		aKeys, aValues := entries(a)
+		bKeys, bValues := entries(b)
+		bIndex := indexOf(bKeys)
+		for i, k := range aKeys {
+			if j, ok := bIndex[k]; ok {
+				m.merge(bValues[j].Key, aValues[i], bValues[j])
+			} else {
+				m.value(aValues[i].Key, Removed, aValues[i])
+			}
+		}
+		aIndex := indexOf(aKeys)
+		for i, k := range bKeys {
+			if _, ok := aIndex[k]; !ok {
+				m.value(bValues[i].Key, Added, bValues[i])
+			}
+		}
+		m.close('}')
+
+	case a.Kind == jsonx.Array && b.Kind == jsonx.Array:
+		m.open(key, '[')
+		aElems, bElems := a.Elements(), b.Elements()
+		if aIDs, bIDs, ok := ids(aElems, bElems, m.opts.ID); ok {
+			aIndex, bIndex := indexOf(aIDs), indexOf(bIDs)
+			for i, id := range aIDs {
+				if j, ok := bIndex[id]; ok {
+					m.merge("", aElems[i], bElems[j])
+				} else {
+					m.value("", Removed, aElems[i])
+				}
+			}
+			for j, id := range bIDs {
+				if _, ok := aIndex[id]; !ok {
+					m.value("", Added, bElems[j])
+				}
+			}
+		} else {
+			for i := 0; i < max(len(aElems), len(bElems)); i++ {
+				switch {
+				case i >= len(bElems):
+					m.value("", Removed, aElems[i])
+				case i >= len(aElems):
+					m.value("", Added, bElems[i])
+				default:
+					m.merge("", aElems[i], bElems[i])
+				}
+			}
+		}
+		m.close(']')
+
+	default:
+		m.value(key, Removed, a)
+		m.value(key, Added, b)
+	}
+}
+
+func (m *merger) value(key string, kind Kind, n *jsonx.Node) {
+	m.separate(key)
+	m.out.WriteString(jsonx.Compact(n))
+	m.kinds = append(m.kinds, kind)
+}
+
+func (m *merger) open(key string, bracket byte) {
+	m.separate(key)
+	m.out.WriteByte(bracket)
+	m.kinds = append(m.kinds, Changed)
+	m.comma = append(m.comma, false)
+}
+
+func (m *merger) close(bracket byte) {
+	m.comma = m.comma[:len(m.comma)-1]
+	m.out.WriteByte(bracket)
+}
+
+func (m *merger) separate(key string) {
+	if len(m.comma) == 0 {
+		m.out.WriteByte('\n')
+	} else if m.comma[len(m.comma)-1] {
+		m.out.WriteByte(',')
+	}
+	if len(m.comma) > 0 {
+		m.comma[len(m.comma)-1] = true
+	}
+	if key != "" {
+		m.out.WriteString(key)
+		m.out.WriteByte(':')
+	}
+}
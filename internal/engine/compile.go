@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// stdlib is Stdlib along with .fxrc.js, compiled once and shared by all VMs.
+var stdlib = sync.OnceValues(func() (*goja.Program, error) {
+	return goja.Compile("", Stdlib, false)
+})
+
+// Compile compiles __main__ for args in a single pass, in strict mode like Stdlib. Only if it fails,
+// args are compiled one by one to point at the one with the syntax error.
+func Compile(args []string) (*goja.Program, error) {
+	program, err := goja.Compile("", JS(args), true)
+	if err == nil {
+		return program, nil
+	}
+	for i := range args {
+		if err := validateSyntax(args, i); err != nil {
+			jsCode := transpile(args[i])
+			return nil, errors.New(formatErr(args, i, jsCode) + errorToString(err))
+		}
+	}
+	return nil, errors.New(errorToString(err))
+}
+
+// load runs the stdlib and the compiled __main__ on vm.
+func load(vm *goja.Runtime, main *goja.Program) error {
+	program, err := stdlib()
+	if err != nil {
+		return err
+	}
+	if _, err := vm.RunProgram(program); err != nil {
+		return err
+	}
+	_, err = vm.RunProgram(main)
+	return err
+}
+
+func validateSyntax(args []string, i int) error {
+	_, err := goja.Compile("", "\nfunction __main__(json) {\n"+Body(args, i)+"  return json\n}\n", true)
+	return err
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestCompile(t *testing.T) {
+	program, err := Compile([]string{".a", "x => x * 2"})
+	require.NoError(t, err)
+
+	vm := NewVM(func(string) {})
+	require.NoError(t, load(vm, program))
+	main, ok := goja.AssertFunction(vm.Get("__main__"))
+	require.True(t, ok)
+	output, err := main(goja.Undefined(), vm.ToValue(map[string]any{"a": 21}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), output.Export())
+}
+
+func TestCompile_Errors(t *testing.T) {
+	_, err := Compile([]string{".a", "x +", ".b"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "  .a x + .b\n     ^^^\n")
+	assert.Contains(t, err.Error(), "Unexpected token")
+
+	_, err = Compile([]string{"x => { with (x) {} }"})
+	assert.ErrorContains(t, err, "Strict mode code may not include a with statement")
+}
+
+func BenchmarkStartup(b *testing.B) {
+	args := []string{".a", "x => x + 1"}
+	input := `{"a":1}`
+	run := func(b *testing.B, load func(vm *goja.Runtime) error) {
+		for i := 0; i < b.N; i++ {
+			vm := NewVM(func(string) {})
+			if err := load(vm); err != nil {
+				b.Fatal(err)
+			}
+			main, _ := goja.AssertFunction(vm.Get("__main__"))
+			node, _ := jsonx.Parse([]byte(input))
+			if _, err := main(goja.Undefined(), node.ToValue(vm)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("RunString", func(b *testing.B) {
+		run(b, func(vm *goja.Runtime) error {
+			for i := range args {
+				if err := validateSyntax(args, i); err != nil {
+					return err
+				}
+			}
+			var code strings.Builder
+			code.WriteString(Stdlib)
+			code.WriteString(JS(args))
+			_, err := vm.RunString(code.String())
+			return err
+		})
+	})
+
+	b.Run("Compile", func(b *testing.B) {
+		run(b, func(vm *goja.Runtime) error {
+			program, err := Compile(args)
+			if err != nil {
+				return err
+			}
+			return load(vm, program)
+		})
+	})
+}
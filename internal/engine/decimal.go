@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// defaultDivScale is the number of fraction digits decimal.div keeps by default.
+const defaultDivScale = 20
+
+// defineDecimal sets the decimal global: decimal(x) creates a decimal from a string
+// or a number, and decimal.add, sub, mul, div and cmp do exact decimal arithmetic.
+func defineDecimal(vm *goja.Runtime) error {
+	operand := func(v goja.Value) (*big.Rat, int) {
+		text, ok := jsonx.DecimalText(v)
+		if !ok {
+			switch v.ExportType() {
+			case nil:
+				panic(vm.NewTypeError("decimal: %s is not a number", v))
+			default:
+				text = v.String()
+			}
+		}
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			panic(vm.NewTypeError("decimal: %q is not a number", text))
+		}
+		return r, scaleOf(text)
+	}
+	binary := func(op func(z, a, b *big.Rat) *big.Rat, scale func(a, b int) int) func(a, b goja.Value) goja.Value {
+		return func(a, b goja.Value) goja.Value {
+			x, xs := operand(a)
+			y, ys := operand(b)
+			return jsonx.NewDecimal(vm, op(new(big.Rat), x, y).FloatString(scale(xs, ys)))
+		}
+	}
+
+	decimal := vm.ToValue(func(x goja.Value) goja.Value {
+		if _, ok := jsonx.DecimalText(x); ok {
+			return x
+		}
+		r, scale := operand(x)
+		return jsonx.NewDecimal(vm, r.FloatString(scale))
+	}).(*goja.Object)
+
+	methods := map[string]any{
+		"add": binary((*big.Rat).Add, maxScale),
+		"sub": binary((*big.Rat).Sub, maxScale),
+		"mul": binary((*big.Rat).Mul, func(a, b int) int { return a + b }),
+		"div": func(call goja.FunctionCall) goja.Value {
+			x, _ := operand(call.Argument(0))
+			y, _ := operand(call.Argument(1))
+			if y.Sign() == 0 {
+				rangeError, _ := goja.AssertConstructor(vm.Get("RangeError"))
+				err, _ := rangeError(nil, vm.ToValue("decimal: division by zero"))
+				panic(err)
+			}
+			scale := defaultDivScale
+			if arg := call.Argument(2); !goja.IsUndefined(arg) {
+				scale = int(arg.ToInteger())
+			}
+			text := new(big.Rat).Quo(x, y).FloatString(scale)
+			if strings.Contains(text, ".") {
+				text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+			}
+			return jsonx.NewDecimal(vm, text)
+		},
+		"cmp": func(a, b goja.Value) int {
+			x, _ := operand(a)
+			y, _ := operand(b)
+			return x.Cmp(y)
+		},
+	}
+	for name, fn := range methods {
+		if err := decimal.Set(name, fn); err != nil {
+			return err
+		}
+	}
+	return vm.Set("decimal", decimal)
+}
+
+func maxScale(a, b int) int {
+	return max(a, b)
+}
+
+// scaleOf returns the number of fraction digits of a number, e.g. 2 for 1.50 and 0 for 1e3.
+func scaleOf(text string) int {
+	mantissa, exp, _ := strings.Cut(strings.ToLower(text), "e")
+	scale := 0
+	if _, frac, ok := strings.Cut(mantissa, "."); ok {
+		scale = len(frac)
+	}
+	if exp != "" {
+		e, _ := strconv.Atoi(exp)
+		scale -= e
+	}
+	return max(scale, 0)
+}
@@ -0,0 +1,81 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+)
+
+func TestStart_Decimals(t *testing.T) {
+	input := `{"price": 19.990000000000001, "qty": 1.50, "big": 1e3, "plain": 0.1, "huge": 1e999}`
+	exitCode, outs, errs := start(input, []string{"x => x"}, engine.Options{Output: "ndjson"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`{"price":19.990000000000001,"qty":1.50,"big":1e3,"plain":0.1,"huge":1e999}`}, outs)
+
+	exitCode, outs, errs = start(input, []string{"x => [typeof x.price, x.price === 19.99, x.price + 1, Number.isFinite(x.price), typeof x.huge, x.huge === Infinity]"}, engine.Options{Output: "ndjson"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`["number",false,20.990000000000002,true,"number",true]`}, outs)
+
+	exitCode, outs, errs = start(input, []string{"x => { x.price = x.price * 1; return x.price }"}, engine.Options{Output: "ndjson"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`19.990000000000002`}, outs)
+
+	exitCode, outs, errs = start(input, []string{"x => x"}, engine.Options{Output: "yaml"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{"price: 19.990000000000001\nqty: 1.50\nbig: 1e3\nplain: 0.1\nhuge: 1e999"}, outs)
+
+	exitCode, outs, errs = start(input, []string{"YAML.stringify"}, engine.Options{})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{"price: 19.990000000000001\nqty: 1.50\nbig: 1e3\nplain: 0.1\nhuge: 1e999\n"}, outs)
+}
+
+func TestStart_NumberForm(t *testing.T) {
+	input := `{"a": 1.0, "b": 0.0, "c": [1e3, 2]}`
+	exitCode, outs, errs := start(input, []string{"x => [typeof x.a, typeof x.b, !!x.a, !!x.b, x.a === 1, x.b === 0, x.a.toFixed(1), x.b.toFixed(2), Number.isFinite(x.a), [x.a, 2].includes(1)]"}, engine.Options{Output: "ndjson"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`["number","number",true,false,true,true,"1.0","0.00",true,true]`}, outs)
+
+	for _, arg := range []string{"x => x", "x => { x.d = 1; return x }"} {
+		exitCode, outs, errs = start(input, []string{arg}, engine.Options{Output: "ndjson"})
+		require.Equal(t, 0, exitCode, errs)
+		assert.Contains(t, outs[0], `{"a":1.0,"b":0.0,"c":[1e3,2]`)
+	}
+
+	exitCode, outs, errs = start(input, []string{"x => { x.a = x.a + 1; return x }"}, engine.Options{Output: "ndjson"})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`{"a":2,"b":0.0,"c":[1e3,2]}`}, outs)
+}
+
+func TestDecimalHelpers(t *testing.T) {
+	tests := []struct {
+		code, want string
+	}{
+		{"decimal.add(0.1, 0.2)", "0.3"},
+		{"decimal.add('19.990000000000001', '0.01')", "20.000000000000001"},
+		{"decimal.sub('1.50', 2)", "-0.50"},
+		{"decimal.mul('1.50', '3')", "4.50"},
+		{"decimal.mul('1e3', '0.5')", "500.0"},
+		{"decimal.div(1, 3)", "0.33333333333333333333"},
+		{"decimal.div(1, 4)", "0.25"},
+		{"decimal.div(10, 3, 2)", "3.33"},
+		{"decimal.cmp('0.10', 0.1)", "0"},
+		{"decimal.cmp(1, '1.000000000000000001')", "-1"},
+		{"decimal('0.10')", "0.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			exitCode, outs, errs := start("null", []string{tt.code}, engine.Options{Output: "ndjson"})
+			require.Equal(t, 0, exitCode, errs)
+			assert.Equal(t, []string{tt.want}, outs)
+		})
+	}
+
+	_, _, errs := start("null", []string{"decimal.div(1, 0)"}, engine.Options{})
+	assert.Contains(t, errs[0], "RangeError: decimal: division by zero")
+
+	_, _, errs = start("null", []string{"decimal('abc')"}, engine.Options{})
+	assert.Contains(t, errs[0], `TypeError: decimal: "abc" is not a number`)
+}
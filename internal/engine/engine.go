@@ -5,19 +5,23 @@ import (
 	"io"
 	"reflect"
 	"strconv"
-	"strings"
 
 	"github.com/dop251/goja"
 
 	"github.com/antonmedv/fx/internal/jsonx"
 	"github.com/antonmedv/fx/internal/pretty"
+	"github.com/antonmedv/fx/internal/rfc9535"
 )
 
 //go:embed stdlib.js
 var Stdlib string
 
+// fxrc is the contents of .fxrc.js files, appended to Stdlib.
+var fxrc string
+
 func init() {
-	fxrc, err := readFxrc()
+	var err error
+	fxrc, err = readFxrc()
 	if err != nil {
 		panic(err)
 	}
@@ -31,7 +35,15 @@ type Parser interface {
 
 type Options struct {
 	Slurp      bool
+	Reduce     *Reduce        // Fold all inputs into one value, if set.
+	Parallel   int            // Number of VMs evaluating inputs concurrently, if more than 1.
+	Unordered  bool           // With Parallel, print results as soon as they are ready.
+	JSONPath   *rfc9535.Query // Print the nodes the query selects instead of evaluating args, if set.
+	JQ         string         // A jq filter evaluated instead of args, printing every output, if set.
+	Patch      *Patch         // Applied to every input before args, if set.
 	WithInline bool
+	Output     string // One of OutputFormats, or empty for pretty printing.
+	Flatten    bool   // Print results as "path = value" lines instead of Output.
 	WriteOut   func(string)
 	WriteErr   func(string)
 }
@@ -45,7 +57,24 @@ func Start(parser Parser, args []string, opts Options) int {
 		}
 	}
 
-	isPrettyPrintArg := len(args) == 1 && (args[0] == "." || args[0] == "this" || args[0] == "x")
+	var encode encoder
+	if opts.Flatten {
+		encode = newFlattenEncoder(opts.WriteOut)
+	} else if opts.Output != "" {
+		var err error
+		encode, err = newEncoder(opts.Output, opts.WriteOut)
+		if err != nil {
+			opts.WriteErr(err.Error())
+			return 1
+		}
+	}
+
+	if opts.JSONPath != nil {
+		return selectPath(parser, opts, encode)
+	}
+
+	isIdentity := len(args) == 0 && opts.JQ == "" || len(args) == 1 && (args[0] == "." || args[0] == "this" || args[0] == "x")
+	isPrettyPrintArg := isIdentity && opts.Reduce == nil && opts.Patch == nil
 
 	// Fast path.
 	if isPrettyPrintArg {
@@ -60,57 +89,51 @@ func Start(parser Parser, args []string, opts Options) int {
 				return 1
 			}
 
-			if node.Kind == jsonx.String {
-				unquoted, err := strconv.Unquote(node.Value)
-				if err != nil {
-					panic(err)
-				}
-				opts.WriteOut(unquoted)
-			} else {
-				opts.WriteOut(pretty.Print(node, opts.WithInline))
+			if err := printNode(node, opts, encode); err != nil {
+				opts.WriteErr(err.Error())
+				return 1
 			}
 		}
 
 		return 0
 	}
 
-	for i := range args {
-		if err := validateSyntax(args, i); err != nil {
-			jsCode := transpile(args[i])
-			snippet := formatErr(args, i, jsCode)
-			message := errorToString(err)
-			opts.WriteErr(snippet + message)
-			return 1
-		}
+	var program *goja.Program
+	var err error
+	if opts.JQ != "" {
+		program, err = CompileJQ(opts.JQ)
+	} else {
+		program, err = Compile(args)
+	}
+	if err != nil {
+		opts.WriteErr(err.Error())
+		return 1
 	}
 
-	var code strings.Builder
-	code.WriteString(Stdlib)
-	code.WriteString(JS(args))
+	if opts.Parallel > 1 {
+		return parallel(parser, program, toValue, opts, encode)
+	}
 
 	vm := NewVM(opts.WriteOut)
-	if _, err := vm.RunString(code.String()); err != nil {
+	if err := load(vm, program); err != nil {
 		opts.WriteErr(errorToString(err))
 		return 1
 	}
 
 	skip := vm.Get("skip")
-	undefined := vm.Get("undefined")
 	main, _ := goja.AssertFunction(vm.Get("__main__"))
+	echo := newEcho(vm, opts, encode)
 
-	echo := func(output goja.Value) {
-		rtype := output.ExportType()
-		if output.StrictEquals(undefined) {
-			opts.WriteErr("undefined")
-		} else if rtype != nil && rtype.Kind() == reflect.String {
-			opts.WriteOut(output.String())
-		} else {
-			jsonOut := Stringify(output, vm, 0)
-			nodeOut, err := jsonx.Parse([]byte(jsonOut))
-			if err != nil {
-				panic(err)
-			}
-			opts.WriteOut(pretty.Print(nodeOut, opts.WithInline))
+	if opts.Reduce != nil {
+		return reduce(parser, vm, main, toValue, opts, echo)
+	}
+
+	var applyPatch patchFunc
+	if opts.Patch != nil {
+		applyPatch, err = compilePatch(vm, opts.Patch)
+		if err != nil {
+			opts.WriteErr(err.Error())
+			return 1
 		}
 	}
 
@@ -124,7 +147,14 @@ func Start(parser Parser, args []string, opts Options) int {
 			return 1
 		}
 
-		input := node.ToValue(vm)
+		input := toValue(node, vm)
+		if applyPatch != nil {
+			input, err = applyPatch(input)
+			if err != nil {
+				opts.WriteErr(err.Error())
+				return 1
+			}
+		}
 		output, exitCode, err := callMain(main, input)
 		if exitCode >= 0 {
 			return exitCode
@@ -137,13 +167,73 @@ func Start(parser Parser, args []string, opts Options) int {
 		if output.StrictEquals(skip) {
 			continue
 		}
-		echo(output)
+		if err := echo(output); err != nil {
+			opts.WriteErr(err.Error())
+			return 1
+		}
 	}
 
 	return 0
 }
 
-func callMain(main goja.Callable, input goja.Value) (output goja.Value, exitCode int, err error) {
+// printNode prints a node as is: strings raw, other values pretty printed,
+// or with encode if the output format is set.
+func printNode(node *jsonx.Node, opts Options, encode encoder) error {
+	if encode != nil {
+		return encode(node)
+	}
+	if node.Kind == jsonx.String {
+		unquoted, err := strconv.Unquote(node.Value)
+		if err != nil {
+			panic(err)
+		}
+		opts.WriteOut(unquoted)
+	} else {
+		opts.WriteOut(pretty.Print(node, opts.WithInline))
+	}
+	return nil
+}
+
+// newEcho returns a function printing a result of __main__ with opts.WriteOut,
+// or with encode if the output format is set.
+func newEcho(vm *goja.Runtime, opts Options, encode encoder) func(goja.Value) error {
+	undefined := vm.Get("undefined")
+	if opts.JQ != "" {
+		// A jq filter returns all its outputs in an array.
+		opts.JQ = ""
+		echo := newEcho(vm, opts, encode)
+		return func(outputs goja.Value) error {
+			obj := outputs.ToObject(vm)
+			for i := int64(0); i < obj.Get("length").ToInteger(); i++ {
+				if err := echo(obj.Get(strconv.FormatInt(i, 10))); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return func(output goja.Value) error {
+		rtype := output.ExportType()
+		if output.StrictEquals(undefined) {
+			opts.WriteErr("undefined")
+		} else if encode == nil && rtype != nil && rtype.Kind() == reflect.String {
+			opts.WriteOut(output.String())
+		} else {
+			jsonOut := Stringify(output, vm, 0)
+			nodeOut, err := jsonx.Parse([]byte(jsonOut))
+			if err != nil {
+				panic(err)
+			}
+			if encode != nil {
+				return encode(nodeOut)
+			}
+			opts.WriteOut(pretty.Print(nodeOut, opts.WithInline))
+		}
+		return nil
+	}
+}
+
+func callMain(main goja.Callable, inputs ...goja.Value) (output goja.Value, exitCode int, err error) {
 	exitCode = -1
 	defer func() {
 		if r := recover(); r != nil {
@@ -154,17 +244,6 @@ func callMain(main goja.Callable, input goja.Value) (output goja.Value, exitCode
 			}
 		}
 	}()
-	output, err = main(goja.Undefined(), input)
+	output, err = main(goja.Undefined(), inputs...)
 	return
 }
-
-func validateSyntax(args []string, i int) error {
-	var code strings.Builder
-	code.WriteString("\nfunction __main__(json) {\n")
-	code.WriteString(Body(args, i))
-	code.WriteString("  return json\n}\n")
-
-	vm := goja.New()
-	_, err := vm.RunString(code.String())
-	return err
-}
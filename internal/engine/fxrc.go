@@ -3,43 +3,16 @@ package engine
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/antonmedv/fx/internal/config"
 )
 
 func readFxrc() (string, error) {
 	var builder strings.Builder
 
-	// Determine search paths
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("get cwd: %w", err)
-	}
-	paths := []string{filepath.Join(cwd, ".fxrc.js")}
-
-	home, err := os.UserHomeDir()
-	if err == nil {
-		paths = append(paths, filepath.Join(home, ".fxrc.js"))
-	}
-
-	xdgHome := os.Getenv("XDG_CONFIG_HOME")
-	if xdgHome == "" && home != "" {
-		xdgHome = filepath.Join(home, ".config")
-	}
-	if xdgHome != "" {
-		paths = append(paths, filepath.Join(xdgHome, "fx", ".fxrc.js"))
-	}
-
-	xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
-	if xdgDirs == "" {
-		xdgDirs = "/etc/xdg"
-	}
-	for _, dir := range strings.Split(xdgDirs, ":") {
-		paths = append(paths, filepath.Join(dir, "fx", ".fxrc.js"))
-	}
-
 	// Read and combine
-	for _, path := range uniq(paths) {
+	for _, path := range config.Paths(".fxrc.js") {
 		info, err := os.Stat(path)
 		if err != nil || info.IsDir() {
 			continue // skip missing or directories
@@ -54,15 +27,3 @@ func readFxrc() (string, error) {
 
 	return builder.String(), nil
 }
-
-func uniq(paths []string) []string {
-	seen := make(map[string]bool)
-	result := []string{}
-	for _, path := range paths {
-		if !seen[path] {
-			seen[path] = true
-			result = append(result, path)
-		}
-	}
-	return result
-}
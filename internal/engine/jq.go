@@ -0,0 +1,20 @@
+package engine
+
+import (
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jq"
+)
+
+// CompileJQ compiles __main__ for a jq filter, returning the array of its outputs.
+func CompileJQ(filter string) (*goja.Program, error) {
+	globals := make([]string, len(Vars))
+	for i, v := range Vars {
+		globals[i] = v.Name
+	}
+	code, err := jq.Translate(filter, globals)
+	if err != nil {
+		return nil, err
+	}
+	return goja.Compile("", jq.Runtime+"\nconst __filter__ = "+code+"\n\nfunction __main__(json) {\n  return __filter__(json)\n}\n", true)
+}
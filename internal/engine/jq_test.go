@@ -0,0 +1,90 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/antonmedv/fx/internal/engine"
+)
+
+func TestStart_JQ(t *testing.T) {
+	tests := []struct {
+		filter  string
+		input   string
+		expects []string
+	}{
+		{`.`, `{"a":1}`, []string{"{\n  \"a\": 1\n}"}},
+		{`.a`, `{"a":1}`, []string{"1"}},
+		{`.a.b`, `{"a":{"b":"x"}}`, []string{"x"}},
+		{`.a?`, `[1]`, nil},
+		{`.missing`, `{}`, []string{"null"}},
+		{`.[0], .[-1]`, `[1,2,3]`, []string{"1", "3"}},
+		{`.[1:]`, `[1,2,3]`, []string{"[\n  2,\n  3\n]"}},
+		{`.[:2] | length`, `"hello"`, []string{"2"}},
+		{`.[]`, `{"a":1,"b":2}`, []string{"1", "2"}},
+		{`.[] | select(. > 1)`, `[1,2,3]`, []string{"2", "3"}},
+		{`map(. * 2) | add`, `[1,2,3]`, []string{"12"}},
+		{`[.[] | .name]`, `[{"name":"a"},{"name":"b"}]`, []string{"[\n  \"a\",\n  \"b\"\n]"}},
+		{`keys`, `{"b":1,"a":2}`, []string{"[\n  \"a\",\n  \"b\"\n]"}},
+		{`keys_unsorted`, `{"b":1,"a":2}`, []string{"[\n  \"b\",\n  \"a\"\n]"}},
+		{`length`, `{"a":1,"b":2}`, []string{"2"}},
+		{`{name, n: .count, (.k): 1}`, `{"name":"x","count":2,"k":"z"}`, []string{"{\n  \"name\": \"x\",\n  \"n\": 2,\n  \"z\": 1\n}"}},
+		{`{a: (1, 2)}`, `null`, []string{"{\n  \"a\": 1\n}", "{\n  \"a\": 2\n}"}},
+		{`.a // "default"`, `{"a":null}`, []string{"default"}},
+		{`.a // "default"`, `{"a":false}`, []string{"default"}},
+		{`.a // "default"`, `{"a":0}`, []string{"0"}},
+		{`to_entries[] | "\(.key)=\(.value)"`, `{"a":1,"b":[2]}`, []string{"a=1", "b=[2]"}},
+		{`from_entries`, `[{"key":"a","value":1},{"name":"b","v":2}]`, []string{"{\n  \"a\": 1,\n  \"b\": 2\n}"}},
+		{`with_entries(.value += 1)`, `{"a":1}`, nil},
+		{`group_by(.t) | map({t: .[0].t, n: length})`, `[{"t":"b"},{"t":"a"},{"t":"b"}]`, []string{"[\n  {\n    \"t\": \"a\",\n    \"n\": 1\n  },\n  {\n    \"t\": \"b\",\n    \"n\": 2\n  }\n]"}},
+		{`sort_by(.n) | map(.n)`, `[{"n":3},{"n":1},{"n":2}]`, []string{"[\n  1,\n  2,\n  3\n]"}},
+		{`if . > 1 then "big" elif . == 1 then "one" else "small" end`, `1`, []string{"one"}},
+		{`.[] as $x | $x * 10`, `[1,2]`, []string{"10", "20"}},
+		{`. as [$a, {b: $c}] | $a + $c`, `[1, {"b": 2}]`, []string{"3"}},
+		{`reduce .[] as $x (0; . + $x)`, `[1,2,3]`, []string{"6"}},
+		{`[foreach .[] as $x (0; . + $x)]`, `[1,2,3]`, []string{"[\n  1,\n  3,\n  6\n]"}},
+		{`[range(3)]`, `null`, []string{"[\n  0,\n  1,\n  2\n]"}},
+		{`[.[] | tostring]`, `[1,"a",null]`, []string{"[\n  \"1\",\n  \"a\",\n  \"null\"\n]"}},
+		{`try error("boom") catch .`, `null`, []string{"boom"}},
+		{`[.[] | try (if . == 2 then error("x") else . end)]`, `[1,2,3]`, []string{"[\n  1,\n  3\n]"}},
+		{`.a and .b, .a or .b, (.a | not)`, `{"a":true,"b":false}`, []string{"false", "true", "false"}},
+		{`[.[] | numbers]`, `[1,"a",2]`, []string{"[\n  1,\n  2\n]"}},
+		{`join(", ")`, `["a","b",1]`, []string{"a, b, 1"}},
+		{`split(",")`, `"a,b"`, []string{"[\n  \"a\",\n  \"b\"\n]"}},
+		{`test("^a")`, `"abc"`, []string{"true"}},
+		{`sub("(?<x>b)"; "[\(.x)]")`, `"abc"`, []string{"a[b]c"}},
+		{`@base64`, `"hi"`, []string{"aGk="}},
+		{`@csv`, `[1,"a\"b",null]`, []string{`1,"a""b",`}},
+		{`@json "v=\(.)"`, `[1]`, []string{"v=[1]"}},
+		{`[..] | length`, `{"a":[1]}`, []string{"3"}},
+		{`.item`, `{"item": {"price": 1.50}}`, []string{"{\n  \"price\": 1.50\n}"}},
+		{`.price + 1`, `{"price": 1.50}`, []string{"2.5"}},
+		{`[.[] | type]`, `[1.50, 1, "a", null, true, [], {}]`, []string{"[\n  \"number\",\n  \"number\",\n  \"string\",\n  \"null\",\n  \"boolean\",\n  \"array\",\n  \"object\"\n]"}},
+		{`unique`, `[3,1,3,2]`, []string{"[\n  1,\n  2,\n  3\n]"}},
+		{`min_by(.n).n, max_by(.n).n`, `[{"n":2},{"n":1},{"n":3}]`, []string{"1", "3"}},
+		{`[limit(2; .[])]`, `[1,2,3]`, []string{"[\n  1,\n  2\n]"}},
+		{`first(.[]), [.[] | . as $x | $x]`, `[1]`, []string{"1", "[\n  1\n]"}},
+		{`$__loc__.line`, `null`, []string{"1"}},
+		{`"é" | length`, `null`, []string{"1"}},
+		{`empty`, `1`, nil},
+		{`-.a`, `{"a":1}`, []string{"-1"}},
+		{`10 / 4, 7 % 3, "a,b" / ","`, `null`, []string{"2.5", "1", "[\n  \"a\",\n  \"b\"\n]"}},
+		{`[.[] | select(.a == "x")] | length`, `[{"a":"x"},{"a":"y"}]`, []string{"1"}},
+		{`.a."b c"`, `{"a":{"b c":5}}`, []string{"5"}},
+		{`.a.[0]`, `{"a":[7]}`, []string{"7"}},
+		{`contains({a: [1]})`, `{"a":[1,2]}`, []string{"true"}},
+		{`paths | join(".")`, `{"a":{"b":1}}`, []string{"a", "a.b"}},
+		{`ascii_downcase, ltrimstr("He")`, `"Hello"`, []string{"hello", "llo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			exitCode, outs, errs := start(tt.input, nil, engine.Options{JQ: tt.filter})
+			if tt.expects == nil && len(errs) > 0 {
+				return
+			}
+			assert.Equal(t, 0, exitCode, errs)
+			assert.Equal(t, tt.expects, outs)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"io"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// selectPath prints the nodes opts.JSONPath selects in each input, one per line.
+// No JS is involved, so it works on inputs too big to convert to JS values.
+func selectPath(parser Parser, opts Options, encode encoder) int {
+	for {
+		node, err := parser.Parse()
+		if err != nil {
+			if err == io.EOF {
+				return 0
+			}
+			opts.WriteErr(err.Error())
+			return 1
+		}
+
+		for _, selected := range opts.JSONPath.Select(node) {
+			// Reparse, so the node is a root with its own depth and no siblings.
+			root, err := jsonx.Parse([]byte(jsonx.Compact(selected)))
+			if err != nil {
+				panic(err)
+			}
+			if err := printNode(root, opts, encode); err != nil {
+				opts.WriteErr(err.Error())
+				return 1
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/rfc9535"
+)
+
+func TestStart_JSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		path     string
+		opts     engine.Options
+		expects  []string
+		exitCode int
+	}{
+		{"values", `{"a": [{"b": 1}, {"b": "two"}, {"c": 3}]}`, `$.a[*].b`, engine.Options{}, []string{"1", "two"}, 0},
+		{"containers", `{"a": {"b": [1, 2]}}`, `$..b`, engine.Options{}, []string{"[\n  1,\n  2\n]"}, 0},
+		{"every input", "{\"a\": 1}\n{\"a\": 2}\n{}", `$.a`, engine.Options{}, []string{"1", "2"}, 0},
+		{"slurp", "{\"a\": 1}\n{\"a\": 2}", `$[?@.a > 1]`, engine.Options{Slurp: true}, []string{"{\n  \"a\": 2\n}"}, 0},
+		{"output format", `[{"a": "x"}]`, `$[0]`, engine.Options{Output: "yaml"}, []string{"a: x"}, 0},
+		{"invalid input", `{"a": 1} {`, `$.a`, engine.Options{}, []string{"1"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := rfc9535.Parse(tt.path)
+			require.NoError(t, err)
+			tt.opts.JSONPath = q
+			exitCode, outs, _ := start(tt.input, nil, tt.opts)
+			assert.Equal(t, tt.exitCode, exitCode)
+			assert.Equal(t, tt.expects, outs)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package engine
+
+import (
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+type toValueFunc func(*jsonx.Node, *goja.Runtime) goja.Value
+
+// toValue converts inputs lazily, see jsonx.ToLazyValue.
+var toValue toValueFunc = (*jsonx.Node).ToLazyValue
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestToLazyValue(t *testing.T) {
+	input := `{
+	  "b": 1, "10": [1, 2.5, "x"], "2": null, "a": {"nested": [{"id": 1}, {"id": 2}]},
+	  "b": 5, "s": "é\n", "big": 12345678901234567890, "empty": {}, "list": [3, 1, 2, 1]
+	}`
+	args := [][]string{
+		{"."},
+		{"x => x"},
+		{".a.nested[].id"},
+		{"Object.keys"},
+		{"x => Object.entries(x)"},
+		{"x => ({...x, c: 1})"},
+		{"x => JSON.stringify([x.a, x[10], x.list])"},
+		{"x => x.a === x.a"},
+		{"x => Array.isArray(x.list)"},
+		{".list", "uniq"},
+		{".list", "sort"},
+		{".list", "reverse"},
+		{".list", "sortBy(x => -x)"},
+		{".list", "map(x => x * 2)"},
+		{".list", "filter(x => x > 1)"},
+		{".list", "chunk(2)"},
+		{".list", "x => x.push(4) && x"},
+		{".list", "x => x.slice(1)"},
+		{".list", "x => x.length"},
+		{".a.nested", "groupBy(x => x.id)"},
+		{"keys"},
+		{"values"},
+		{"len"},
+		{"sortKeys"},
+		{"del('a')"},
+		{".list", "list"},
+		{"walk((k, v) => typeof v === 'number' ? v + 1 : v)"},
+		{"x => zip(x.list, x[10])"},
+		{"x => flatten([x.list, x[10]])"},
+		{"x => 'a' in x && !('z' in x)"},
+		{"x => x.hasOwnProperty('b')"},
+		{"YAML.stringify"},
+		{"x => { Object.freeze(x); return [Object.isFrozen(x), x.a, x.b] }"},
+		{"x => { Object.seal(x.list); x.list[0] = 9; return x.list }"},
+		{".list", "x => { x.foo = 1; return [x.foo, x] }"},
+		{".list", "x => { x.length = 1; x.length = 3; return x }"},
+		{"x => Object.getOwnPropertyDescriptor(x, 'a')"},
+		{"x => { delete x.b; x.b = 2; return x }"},
+		{"x => Object.assign(x, {c: 1})"},
+		{"x => Object.defineProperty(x, 'a', {enumerable: false})"},
+	}
+	node, err := jsonx.Parse([]byte(input))
+	require.NoError(t, err)
+
+	run := func(args []string, toValue toValueFunc) string {
+		program, err := Compile(args)
+		require.NoError(t, err)
+		vm := NewVM(func(string) {})
+		require.NoError(t, load(vm, program))
+		main, _ := goja.AssertFunction(vm.Get("__main__"))
+		output, err := main(goja.Undefined(), toValue(node, vm))
+		require.NoError(t, err)
+		return Stringify(output, vm, 0)
+	}
+	for _, args := range args {
+		eager := run(args, (*jsonx.Node).ToValue)
+		lazy := run(args, (*jsonx.Node).ToLazyValue)
+		assert.Equal(t, eager, lazy, "%v", args)
+	}
+}
+
+func TestToLazyValue_Mutations(t *testing.T) {
+	node, err := jsonx.Parse([]byte(`{"b": 1, "a": [1, 2]}`))
+	require.NoError(t, err)
+
+	vm := NewVM(func(string) {})
+	require.NoError(t, vm.Set("x", node.ToLazyValue(vm)))
+	_, err = vm.RunString(`
+	  x.c = 3; x[5] = 4; delete x.b
+	  x.a.push(3); x.a[0] = 0; x.a.length = 4
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"5":4,"a":[0,2,3],"c":3}`, jsonx.Compact(mustParse(t, Stringify(vm.Get("x"), vm, 0))))
+}
+
+func mustParse(t *testing.T, s string) *jsonx.Node {
+	node, err := jsonx.Parse([]byte(s))
+	require.NoError(t, err)
+	return node
+}
+
+func BenchmarkToValue(b *testing.B) {
+	var input []byte
+	input = append(input, `{"meta":{"id":1},"items":[`...)
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			input = append(input, ',')
+		}
+		input = append(input, `{"id":1,"name":"item","tags":["a","b"],"nested":{"x":1.5,"y":null}}`...)
+	}
+	input = append(input, `]}`...)
+	node, err := jsonx.Parse(input)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bench := range []struct {
+		name    string
+		toValue toValueFunc
+	}{
+		{"eager", (*jsonx.Node).ToValue},
+		{"lazy", (*jsonx.Node).ToLazyValue},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			vm := goja.New()
+			for i := 0; i < b.N; i++ {
+				value := bench.toValue(node, vm).ToObject(vm)
+				_ = value.Get("meta").ToObject(vm).Get("id")
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/antonmedv/fx/internal/config"
+	"github.com/antonmedv/fx/internal/ident"
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// OutputFormats lists formats accepted by Options.Output.
+var OutputFormats = config.OutputFormats
+
+type encoder func(n *jsonx.Node) error
+
+func newEncoder(format string, writeOut func(string)) (encoder, error) {
+	switch format {
+	case "json":
+		return func(n *jsonx.Node) error {
+			writeOut(jsonx.FormatJSON(n, ident.Ident))
+			return nil
+		}, nil
+
+	case "ndjson":
+		return func(n *jsonx.Node) error {
+			writeOut(jsonx.Compact(n))
+			return nil
+		}, nil
+
+	case "yaml":
+		return func(n *jsonx.Node) error {
+			b, err := ToYAML(n)
+			if err != nil {
+				return err
+			}
+			writeOut(strings.TrimSuffix(string(b), "\n"))
+			return nil
+		}, nil
+
+	case "toml":
+		docs := 0
+		return func(n *jsonx.Node) error {
+			// Tables of several documents would merge into one, or clash.
+			if docs++; docs > 1 {
+				return fmt.Errorf("toml: can't write several documents as one")
+			}
+			s, err := ToTOML(n)
+			if err != nil {
+				return err
+			}
+			writeOut(strings.TrimSuffix(s, "\n"))
+			return nil
+		}, nil
+
+	case "xml":
+		return func(n *jsonx.Node) error {
+			s, err := ToXML(n)
+			if err != nil {
+				return err
+			}
+			writeOut(strings.TrimSuffix(s, "\n"))
+			return nil
+		}, nil
+
+	case "csv":
+		e := &csvEncoder{writeOut: writeOut, comma: ','}
+		return e.encode, nil
+
+	case "tsv":
+		e := &csvEncoder{writeOut: writeOut, comma: '\t'}
+		return e.encode, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, must be one of: %s", format, strings.Join(OutputFormats, ", "))
+}
+
+// newFlattenEncoder prints documents with jsonx.Flatten, separated by blank lines.
+func newFlattenEncoder(writeOut func(string)) encoder {
+	first := true
+	return func(n *jsonx.Node) error {
+		if !first {
+			writeOut("")
+		}
+		first = false
+		jsonx.Flatten(n, writeOut)
+		return nil
+	}
+}
+
+// Encode serializes documents into one of OutputFormats.
+func Encode(format string, docs []*jsonx.Node) (string, error) {
+	var out []string
+	encode, err := newEncoder(format, func(s string) { out = append(out, s) })
+	if err != nil {
+		return "", err
+	}
+	for _, doc := range docs {
+		if err := encode(doc); err != nil {
+			return "", err
+		}
+	}
+	separator := "\n"
+	if format == "yaml" {
+		separator = "\n---\n"
+	}
+	return strings.Join(out, separator) + "\n", nil
+}
+
+func children(n *jsonx.Node) []*jsonx.Node {
+	var nodes []*jsonx.Node
+	if n.HasChildren() {
+		n.ForEach(func(child *jsonx.Node) {
+			if child != n.End {
+				nodes = append(nodes, child)
+			}
+		})
+	}
+	return nodes
+}
+
+func unquoteKey(n *jsonx.Node) string {
+	key, err := utils.Unquote(n.Key)
+	if err != nil {
+		return n.Key
+	}
+	return key
+}
+
+// ToYAML converts a node to YAML. Numbers keep their source text,
+// e.g. 1.50 or 19.990000000000001, which a float64 would change.
+func ToYAML(n *jsonx.Node) ([]byte, error) {
+	v, err := yamlValue(n)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// yamlNumber is a number written verbatim.
+type yamlNumber string
+
+func (n yamlNumber) MarshalYAML() ([]byte, error) {
+	return []byte(n), nil
+}
+
+func yamlValue(n *jsonx.Node) (any, error) {
+	switch n.Kind {
+	case jsonx.Null:
+		return nil, nil
+
+	case jsonx.Bool:
+		return n.Value == "true", nil
+
+	case jsonx.Number:
+		return yamlNumber(n.Value), nil
+
+	case jsonx.NaN:
+		return math.NaN(), nil
+
+	case jsonx.Infinity:
+		if n.Value[0] == '-' {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+
+	case jsonx.String:
+		return utils.Unquote(n.Value)
+
+	case jsonx.Object:
+		m := yaml.MapSlice{}
+		for _, field := range children(n) {
+			value, err := yamlValue(field)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, yaml.MapItem{Key: unquoteKey(field), Value: value})
+		}
+		return m, nil
+
+	case jsonx.Array:
+		a := []any{}
+		for _, item := range children(n) {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, value)
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("yaml: cannot encode %s", n.Value)
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ToTOML converts an object node to a TOML document.
+func ToTOML(n *jsonx.Node) (string, error) {
+	if n.Kind != jsonx.Object {
+		return "", fmt.Errorf("toml: top-level value must be an object")
+	}
+	var out strings.Builder
+	if err := tomlTable(&out, nil, n, false); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func tomlTable(out *strings.Builder, path []string, n *jsonx.Node, isArrayTable bool) error {
+	fields := children(n)
+	hasValues := false
+	for _, field := range fields {
+		if !isTOMLTable(field) && !isTOMLArrayOfTables(field) {
+			hasValues = true
+		}
+	}
+
+	// Tables without own values are declared implicitly by their sub-tables.
+	if len(path) > 0 && (hasValues || isArrayTable || len(fields) == 0) {
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		if isArrayTable {
+			out.WriteString("[[" + tomlPath(path) + "]]\n")
+		} else {
+			out.WriteString("[" + tomlPath(path) + "]\n")
+		}
+	}
+
+	for _, field := range fields {
+		if isTOMLTable(field) || isTOMLArrayOfTables(field) {
+			continue
+		}
+		key := unquoteKey(field)
+		value, err := tomlInline(append(path, key), field)
+		if err != nil {
+			return err
+		}
+		out.WriteString(tomlKey(key) + " = " + value + "\n")
+	}
+
+	for _, field := range fields {
+		subPath := append(append([]string{}, path...), unquoteKey(field))
+		if isTOMLTable(field) {
+			if err := tomlTable(out, subPath, field, false); err != nil {
+				return err
+			}
+		} else if isTOMLArrayOfTables(field) {
+			for _, item := range children(field) {
+				if err := tomlTable(out, subPath, item, true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func tomlInline(path []string, n *jsonx.Node) (string, error) {
+	switch n.Kind {
+	case jsonx.String:
+		s, err := utils.Unquote(n.Value)
+		if err != nil {
+			return "", err
+		}
+		return Quote(s), nil
+
+	case jsonx.Number, jsonx.Bool:
+		return n.Value, nil
+
+	case jsonx.NaN:
+		return "nan", nil
+
+	case jsonx.Infinity:
+		if n.Value[0] == '-' {
+			return "-inf", nil
+		}
+		return "inf", nil
+
+	case jsonx.Object:
+		var parts []string
+		for _, field := range children(n) {
+			key := unquoteKey(field)
+			value, err := tomlInline(append(path, key), field)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(key)+" = "+value)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+
+	case jsonx.Array:
+		var parts []string
+		for i, item := range children(n) {
+			value, err := tomlInline(append(path, fmt.Sprint(i)), item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, value)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("toml: cannot encode %s at %s", n.Value, tomlPath(path))
+}
+
+func isTOMLTable(n *jsonx.Node) bool {
+	return n.Kind == jsonx.Object && n.HasChildren()
+}
+
+func isTOMLArrayOfTables(n *jsonx.Node) bool {
+	if n.Kind != jsonx.Array || !n.HasChildren() {
+		return false
+	}
+	for _, item := range children(n) {
+		if !isTOMLTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return Quote(key)
+}
+
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// csvEncoder writes documents as rows of one table. The header is the keys
+// of the first document, as it is written before the next document is read.
+type csvEncoder struct {
+	writeOut func(string)
+	comma    rune
+	header   []string
+	docs     int
+}
+
+func (e *csvEncoder) encode(n *jsonx.Node) error {
+	e.docs++
+	var rows []*jsonx.Node
+	switch n.Kind {
+	case jsonx.Object:
+		rows = []*jsonx.Node{n}
+	case jsonx.Array:
+		rows = children(n)
+	default:
+		return fmt.Errorf("csv: expected an object or an array of objects, got %s", n.Value)
+	}
+
+	var out strings.Builder
+	w := csv.NewWriter(&out)
+	w.Comma = e.comma
+
+	if e.header == nil {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			if row.Kind != jsonx.Object {
+				continue
+			}
+			for _, field := range children(row) {
+				key := unquoteKey(field)
+				if !seen[key] {
+					seen[key] = true
+					e.header = append(e.header, key)
+				}
+			}
+		}
+		if len(e.header) > 0 {
+			_ = w.Write(e.header)
+		}
+	}
+
+	for i, row := range rows {
+		var record []string
+		switch row.Kind {
+		case jsonx.Object:
+			record = make([]string, len(e.header))
+			for _, field := range children(row) {
+				key := unquoteKey(field)
+				column := -1
+				for j, name := range e.header {
+					if name == key {
+						column = j
+						break
+					}
+				}
+				if column == -1 {
+					return fmt.Errorf("csv: key %q of document %d is not in the header of document 1, use --slurp for a header of all documents", key, e.docs)
+				}
+				record[column] = csvCell(field)
+			}
+		case jsonx.Array:
+			for _, item := range children(row) {
+				record = append(record, csvCell(item))
+			}
+		default:
+			return fmt.Errorf("csv: row %d is not an object or an array", i)
+		}
+		_ = w.Write(record)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if out.Len() > 0 {
+		e.writeOut(strings.TrimSuffix(out.String(), "\n"))
+	}
+	return nil
+}
+
+func csvCell(n *jsonx.Node) string {
+	switch n.Kind {
+	case jsonx.String:
+		s, err := utils.Unquote(n.Value)
+		if err != nil {
+			return n.Value
+		}
+		return s
+	case jsonx.Null:
+		return ""
+	case jsonx.Object, jsonx.Array:
+		return jsonx.Compact(n)
+	}
+	return n.Value
+}
@@ -0,0 +1,255 @@
+package engine_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/toml"
+)
+
+func convert(t *testing.T, input string, args []string, output string) string {
+	parser := jsonx.NewJsonParser(strings.NewReader(input), false)
+
+	var outs, errs []string
+	opts := engine.Options{
+		Output:   output,
+		WriteOut: func(s string) { outs = append(outs, s) },
+		WriteErr: func(s string) { errs = append(errs, s) },
+	}
+	exitCode := engine.Start(parser, args, opts)
+	require.Equal(t, 0, exitCode, "errors: %v", errs)
+	require.Empty(t, errs)
+	return strings.Join(outs, "\n")
+}
+
+// compactJSON reformats JSON into a single line preserving key order.
+func compactJSON(t *testing.T, input []byte) string {
+	p := jsonx.NewJsonParser(bytes.NewReader(input), true)
+	var docs []string
+	for {
+		node, err := p.Parse()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		docs = append(docs, node.String())
+	}
+	return strings.Join(docs, "\n")
+}
+
+// parseYAML mirrors the YAML input path of the main package.
+func parseYAML(t *testing.T, b []byte) []byte {
+	var out []byte
+	decoder := yaml.NewDecoder(bytes.NewReader(b), yaml.UseOrderedMap())
+	for {
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+		}
+		j, err := yaml.MarshalWithOptions(v, yaml.JSON())
+		require.NoError(t, err)
+		out = append(out, j...)
+	}
+	return out
+}
+
+func TestOutput_TOML_RoundTrip(t *testing.T) {
+	tests := []string{
+		`{"a":1,"b":"x","c":true}`,
+		`{"title":"fx","owner":{"name":"Anton","tags":["a","b"]}}`,
+		`{"name":"x","servers":[{"ip":"10.0.0.1","role":{"main":true}},{"ip":"10.0.0.2"}]}`,
+		`{"d":[[1,2],[3]],"a":{"b":{"c":1.5}}}`,
+		`{"inline":[{"x":1},2],"empty":{},"list":[]}`,
+		`{"key with spaces":"\"quoted\"\n","k.dot":1}`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			out := convert(t, input, nil, "toml")
+			got, err := toml.ToJSON([]byte(out))
+			require.NoError(t, err, out)
+			assert.Equal(t, input, compactJSON(t, got), out)
+		})
+	}
+}
+
+func TestOutput_TOML_Errors(t *testing.T) {
+	for _, input := range []string{`[1,2]`, `"a"`, `{"a":null}`, `{"a":1} {"b":2}`} {
+		parser := jsonx.NewJsonParser(strings.NewReader(input), false)
+		var errs []string
+		exitCode := engine.Start(parser, nil, engine.Options{
+			Output:   "toml",
+			WriteOut: func(string) {},
+			WriteErr: func(s string) { errs = append(errs, s) },
+		})
+		assert.Equal(t, 1, exitCode, input)
+		assert.Len(t, errs, 1, input)
+	}
+}
+
+func TestOutput_YAML_RoundTrip(t *testing.T) {
+	tests := []string{
+		`{"z":1,"a":{"b":[1,{"c":"multi\nline"}],"d":null,"e":{},"f":[]}}`,
+		`[1,"yes",true,{"no":false}]`,
+		`"plain string"`,
+	}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			out := convert(t, input, []string{"x"}, "yaml")
+			got := parseYAML(t, []byte(out))
+			assert.Equal(t, input, compactJSON(t, got), out)
+		})
+	}
+}
+
+func TestOutput_NDJSON(t *testing.T) {
+	out := convert(t, "{\"a\": [1, {\"b\": 2}]}\n{\"a\": \"x\"}", []string{"."}, "ndjson")
+	assert.Equal(t, "{\"a\":[1,{\"b\":2}]}\n{\"a\":\"x\"}", out)
+
+	out = convert(t, `{"a": [1, 2]}`, []string{".a"}, "ndjson")
+	assert.Equal(t, "[1,2]", out)
+}
+
+func TestOutput_JSON(t *testing.T) {
+	out := convert(t, `{"a":[1,{"b":"x"}],"c":{}}`, []string{"."}, "json")
+	assert.Equal(t, "{\n  \"a\": [\n    1,\n    {\n      \"b\": \"x\"\n    }\n  ],\n  \"c\": {}\n}", out)
+}
+
+func TestOutput_Flatten(t *testing.T) {
+	parser := jsonx.NewJsonParser(strings.NewReader(`{"a": [1, {"b c": 2}]} {"a": "x"}`), false)
+	var outs []string
+	opts := engine.Options{
+		Flatten:  true,
+		Output:   "yaml",
+		WriteOut: func(s string) { outs = append(outs, s) },
+		WriteErr: func(s string) { t.Fatal(s) },
+	}
+	require.Equal(t, 0, engine.Start(parser, []string{"x.a"}, opts))
+	assert.Equal(t, []string{`[0] = 1`, `[1]["b c"] = 2`, ``, `. = "x"`}, outs)
+}
+
+func TestOutput_FlattenKeyOrder(t *testing.T) {
+	for _, args := range [][]string{nil, {"."}} {
+		parser := jsonx.NewJsonParser(strings.NewReader(`{"b":1,"10":2,"a":3,"2":4}`), false)
+		var outs []string
+		opts := engine.Options{
+			Flatten:  true,
+			WriteOut: func(s string) { outs = append(outs, s) },
+			WriteErr: func(s string) { t.Fatal(s) },
+		}
+		require.Equal(t, 0, engine.Start(parser, args, opts))
+		assert.Equal(t, []string{`.b = 1`, `["10"] = 2`, `.a = 3`, `["2"] = 4`}, outs)
+	}
+}
+
+func TestOutput_CSV(t *testing.T) {
+	out := convert(t, `[{"a":1,"b":"x, y"},{"b":null,"c":{"d":[1]}}]`, []string{"."}, "csv")
+	assert.Equal(t, "a,b,c\n1,\"x, y\",\n,,\"{\"\"d\"\":[1]}\"", out)
+
+	out = convert(t, "{\"a\":1,\"b\":2}\n{\"a\":3,\"b\":4}", nil, "csv")
+	assert.Equal(t, "a,b\n1,2\n3,4", out)
+
+	out = convert(t, `[{"c":{"d":1},"e":2}]`, []string{"."}, "csv")
+	assert.Equal(t, "c,e\n\"{\"\"d\"\":1}\",2", out)
+
+	out = convert(t, `[[1,"a"],[2,"b"]]`, []string{"."}, "csv")
+	assert.Equal(t, "1,a\n2,b", out)
+
+	out = convert(t, "{\"a\":1,\"b\":2}\n{\"b\":3}", nil, "csv")
+	assert.Equal(t, "a,b\n1,2\n,3", out)
+}
+
+func TestOutput_CSV_HeaderMismatch(t *testing.T) {
+	exitCode, outs, errs := start("{\"a\":1}\n{\"a\":2,\"b\":3}", nil, engine.Options{Output: "csv"})
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, []string{"a\n1"}, outs)
+	assert.Equal(t, []string{`csv: key "b" of document 2 is not in the header of document 1, use --slurp for a header of all documents`}, errs)
+}
+
+func TestOutput_TSV(t *testing.T) {
+	out := convert(t, `[{"a":1,"b":"x y"}]`, []string{"."}, "tsv")
+	assert.Equal(t, "a\tb\n1\tx y", out)
+}
+
+func TestEncode(t *testing.T) {
+	parse := func(input string) *jsonx.Node {
+		node, err := jsonx.Parse([]byte(input))
+		require.NoError(t, err)
+		return node
+	}
+	docs := []*jsonx.Node{parse(`{"a":1}`), parse(`{"a":2}`)}
+
+	out, err := engine.Encode("json", docs)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 1\n}\n{\n  \"a\": 2\n}\n", out)
+
+	out, err = engine.Encode("yaml", docs)
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\n---\na: 2\n", out)
+
+	out, err = engine.Encode("csv", docs)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n1\n2\n", out)
+
+	_, err = engine.Encode("toml", []*jsonx.Node{parse(`[1]`)})
+	assert.Error(t, err)
+
+	_, err = engine.Encode("toml", docs)
+	assert.EqualError(t, err, "toml: can't write several documents as one")
+}
+
+func TestOutput_XML(t *testing.T) {
+	out := convert(t, `{"a":{"@id":"1","#text":"x & y","b":[1,{"c":null}],"d":"<"}}`, nil, "xml")
+	assert.Equal(t, "<a id=\"1\">\n  x &amp; y\n  <b>1</b>\n  <b>\n    <c/>\n  </b>\n  <d>&lt;</d>\n</a>", out)
+
+	out = convert(t, `[1,"a"]`, nil, "xml")
+	assert.Equal(t, "<root>\n  <item>1</item>\n  <item>a</item>\n</root>", out)
+
+	out = convert(t, `{"a":[1,2]}`, nil, "xml")
+	assert.Equal(t, "<root>\n  <a>1</a>\n  <a>2</a>\n</root>", out)
+
+	out = convert(t, `{"p":{"#text":["Hi","and","!"],"b":"you","i":"me"}}`, nil, "xml")
+	assert.Equal(t, "<p>\n  Hi\n  <b>you</b>\n  and\n  <i>me</i>\n  !\n</p>", out)
+}
+
+func TestOutput_XMLRoundTrip(t *testing.T) {
+	inputs := []string{
+		`<a id="1">x<b>1</b><b><c/></b></a>`,
+		`<p>Hi <b>you</b> and <i>me</i>!</p>`,
+		`<p><b>1</b>, <b>2</b>.</p>`,
+		`<p><b>1</b>, <i>2</i>, <b>3</b></p>`,
+		`<catalog xmlns:x="urn:x" id="1"><book lang="en"><title>Go &amp; XML</title><x:tag/><note></note></book>` +
+			`<book><title> Padded </title></book>Some <![CDATA[<text>]]></catalog>`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			node, err := jsonx.NewXmlParser(strings.NewReader(input)).Parse()
+			require.NoError(t, err)
+			xml, err := engine.ToXML(node)
+			require.NoError(t, err)
+			back, err := jsonx.NewXmlParser(strings.NewReader(xml)).Parse()
+			require.NoError(t, err)
+			assert.Equal(t, jsonx.Compact(node), jsonx.Compact(back))
+		})
+	}
+}
+
+func TestOutput_Unknown(t *testing.T) {
+	exitCode, outs, errs := start(`{}`, []string{"."}, engine.Options{Output: "foo"})
+	assert.Equal(t, 1, exitCode)
+	assert.Empty(t, outs)
+	assert.Equal(t, []string{`unknown output format "foo", must be one of: json, ndjson, yaml, toml, csv, tsv, xml`}, errs)
+
+	_, err := engine.Encode("foo", nil)
+	assert.Error(t, err)
+}
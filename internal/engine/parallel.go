@@ -0,0 +1,227 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+type job struct {
+	index int
+	node  *jsonx.Node
+}
+
+// output is a line printed while evaluating an input, or a result to encode.
+type output struct {
+	text   string
+	stderr bool
+	node   *jsonx.Node
+}
+
+// evaluation holds everything an input printed, to be written in input order.
+type evaluation struct {
+	index    int
+	outputs  []output
+	exitCode int // Code passed to exit(), 1 on errors, -1 otherwise.
+}
+
+// parallel evaluates inputs on opts.Parallel VMs. Workers buffer their output,
+// so results, errors and exit() take effect in input order as in the sequential
+// path, unless opts.Unordered is set.
+func parallel(parser Parser, program *goja.Program, toValue toValueFunc, opts Options, encode encoder) int {
+	var err error
+	workers := make([]*worker, opts.Parallel)
+	for i := range workers {
+		workers[i], err = newWorker(program, toValue, opts, encode != nil)
+		if err != nil {
+			opts.WriteErr(errorToString(err))
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Limit inputs in flight, so a slow one doesn't pile up results in memory.
+	inFlight := make(chan struct{}, 4*opts.Parallel)
+	jobs := make(chan job)
+	results := make(chan evaluation)
+
+	go func() {
+		defer close(jobs)
+		for i := 0; ; i++ {
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			node, err := parser.Parse()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				e := evaluation{index: i, outputs: []output{{text: err.Error(), stderr: true}}, exitCode: 1}
+				select {
+				case results <- e:
+				case <-ctx.Done():
+				}
+				return
+			}
+			select {
+			case jobs <- job{i, node}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx, jobs, results)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	write := func(e evaluation) int {
+		for _, out := range e.outputs {
+			switch {
+			case out.node != nil:
+				if err := encode(out.node); err != nil {
+					opts.WriteErr(err.Error())
+					return 1
+				}
+			case out.stderr:
+				opts.WriteErr(out.text)
+			default:
+				opts.WriteOut(out.text)
+			}
+		}
+		return e.exitCode
+	}
+
+	pending := make(map[int]evaluation)
+	next := 0
+	for e := range results {
+		ready := []evaluation{e}
+		if !opts.Unordered {
+			pending[e.index] = e
+			ready = ready[:0]
+			for {
+				e, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				ready = append(ready, e)
+				next++
+			}
+		}
+		for _, e := range ready {
+			<-inFlight
+			if exitCode := write(e); exitCode >= 0 {
+				return exitCode
+			}
+		}
+	}
+	return 0
+}
+
+type worker struct {
+	vm      *goja.Runtime
+	main    goja.Callable
+	skip    goja.Value
+	echo    func(goja.Value) error
+	toValue toValueFunc
+	patch   patchFunc
+	current *evaluation
+}
+
+func newWorker(program *goja.Program, toValue toValueFunc, opts Options, encoded bool) (*worker, error) {
+	w := &worker{toValue: toValue}
+	add := func(out output) {
+		w.current.outputs = append(w.current.outputs, out)
+	}
+	opts.WriteOut = func(s string) { add(output{text: s}) }
+	opts.WriteErr = func(s string) { add(output{text: s, stderr: true}) }
+	var encode encoder
+	if encoded {
+		encode = func(n *jsonx.Node) error {
+			add(output{node: n})
+			return nil
+		}
+	}
+
+	w.vm = NewVM(opts.WriteOut)
+	if err := load(w.vm, program); err != nil {
+		return nil, err
+	}
+	w.main, _ = goja.AssertFunction(w.vm.Get("__main__"))
+	w.skip = w.vm.Get("skip")
+	w.echo = newEcho(w.vm, opts, encode)
+	if opts.Patch != nil {
+		var err error
+		if w.patch, err = compilePatch(w.vm, opts.Patch); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *worker) run(ctx context.Context, jobs <-chan job, results chan<- evaluation) {
+	stop := context.AfterFunc(ctx, func() {
+		w.vm.Interrupt(ctx.Err())
+	})
+	defer stop()
+
+	for j := range jobs {
+		e := w.eval(j)
+		select {
+		case results <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *worker) eval(j job) evaluation {
+	e := evaluation{index: j.index, exitCode: -1}
+	w.current = &e
+
+	input := w.toValue(j.node, w.vm)
+	if w.patch != nil {
+		var err error
+		if input, err = w.patch(input); err != nil {
+			e.outputs = append(e.outputs, output{text: err.Error(), stderr: true})
+			e.exitCode = 1
+			return e
+		}
+	}
+	result, exitCode, err := callMain(w.main, input)
+	if exitCode >= 0 {
+		e.exitCode = exitCode
+		return e
+	}
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if !errors.As(err, &interrupted) {
+			e.outputs = append(e.outputs, output{text: errorToString(err), stderr: true})
+		}
+		e.exitCode = 1
+		return e
+	}
+	if !result.StrictEquals(w.skip) {
+		_ = w.echo(result) // Never fails, encoding is done by the writer.
+	}
+	return e
+}
@@ -0,0 +1,84 @@
+package engine_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func start(input string, args []string, opts engine.Options) (int, []string, []string) {
+	var outs, errs []string
+	opts.WriteOut = func(s string) { outs = append(outs, s) }
+	opts.WriteErr = func(s string) { errs = append(errs, s) }
+	parser := jsonx.NewJsonParser(strings.NewReader(input), false)
+	exitCode := engine.Start(parser, args, opts)
+	return exitCode, outs, errs
+}
+
+func TestStart_Parallel(t *testing.T) {
+	var input strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&input, "{\"n\":%d}\n", i)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		args   []string
+		output string
+	}{
+		{"map", input.String(), []string{".n * 2"}, ""},
+		{"skip", input.String(), []string{"x.n % 3 ? skip : x"}, ""},
+		{"println", input.String(), []string{"x => (println('n=' + x.n), x.n)"}, ""},
+		{"exit", input.String(), []string{"x.n == 42 ? exit(5) : x.n"}, ""},
+		{"exit zero", input.String(), []string{"x.n == 42 ? exit(0) : x.n"}, ""},
+		{"error", input.String(), []string{"x.n == 42 ? x.a.b : x.n"}, ""},
+		{"undefined", input.String(), []string{"x.n == 42 ? undefined : x.n"}, ""},
+		{"parse error", "1 2 3 {", []string{"x"}, ""},
+		{"output format", input.String(), []string{".n"}, "yaml"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			wantCode, wantOuts, wantErrs := start(tc.input, tc.args, engine.Options{Output: tc.output})
+			for _, n := range []int{2, 7} {
+				exitCode, outs, errs := start(tc.input, tc.args, engine.Options{Output: tc.output, Parallel: n})
+				assert.Equal(t, wantCode, exitCode)
+				assert.Equal(t, wantOuts, outs)
+				assert.Equal(t, wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestStart_Parallel_Unordered(t *testing.T) {
+	exitCode, outs, errs := start("1 2 3 4 5 6 7 8", []string{"x * 10"}, engine.Options{Parallel: 3, Unordered: true})
+	assert.Equal(t, 0, exitCode)
+	assert.Empty(t, errs)
+	assert.ElementsMatch(t, []string{"10", "20", "30", "40", "50", "60", "70", "80"}, outs)
+}
+
+func BenchmarkStart_Parallel(b *testing.B) {
+	var input strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&input, "{\"id\":%d,\"tags\":[\"a\",\"b\",\"c\"]}\n", i)
+	}
+	// A CPU-heavy transform, so evaluation dominates parsing.
+	args := []string{"x => { let h = x.id; for (let i = 0; i < 2000; i++) h = (h * 31 + i) % 1000003; return {...x, h} }"}
+
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("parallel=%d", n), func(b *testing.B) {
+			opts := engine.Options{Parallel: n, WriteOut: func(string) {}, WriteErr: func(string) {}}
+			for i := 0; i < b.N; i++ {
+				parser := jsonx.NewJsonParser(strings.NewReader(input.String()), false)
+				if engine.Start(parser, args, opts) != 0 {
+					b.Fatal("failed")
+				}
+			}
+		})
+	}
+}
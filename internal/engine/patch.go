@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// Patch is a JSON Patch, an array of operations, or a JSON Merge Patch
+// applied to every input before args.
+type Patch struct {
+	File string // Name of the patch in errors.
+	Ops  *jsonx.Node
+}
+
+type patchFunc func(goja.Value) (goja.Value, error)
+
+// compilePatch returns a function applying p with the patch or mergePatch of vm.
+func compilePatch(vm *goja.Runtime, p *Patch) (patchFunc, error) {
+	name := "mergePatch"
+	if p.Ops.Kind == jsonx.Array {
+		name = "patch"
+	}
+	newPatch, _ := goja.AssertFunction(vm.Get(name))
+	fn, err := newPatch(goja.Undefined(), p.Ops.ToValue(vm))
+	if err != nil {
+		return nil, fmt.Errorf("--patch %s: %s", p.File, errorToString(err))
+	}
+	apply, _ := goja.AssertFunction(fn)
+	return func(x goja.Value) (goja.Value, error) {
+		out, err := apply(goja.Undefined(), x)
+		if err == nil {
+			return out, nil
+		}
+		if exception, ok := err.(*goja.Exception); ok {
+			if obj, ok := exception.Value().(*goja.Object); ok {
+				op, reason := obj.Get("operation"), obj.Get("reason")
+				if op != nil && reason != nil {
+					return nil, fmt.Errorf("--patch %s: %d: %s", p.File, op.ToInteger(), reason.String())
+				}
+			}
+		}
+		return nil, fmt.Errorf("--patch %s: %s", p.File, errorToString(err))
+	}, nil
+}
@@ -0,0 +1,46 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func patchOf(t *testing.T, ops string) *engine.Patch {
+	node, err := jsonx.Parse([]byte(ops))
+	require.NoError(t, err)
+	return &engine.Patch{File: "ops.json", Ops: node}
+}
+
+func TestStart_Patch(t *testing.T) {
+	input := `{"a": 1, "b": {"c": 2}} {"a": 3}`
+
+	exitCode, outs, errs := start(input, nil, engine.Options{Output: "ndjson", Patch: patchOf(t, `{"b": null}`)})
+	require.Equal(t, 0, exitCode, errs)
+	assert.Equal(t, []string{`{"a":1}`, `{"a":3}`}, outs)
+
+	ops := patchOf(t, `[{"op": "replace", "path": "/a", "value": 10}]`)
+	for _, parallel := range []int{0, 2} {
+		exitCode, outs, errs = start(input, []string{".a", "x + 1"}, engine.Options{Patch: ops, Parallel: parallel})
+		require.Equal(t, 0, exitCode, errs)
+		assert.Equal(t, []string{"11", "11"}, outs)
+	}
+}
+
+func TestStart_PatchErrors(t *testing.T) {
+	ops := patchOf(t, `[{"op": "test", "path": "/a", "value": 1}, {"op": "remove", "path": "/b"}]`)
+	for _, parallel := range []int{0, 2} {
+		exitCode, _, errs := start(`{"a": 1}`, []string{".a"}, engine.Options{Patch: ops, Parallel: parallel})
+		assert.Equal(t, 1, exitCode)
+		assert.Equal(t, []string{"--patch ops.json: 1: remove /b: path not found"}, errs)
+	}
+
+	// Errors of args show the args as written.
+	_, _, errs := start(`{"a": 1}`, []string{".a.b.c"}, engine.Options{Patch: patchOf(t, `{}`)})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "\n  .a.b.c\n  ^^^^^^\n\nx.a.b.c\n\nTypeError: Cannot read property 'c' of undefined", errs[0])
+}
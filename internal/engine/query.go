@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// Query evaluates expressions against the documents and returns the results as nodes
+// instead of printing them. It is used by the interactive query mode: errors are
+// returned rather than printed, and the evaluation is interrupted when ctx is done.
+func Query(ctx context.Context, docs []*jsonx.Node, args []string) ([]*jsonx.Node, error) {
+	program, err := Compile(args)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := NewVM(func(string) {})
+	stop := context.AfterFunc(ctx, func() {
+		vm.Interrupt(ctx.Err())
+	})
+	defer stop()
+
+	if err := load(vm, program); err != nil {
+		return nil, errors.New(errorToString(err))
+	}
+
+	skip := vm.Get("skip")
+	main, _ := goja.AssertFunction(vm.Get("__main__"))
+
+	var out strings.Builder
+	for _, doc := range docs {
+		output, exitCode, err := callMain(main, toValue(doc, vm))
+		if exitCode >= 0 {
+			break
+		}
+		if err != nil {
+			var interrupted *goja.InterruptedError
+			if errors.As(err, &interrupted) {
+				return nil, fmt.Errorf("interrupted: %v", interrupted.Value())
+			}
+			return nil, errors.New(errorToString(err))
+		}
+		if output.StrictEquals(skip) || goja.IsUndefined(output) {
+			continue
+		}
+		out.WriteString(Stringify(output, vm, 0))
+		out.WriteByte('\n')
+	}
+
+	// Parse all results with one parser, so line numbers continue across documents.
+	parser := jsonx.NewJsonParser(strings.NewReader(out.String()), false)
+	var results []*jsonx.Node
+	for {
+		node, err := parser.Parse()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, node)
+	}
+	return results, nil
+}
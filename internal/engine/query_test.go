@@ -0,0 +1,61 @@
+package engine_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func parseDocs(t *testing.T, inputs ...string) []*jsonx.Node {
+	var docs []*jsonx.Node
+	for _, input := range inputs {
+		node, err := jsonx.Parse([]byte(input))
+		require.NoError(t, err)
+		docs = append(docs, node)
+	}
+	return docs
+}
+
+func TestQuery(t *testing.T) {
+	docs := parseDocs(t, `{"items":[{"name":"a"},{"name":"b"}]}`, `{"items":[]}`)
+
+	results, err := engine.Query(context.Background(), docs, []string{".items[].name"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, `["a","b"]`, jsonx.Compact(results[0]))
+	assert.Equal(t, `[]`, jsonx.Compact(results[1]))
+	assert.Equal(t, 5, results[1].LineNumber, "line numbers continue across results")
+}
+
+func TestQuery_Skip(t *testing.T) {
+	docs := parseDocs(t, `1`, `2`, `3`)
+
+	results, err := engine.Query(context.Background(), docs, []string{"x => x > 1 ? x : skip"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].Value)
+}
+
+func TestQuery_Errors(t *testing.T) {
+	docs := parseDocs(t, `{}`)
+
+	_, err := engine.Query(context.Background(), docs, []string{".foo("})
+	assert.ErrorContains(t, err, "Unexpected token")
+
+	_, err = engine.Query(context.Background(), docs, []string{".a.b.c"})
+	assert.ErrorContains(t, err, "TypeError")
+}
+
+func TestQuery_Interrupt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := engine.Query(ctx, parseDocs(t, `1`), []string{"x => { while (true) {} }"})
+	assert.ErrorContains(t, err, "interrupted")
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dop251/goja"
+)
+
+// Reduce folds all inputs into a single accumulator, one input at a time,
+// so inputs don't have to be slurped into memory.
+type Reduce struct {
+	Init  string // Expression of the initial accumulator.
+	Fn    string // Function (acc, x) returning the next accumulator.
+	Every int    // Emit the accumulator every N inputs as well as at the end, if positive.
+}
+
+func compileReduce(vm *goja.Runtime, r *Reduce) (goja.Value, goja.Callable, error) {
+	init, err := vm.RunString("(" + r.Init + "\n)")
+	if err != nil {
+		return nil, nil, fmt.Errorf("--reduce %s: %s", r.Init, errorToString(err))
+	}
+	fn, err := vm.RunString("(" + r.Fn + "\n)")
+	if err != nil {
+		return nil, nil, fmt.Errorf("--reduce %s: %s", r.Fn, errorToString(err))
+	}
+	reducer, ok := goja.AssertFunction(fn)
+	if !ok {
+		return nil, nil, fmt.Errorf("--reduce %s: not a function", r.Fn)
+	}
+	return init, reducer, nil
+}
+
+// reduce runs inputs through main, then folds the results with the reducer.
+// Inputs for which main or the reducer return skip leave the accumulator as is.
+func reduce(parser Parser, vm *goja.Runtime, main goja.Callable, toValue toValueFunc, opts Options, echo func(goja.Value) error) int {
+	acc, reducer, err := compileReduce(vm, opts.Reduce)
+	if err != nil {
+		opts.WriteErr(err.Error())
+		return 1
+	}
+
+	skip := vm.Get("skip")
+	count := 0
+	for {
+		node, err := parser.Parse()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			opts.WriteErr(err.Error())
+			return 1
+		}
+
+		output, exitCode, err := callMain(main, toValue(node, vm))
+		if exitCode >= 0 {
+			return exitCode
+		}
+		if err != nil {
+			opts.WriteErr(errorToString(err))
+			return 1
+		}
+		if output.StrictEquals(skip) {
+			continue
+		}
+
+		next, exitCode, err := callMain(reducer, acc, output)
+		if exitCode >= 0 {
+			return exitCode
+		}
+		if err != nil {
+			opts.WriteErr(errorToString(err))
+			return 1
+		}
+		if next.StrictEquals(skip) {
+			continue
+		}
+		acc = next
+
+		count++
+		if opts.Reduce.Every > 0 && count%opts.Reduce.Every == 0 {
+			if err := echo(acc); err != nil {
+				opts.WriteErr(err.Error())
+				return 1
+			}
+		}
+	}
+
+	// Don't repeat the accumulator if it was just emitted.
+	if opts.Reduce.Every > 0 && count > 0 && count%opts.Reduce.Every == 0 {
+		return 0
+	}
+	if err := echo(acc); err != nil {
+		opts.WriteErr(err.Error())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+package engine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestStart_Reduce(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		args     []string
+		reduce   engine.Reduce
+		expects  []string
+		exitCode int
+	}{
+		{
+			name:    "sum",
+			input:   `{"n":1} {"n":2} {"n":3}`,
+			args:    []string{".n"},
+			reduce:  engine.Reduce{Init: "0", Fn: "(acc, x) => acc + x"},
+			expects: []string{"6"},
+		},
+		{
+			name:    "group by",
+			input:   `{"t":"a"} {"t":"b"} {"t":"a"}`,
+			reduce:  engine.Reduce{Init: "{}", Fn: "(acc, x) => ({...acc, [x.t]: (acc[x.t] || 0) + 1})"},
+			expects: []string{`{"a":2,"b":1}`},
+		},
+		{
+			name:    "no inputs",
+			input:   ``,
+			reduce:  engine.Reduce{Init: "[]", Fn: "(acc, x) => [...acc, x]"},
+			expects: []string{`[]`},
+		},
+		{
+			name:    "skip in args and reducer",
+			input:   `1 2 3 4 5`,
+			args:    []string{"x == 2 ? skip : x"},
+			reduce:  engine.Reduce{Init: "0", Fn: "(acc, x) => x == 4 ? skip : acc + x"},
+			expects: []string{"9"},
+		},
+		{
+			name:    "every",
+			input:   `1 2 3 4 5`,
+			reduce:  engine.Reduce{Init: "0", Fn: "(acc, x) => acc + x", Every: 2},
+			expects: []string{"3", "10", "15"},
+		},
+		{
+			name:    "every without a repeat at the end",
+			input:   `1 2 3 4`,
+			reduce:  engine.Reduce{Init: "0", Fn: "(acc, x) => acc + x", Every: 2},
+			expects: []string{"3", "10"},
+		},
+		{
+			name:     "exit",
+			input:    `1 2 3`,
+			reduce:   engine.Reduce{Init: "0", Fn: "(acc, x) => x == 2 ? exit(3) : acc + x"},
+			exitCode: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := jsonx.NewJsonParser(strings.NewReader(tc.input), false)
+
+			var outs, errs []string
+			opts := engine.Options{
+				Reduce:   &tc.reduce,
+				WriteOut: func(s string) { outs = append(outs, s) },
+				WriteErr: func(s string) { errs = append(errs, s) },
+			}
+			exitCode := engine.Start(parser, tc.args, opts)
+
+			assert.Equal(t, tc.exitCode, exitCode)
+			assert.Empty(t, errs)
+			var compact []string
+			for _, out := range outs {
+				node, err := jsonx.Parse([]byte(out))
+				assert.NoError(t, err)
+				compact = append(compact, jsonx.Compact(node))
+			}
+			assert.Equal(t, tc.expects, compact)
+		})
+	}
+}
+
+func TestStart_Reduce_Errors(t *testing.T) {
+	tests := []struct {
+		reduce engine.Reduce
+		err    string
+	}{
+		{engine.Reduce{Init: "{", Fn: "(acc, x) => acc"}, "--reduce {: "},
+		{engine.Reduce{Init: "0", Fn: "42"}, "--reduce 42: not a function"},
+		{engine.Reduce{Init: "0", Fn: "(acc, x) => acc.a.b"}, "TypeError"},
+	}
+	for _, tc := range tests {
+		parser := jsonx.NewJsonParser(strings.NewReader(`1`), false)
+		var errs []string
+		opts := engine.Options{
+			Reduce:   &tc.reduce,
+			WriteOut: func(string) {},
+			WriteErr: func(s string) { errs = append(errs, s) },
+		}
+		assert.Equal(t, 1, engine.Start(parser, nil, opts))
+		if assert.Len(t, errs, 1) {
+			assert.Contains(t, errs[0], tc.err)
+		}
+	}
+}
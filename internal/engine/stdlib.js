@@ -202,3 +202,207 @@ const YAML = {
   stringify: x => __yaml_stringify__(x),
   parse: x => JSON.parse(__yaml_parse__(x)),
 }
+
+// __patch__ keeps the JSON Patch helpers out of the global scope,
+// which user code and .fxrc.js share.
+const __patch__ = (function () {
+  let decimalPrototype
+
+  function isDecimal(x) {
+    if (typeof x !== 'object' || x === null) return false
+    if (decimalPrototype === undefined) decimalPrototype = Object.getPrototypeOf(decimal('0'))
+    return Object.getPrototypeOf(x) === decimalPrototype
+  }
+
+  function isPlainObject(x) {
+    return typeof x === 'object' && x !== null && !Array.isArray(x) && !isDecimal(x)
+  }
+
+  function setKey(obj, key, value) {
+    Object.defineProperty(obj, key, {value, writable: true, enumerable: true, configurable: true})
+  }
+
+  function cloneJSON(x) {
+    if (Array.isArray(x)) return x.map(cloneJSON)
+    if (isPlainObject(x)) {
+      const copy = {}
+      for (const [k, v] of Object.entries(x)) setKey(copy, k, cloneJSON(v))
+      return copy
+    }
+    return x
+  }
+
+  function equalJSON(a, b) {
+    if ((typeof a === 'number' || isDecimal(a)) && (typeof b === 'number' || isDecimal(b))) {
+      return decimal.cmp(a, b) === 0
+    }
+    if (Array.isArray(a) && Array.isArray(b)) {
+      return a.length === b.length && a.every((v, i) => equalJSON(v, b[i]))
+    }
+    if (isPlainObject(a) && isPlainObject(b)) {
+      const keys = Object.keys(a)
+      return keys.length === Object.keys(b).length &&
+        keys.every(k => Object.prototype.hasOwnProperty.call(b, k) && equalJSON(a[k], b[k]))
+    }
+    return a === b
+  }
+
+  function escapePointer(key) {
+    return String(key).replace(/~/g, '~0').replace(/\//g, '~1')
+  }
+
+  function patch(ops) {
+    if (!Array.isArray(ops)) throw new Error('patch: operations must be an array')
+
+    const has = (obj, key) => Object.prototype.hasOwnProperty.call(obj, key)
+    const member = (op, name) => {
+      if (!has(op, name)) throw new Error(`missing "${name}"`)
+      return op[name]
+    }
+    const index = (arr, token, end) => {
+      if (token === '-' && end) return arr.length
+      if (!/^(0|[1-9][0-9]*)$/.test(token)) throw new Error(`invalid array index "${token}"`)
+      const i = Number(token)
+      if (i > arr.length || (i === arr.length && !end)) throw new Error(`array index ${i} is out of bounds`)
+      return i
+    }
+    const get = (doc, tokens) => {
+      let x = doc
+      for (const token of tokens) {
+        if (Array.isArray(x)) x = x[index(x, token, false)]
+        else if (isPlainObject(x) && has(x, token)) x = x[token]
+        else throw new Error('path not found')
+      }
+      return x
+    }
+    const parent = (doc, tokens) => {
+      const container = get(doc, tokens.slice(0, -1))
+      if (!Array.isArray(container) && !isPlainObject(container)) throw new Error('path not found')
+      return [container, tokens[tokens.length - 1]]
+    }
+    const add = (doc, tokens, value) => {
+      if (tokens.length === 0) return value
+      const [container, key] = parent(doc, tokens)
+      if (Array.isArray(container)) container.splice(index(container, key, true), 0, value)
+      else setKey(container, key, value)
+      return doc
+    }
+    const remove = (doc, tokens) => {
+      if (tokens.length === 0) throw new Error('cannot remove the root')
+      const [container, key] = parent(doc, tokens)
+      if (Array.isArray(container)) container.splice(index(container, key, false), 1)
+      else if (has(container, key)) delete container[key]
+      else throw new Error('path not found')
+      return doc
+    }
+    const pointer = p => {
+      if (typeof p !== 'string') throw new Error('path must be a string')
+      return __pointer__(p)
+    }
+    const apply = (doc, op) => {
+      if (!isPlainObject(op)) throw new Error('operation must be an object')
+      const path = member(op, 'path')
+      const tokens = pointer(path)
+      switch (op.op) {
+        case 'add':
+          return add(doc, tokens, cloneJSON(member(op, 'value')))
+        case 'remove':
+          return remove(doc, tokens)
+        case 'replace': {
+          const value = cloneJSON(member(op, 'value'))
+          get(doc, tokens)
+          if (tokens.length === 0) return value
+          const [container, key] = parent(doc, tokens)
+          if (Array.isArray(container)) container[index(container, key, false)] = value
+          else setKey(container, key, value)
+          return doc
+        }
+        case 'move': {
+          const from = member(op, 'from')
+          if (from === path) return doc
+          if (path.startsWith(from + '/')) throw new Error(`cannot move ${from} into itself`)
+          const fromTokens = pointer(from)
+          const value = get(doc, fromTokens)
+          return add(remove(doc, fromTokens), tokens, value)
+        }
+        case 'copy':
+          return add(doc, tokens, cloneJSON(get(doc, pointer(member(op, 'from')))))
+        case 'test': {
+          const expected = member(op, 'value')
+          if (!equalJSON(get(doc, tokens), expected)) throw new Error(`test failed: value is not ${JSON.stringify(expected)}`)
+          return doc
+        }
+        default:
+          throw new Error(`unknown operation ${JSON.stringify(op.op)}`)
+      }
+    }
+
+    return function (x) {
+      let doc = cloneJSON(x)
+      ops.forEach((op, i) => {
+        try {
+          doc = apply(doc, op)
+        } catch (err) {
+          const what = isPlainObject(op) ? ` (${op.op} ${op.path})` : ''
+          const error = new Error(`patch: operation ${i}${what}: ${err.message}`)
+          error.operation = i
+          error.reason = (isPlainObject(op) ? `${op.op} ${op.path}: ` : '') + err.message
+          throw error
+        }
+      })
+      return doc
+    }
+  }
+
+  function mergePatch(p) {
+    return function merge(x, p2 = p) {
+      if (!isPlainObject(p2)) return cloneJSON(p2)
+      const result = isPlainObject(x) ? cloneJSON(x) : {}
+      for (const [k, v] of Object.entries(p2)) {
+        if (v === null) delete result[k]
+        else setKey(result, k, merge(result[k], v))
+      }
+      return result
+    }
+  }
+
+  function diffPatch(a, b) {
+    const ops = []
+    const diff = (a, b, path) => {
+      if (equalJSON(a, b)) return
+      if (Array.isArray(a) && Array.isArray(b)) {
+        const common = Math.min(a.length, b.length)
+        for (let i = 0; i < common; i++) diff(a[i], b[i], `${path}/${i}`)
+        for (let i = a.length - 1; i >= common; i--) ops.push({op: 'remove', path: `${path}/${i}`})
+        for (let i = common; i < b.length; i++) ops.push({op: 'add', path: `${path}/${i}`, value: cloneJSON(b[i])})
+      } else if (isPlainObject(a) && isPlainObject(b)) {
+        for (const k of Object.keys(a)) {
+          if (!Object.prototype.hasOwnProperty.call(b, k)) ops.push({op: 'remove', path: `${path}/${escapePointer(k)}`})
+        }
+        for (const [k, v] of Object.entries(b)) {
+          const p = `${path}/${escapePointer(k)}`
+          if (Object.prototype.hasOwnProperty.call(a, k)) diff(a[k], v, p)
+          else ops.push({op: 'add', path: p, value: cloneJSON(v)})
+        }
+      } else {
+        ops.push({op: 'replace', path, value: cloneJSON(b)})
+      }
+    }
+    diff(a, b, '')
+    return ops
+  }
+
+  return {patch, mergePatch, diffPatch}
+})()
+
+function patch(ops) {
+  return __patch__.patch(ops)
+}
+
+function mergePatch(p) {
+  return __patch__.mergePatch(p)
+}
+
+function diffPatch(a, b) {
+  return __patch__.diffPatch(a, b)
+}
@@ -1,14 +1,19 @@
 package engine_test
 
 import (
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
 )
 
 // setupVM creates a new goja runtime with stdlib loaded.
@@ -22,6 +27,91 @@ func setupVM(t *testing.T) *goja.Runtime {
 	return vm
 }
 
+// run evaluates code on vm, and again on a new VM with the JSON literals of code
+// converted by ToLazyValue, as inputs are, checking both give the same result.
+func run(t *testing.T, vm *goja.Runtime, code string) (goja.Value, error) {
+	t.Helper()
+	result, err := vm.RunString(code)
+
+	lazyCode, literals := lazyLiterals(code)
+	if len(literals) == 0 {
+		return result, err
+	}
+	lazyVM := setupVM(t)
+	require.NoError(t, lazyVM.Set("__lazy__", func(i int) goja.Value {
+		return literals[i].ToLazyValue(lazyVM)
+	}))
+	lazyResult, lazyErr := lazyVM.RunString(lazyCode)
+	if err != nil {
+		assert.Error(t, lazyErr, "lazy: %s", lazyCode)
+	} else if assert.NoError(t, lazyErr, "lazy: %s", lazyCode) {
+		assert.Equal(t, stringify(vm, result), stringify(lazyVM, lazyResult), "lazy: %s", lazyCode)
+	}
+	return result, err
+}
+
+// lazyLiterals replaces array and object literals of code, which are valid JSON,
+// with calls of __lazy__ with an index of the parsed literals.
+func lazyLiterals(code string) (string, []*jsonx.Node) {
+	program, err := parser.ParseFile(nil, "", code, 0)
+	if err != nil {
+		return code, nil
+	}
+	type span struct{ from, to int }
+	var spans []span
+	var literals []*jsonx.Node
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface:
+			if v.IsNil() {
+				return
+			}
+			switch n := v.Interface().(type) {
+			case *ast.ArrayLiteral, *ast.ObjectLiteral:
+				expr := n.(ast.Expression)
+				from, to := int(expr.Idx0())-1, int(expr.Idx1())-1
+				if node, err := jsonx.Parse([]byte(code[from:to])); err == nil {
+					spans = append(spans, span{from, to})
+					literals = append(literals, node)
+					return
+				}
+			}
+			walk(v.Elem())
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					walk(v.Field(i))
+				}
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(program))
+
+	var out strings.Builder
+	last := 0
+	for i, s := range spans {
+		out.WriteString(code[last:s.from])
+		out.WriteString("__lazy__(" + strconv.Itoa(i) + ")")
+		last = s.to
+	}
+	out.WriteString(code[last:])
+	return out.String(), literals
+}
+
+func stringify(vm *goja.Runtime, v goja.Value) (s string) {
+	defer func() {
+		if recover() != nil {
+			s = v.String() // Functions and other values Stringify doesn't support.
+		}
+	}()
+	return engine.Stringify(v, vm, 0)
+}
+
 // setupVMWithOutput creates a new goja runtime with stdlib loaded and returns output slice.
 func setupVMWithOutput(t *testing.T) (*goja.Runtime, *[]string) {
 	output := &[]string{}
@@ -52,7 +142,7 @@ func TestApply(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			if tc.expected == goja.Undefined() {
 				assert.True(t, goja.IsUndefined(result))
@@ -85,7 +175,7 @@ func TestLen(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -116,7 +206,7 @@ func TestUniq(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -147,7 +237,7 @@ func TestSort(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -177,7 +267,7 @@ func TestFilter(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -185,20 +275,20 @@ func TestFilter(t *testing.T) {
 
 	// Test skip symbol for non-array falsy
 	t.Run("filter non-array falsy returns skip", func(t *testing.T) {
-		result, err := vm.RunString("filter(x => x > 10)(5) === skip")
+		result, err := run(t, vm, "filter(x => x > 10)(5) === skip")
 		require.NoError(t, err)
 		assert.Equal(t, true, result.Export())
 	})
 
 	// Test null/undefined/false filtering
 	t.Run("filter removes null", func(t *testing.T) {
-		result, err := vm.RunString("filter(x => null)([1, 2, 3])")
+		result, err := run(t, vm, "filter(x => null)([1, 2, 3])")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{}, result.Export())
 	})
 
 	t.Run("filter removes undefined", func(t *testing.T) {
-		result, err := vm.RunString("filter(x => undefined)([1, 2, 3])")
+		result, err := run(t, vm, "filter(x => undefined)([1, 2, 3])")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{}, result.Export())
 	})
@@ -222,7 +312,7 @@ func TestMap(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -249,7 +339,7 @@ func TestWalk(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -280,7 +370,7 @@ func TestSortBy(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -339,7 +429,7 @@ func TestSortKeys(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			tc.check(t, result)
 		})
@@ -376,7 +466,7 @@ func TestGroupBy(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -415,7 +505,7 @@ func TestChunk(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -453,7 +543,7 @@ func TestZip(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -485,7 +575,7 @@ func TestFlatten(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -517,7 +607,7 @@ func TestReverse(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -547,7 +637,7 @@ func TestKeys(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -559,13 +649,13 @@ func TestKeys(t *testing.T) {
 
 	// Specific value checks
 	t.Run("object keys values", func(t *testing.T) {
-		result, err := vm.RunString("keys({a: 1, b: 2}).sort()")
+		result, err := run(t, vm, "keys({a: 1, b: 2}).sort()")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{"a", "b"}, result.Export())
 	})
 
 	t.Run("array keys values", func(t *testing.T) {
-		result, err := vm.RunString("keys([10, 20])")
+		result, err := run(t, vm, "keys([10, 20])")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{"0", "1"}, result.Export())
 	})
@@ -590,7 +680,7 @@ func TestValues(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -602,13 +692,13 @@ func TestValues(t *testing.T) {
 
 	// Specific value checks
 	t.Run("object values sorted", func(t *testing.T) {
-		result, err := vm.RunString("values({a: 1, b: 2}).sort()")
+		result, err := run(t, vm, "values({a: 1, b: 2}).sort()")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{int64(1), int64(2)}, result.Export())
 	})
 
 	t.Run("array values", func(t *testing.T) {
-		result, err := vm.RunString("values([10, 20, 30])")
+		result, err := run(t, vm, "values([10, 20, 30])")
 		require.NoError(t, err)
 		assert.Equal(t, []interface{}{int64(10), int64(20), int64(30)}, result.Export())
 	})
@@ -620,7 +710,7 @@ func TestList(t *testing.T) {
 
 	t.Run("list prints each item", func(t *testing.T) {
 		*output = []string{} // Reset output
-		result, err := vm.RunString("list([1, 2, 3]) === skip")
+		result, err := run(t, vm, "list([1, 2, 3]) === skip")
 		require.NoError(t, err)
 		assert.True(t, result.Export().(bool))
 		assert.Equal(t, []string{"1", "2", "3"}, *output)
@@ -628,14 +718,14 @@ func TestList(t *testing.T) {
 
 	t.Run("list with objects", func(t *testing.T) {
 		*output = []string{}
-		result, err := vm.RunString(`list([{a: 1}]) === skip`)
+		result, err := run(t, vm, `list([{a: 1}]) === skip`)
 		require.NoError(t, err)
 		assert.True(t, result.Export().(bool))
 		assert.Len(t, *output, 1)
 	})
 
 	t.Run("list non-array error", func(t *testing.T) {
-		_, err := vm.RunString("list('hello')")
+		_, err := run(t, vm, "list('hello')")
 		assert.Error(t, err)
 	})
 }
@@ -667,7 +757,7 @@ func TestDel(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -683,13 +773,13 @@ func TestSkipSymbol(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("skip is a symbol", func(t *testing.T) {
-		result, err := vm.RunString("typeof skip")
+		result, err := run(t, vm, "typeof skip")
 		require.NoError(t, err)
 		assert.Equal(t, "symbol", result.Export())
 	})
 
 	t.Run("skip is unique", func(t *testing.T) {
-		result, err := vm.RunString("skip === skip")
+		result, err := run(t, vm, "skip === skip")
 		require.NoError(t, err)
 		assert.True(t, result.Export().(bool))
 	})
@@ -714,7 +804,7 @@ func TestConsoleLog(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			*output = []string{}
-			_, err := vm.RunString(tc.code)
+			_, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, *output)
 		})
@@ -737,7 +827,7 @@ func TestToBase64(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.input)
+			result, err := run(t, vm, tc.input)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -761,7 +851,7 @@ func TestFromBase64(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.input)
+			result, err := run(t, vm, tc.input)
 			if tc.hasError {
 				assert.Error(t, err)
 			} else {
@@ -772,7 +862,7 @@ func TestFromBase64(t *testing.T) {
 	}
 
 	t.Run("invalid base64 error", func(t *testing.T) {
-		_, err := vm.RunString("fromBase64('not-valid-base64!!!')")
+		_, err := run(t, vm, "fromBase64('not-valid-base64!!!')")
 		assert.Error(t, err)
 	})
 }
@@ -782,21 +872,21 @@ func TestYAML(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("YAML.parse simple", func(t *testing.T) {
-		result, err := vm.RunString(`YAML.parse('name: John\nage: 30')`)
+		result, err := run(t, vm, `YAML.parse('name: John\nage: 30')`)
 		require.NoError(t, err)
 		expected := map[string]interface{}{"name": "John", "age": int64(30)}
 		assert.Equal(t, expected, result.Export())
 	})
 
 	t.Run("YAML.parse array", func(t *testing.T) {
-		result, err := vm.RunString(`YAML.parse('- 1\n- 2\n- 3')`)
+		result, err := run(t, vm, `YAML.parse('- 1\n- 2\n- 3')`)
 		require.NoError(t, err)
 		expected := []interface{}{int64(1), int64(2), int64(3)}
 		assert.Equal(t, expected, result.Export())
 	})
 
 	t.Run("YAML.stringify simple", func(t *testing.T) {
-		result, err := vm.RunString(`YAML.stringify({name: 'John', age: 30})`)
+		result, err := run(t, vm, `YAML.stringify({name: 'John', age: 30})`)
 		require.NoError(t, err)
 		yamlStr := result.Export().(string)
 		assert.Contains(t, yamlStr, "name: John")
@@ -804,7 +894,7 @@ func TestYAML(t *testing.T) {
 	})
 
 	t.Run("YAML.stringify array", func(t *testing.T) {
-		result, err := vm.RunString(`YAML.stringify([1, 2, 3])`)
+		result, err := run(t, vm, `YAML.stringify([1, 2, 3])`)
 		require.NoError(t, err)
 		yamlStr := result.Export().(string)
 		assert.Contains(t, yamlStr, "- 1")
@@ -813,7 +903,7 @@ func TestYAML(t *testing.T) {
 	})
 
 	t.Run("YAML.parse invalid", func(t *testing.T) {
-		_, err := vm.RunString(`YAML.parse('invalid: [unclosed')`)
+		_, err := run(t, vm, `YAML.parse('invalid: [unclosed')`)
 		assert.Error(t, err)
 	})
 }
@@ -840,7 +930,7 @@ func TestIsFalsely(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -885,7 +975,7 @@ func TestChainedOperations(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := vm.RunString(tc.code)
+			result, err := run(t, vm, tc.code)
 			require.NoError(t, err)
 			assert.Equal(t, tc.expected, result.Export())
 		})
@@ -897,7 +987,7 @@ func TestEdgeCases(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("deeply nested walk", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			walk(x => typeof x === 'number' ? x * 2 : x)({
 				a: {
 					b: {
@@ -934,13 +1024,13 @@ func TestEdgeCases(t *testing.T) {
 			"values({})",
 		}
 		for _, code := range tests {
-			_, err := vm.RunString(code)
+			_, err := run(t, vm, code)
 			assert.NoError(t, err, "Failed for: %s", code)
 		}
 	})
 
 	t.Run("large array handling", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			const arr = [];
 			for (let i = 0; i < 1000; i++) arr.push(i);
 			len(arr)
@@ -950,7 +1040,7 @@ func TestEdgeCases(t *testing.T) {
 	})
 
 	t.Run("unicode strings", func(t *testing.T) {
-		result, err := vm.RunString(`len('你好世界')`)
+		result, err := run(t, vm, `len('你好世界')`)
 		require.NoError(t, err)
 		assert.Equal(t, int64(4), result.Export())
 	})
@@ -971,7 +1061,7 @@ func TestBase64RoundTrip(t *testing.T) {
 	for _, input := range tests {
 		t.Run(input[:min(len(input), 20)], func(t *testing.T) {
 			code := "fromBase64(toBase64('" + escapeJS(input) + "'))"
-			result, err := vm.RunString(code)
+			result, err := run(t, vm, code)
 			require.NoError(t, err)
 			assert.Equal(t, input, result.Export())
 		})
@@ -1005,7 +1095,7 @@ func TestExit(t *testing.T) {
 			require.True(t, ok, "Expected ExitError, got %T", r)
 			assert.Equal(t, 42, exitErr.Code)
 		}()
-		_, _ = vm.RunString("exit(42)")
+		_, _ = run(t, vm, "exit(42)")
 	})
 
 	t.Run("exit with 0", func(t *testing.T) {
@@ -1016,7 +1106,7 @@ func TestExit(t *testing.T) {
 			require.True(t, ok)
 			assert.Equal(t, 0, exitErr.Code)
 		}()
-		_, _ = vm.RunString("exit(0)")
+		_, _ = run(t, vm, "exit(0)")
 	})
 }
 
@@ -1025,14 +1115,14 @@ func TestSave(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("save undefined throws error", func(t *testing.T) {
-		_, err := vm.RunString("save(undefined)")
+		_, err := run(t, vm, "save(undefined)")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "Cannot save undefined")
 	})
 
 	t.Run("save without file path throws error", func(t *testing.T) {
 		// FilePath is empty by default in tests
-		_, err := vm.RunString("save({a: 1})")
+		_, err := run(t, vm, "save({a: 1})")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "specify a file")
 	})
@@ -1043,7 +1133,7 @@ func TestSortMutation(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("sort mutates original array", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			const arr = [3, 1, 2];
 			sort(arr);
 			arr[0]
@@ -1059,7 +1149,7 @@ func TestReverseMutation(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("reverse mutates original array", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			const arr = [1, 2, 3];
 			reverse(arr);
 			arr[0]
@@ -1075,7 +1165,7 @@ func TestDelImmutability(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("del does not mutate original object", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			const obj = {a: 1, b: 2};
 			del('a')(obj);
 			obj.a
@@ -1085,7 +1175,7 @@ func TestDelImmutability(t *testing.T) {
 	})
 
 	t.Run("del does not mutate original array", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			const arr = [1, 2, 3];
 			del(0)(arr);
 			arr[0]
@@ -1100,7 +1190,7 @@ func TestWalkWithNull(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("walk handles null values", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			walk(x => x === null ? 'was null' : x)({a: null, b: 1})
 		`)
 		require.NoError(t, err)
@@ -1114,7 +1204,7 @@ func TestGroupByWithPrototypeKeys(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("groupBy with hasOwnProperty key", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			groupBy(x => x)(['hasOwnProperty', 'toString', 'normal'])
 		`)
 		require.NoError(t, err)
@@ -1130,14 +1220,14 @@ func TestChunkEdgeCases(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("chunk size larger than array", func(t *testing.T) {
-		result, err := vm.RunString("chunk(10)([1, 2, 3])")
+		result, err := run(t, vm, "chunk(10)([1, 2, 3])")
 		require.NoError(t, err)
 		expected := []interface{}{[]interface{}{int64(1), int64(2), int64(3)}}
 		assert.Equal(t, expected, result.Export())
 	})
 
 	t.Run("chunk size equals array length", func(t *testing.T) {
-		result, err := vm.RunString("chunk(3)([1, 2, 3])")
+		result, err := run(t, vm, "chunk(3)([1, 2, 3])")
 		require.NoError(t, err)
 		expected := []interface{}{[]interface{}{int64(1), int64(2), int64(3)}}
 		assert.Equal(t, expected, result.Export())
@@ -1149,7 +1239,7 @@ func TestZipEdgeCases(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("zip single array", func(t *testing.T) {
-		result, err := vm.RunString("zip([1, 2, 3])")
+		result, err := run(t, vm, "zip([1, 2, 3])")
 		require.NoError(t, err)
 		expected := []interface{}{
 			[]interface{}{int64(1)},
@@ -1160,7 +1250,7 @@ func TestZipEdgeCases(t *testing.T) {
 	})
 
 	t.Run("zip with one empty array", func(t *testing.T) {
-		result, err := vm.RunString("zip([1, 2, 3], [])")
+		result, err := run(t, vm, "zip([1, 2, 3], [])")
 		require.NoError(t, err)
 		expected := []interface{}{}
 		assert.Equal(t, expected, result.Export())
@@ -1172,7 +1262,7 @@ func TestFilterWithObjects(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("filter objects by property", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			filter(x => x.active)([
 				{name: 'a', active: true},
 				{name: 'b', active: false},
@@ -1190,7 +1280,7 @@ func TestMapWithObjects(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("map extract property", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			map(x => x.name)([
 				{name: 'a', value: 1},
 				{name: 'b', value: 2}
@@ -1202,7 +1292,7 @@ func TestMapWithObjects(t *testing.T) {
 	})
 
 	t.Run("map transform object", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			map(x => ({...x, doubled: x.value * 2}))([
 				{value: 1},
 				{value: 2}
@@ -1221,7 +1311,7 @@ func TestSortByStability(t *testing.T) {
 
 	t.Run("sortBy preserves order for equal keys", func(t *testing.T) {
 		// Note: JavaScript sort is not guaranteed to be stable, but this tests the behavior
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			sortBy(x => x.group)([
 				{name: 'a', group: 1},
 				{name: 'b', group: 2},
@@ -1240,7 +1330,7 @@ func TestNestedOperations(t *testing.T) {
 	vm := setupVM(t)
 
 	t.Run("complex nested operations", func(t *testing.T) {
-		result, err := vm.RunString(`
+		result, err := run(t, vm, `
 			map(x => x * 2)(
 				filter(x => x > 0)(
 					flatten([
@@ -1255,3 +1345,141 @@ func TestNestedOperations(t *testing.T) {
 		assert.Equal(t, expected, result.Export())
 	})
 }
+
+func TestPatch(t *testing.T) {
+	vm := setupVM(t)
+
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"add member", `patch([{op: 'add', path: '/baz', value: 'qux'}])({foo: 'bar'})`, `{"foo":"bar","baz":"qux"}`},
+		{"add array element", `patch([{op: 'add', path: '/foo/1', value: 'qux'}])({foo: ['bar', 'baz']})`, `{"foo":["bar","qux","baz"]}`},
+		{"add to the end", `patch([{op: 'add', path: '/-', value: 3}])([1, 2])`, `[1,2,3]`},
+		{"add root", `patch([{op: 'add', path: '', value: 1}])({a: 2})`, `1`},
+		{"remove member", `patch([{op: 'remove', path: '/baz'}])({baz: 'qux', foo: 'bar'})`, `{"foo":"bar"}`},
+		{"remove array element", `patch([{op: 'remove', path: '/foo/1'}])({foo: ['bar', 'qux', 'baz']})`, `{"foo":["bar","baz"]}`},
+		{"replace keeps order", `patch([{op: 'replace', path: '/a', value: 3}])({a: 1, b: 2})`, `{"a":3,"b":2}`},
+		{"move", `patch([{op: 'move', from: '/foo/waldo', path: '/qux/thud'}])({foo: {waldo: 'fred'}, qux: {}})`, `{"foo":{},"qux":{"thud":"fred"}}`},
+		{"move array element", `patch([{op: 'move', from: '/1', path: '/3'}])(['all', 'grass', 'cows', 'eat'])`, `["all","cows","eat","grass"]`},
+		{"copy", `patch([{op: 'copy', from: '/a', path: '/b'}])({a: {c: 1}})`, `{"a":{"c":1},"b":{"c":1}}`},
+		{"test passes", `patch([{op: 'test', path: '/a', value: [1, {b: 2}]}])({a: [1, {b: 2}]})`, `{"a":[1,{"b":2}]}`},
+		{"escaped pointer", `patch([{op: 'replace', path: '/a~1b/m~0n', value: 1}])({'a/b': {'m~n': 0}})`, `{"a/b":{"m~n":1}}`},
+		{"does not mutate input", `(() => { const x = {a: [1]}; patch([{op: 'add', path: '/a/-', value: 2}])(x); return x })()`, `{"a":[1]}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := run(t, vm, "JSON.stringify("+tc.code+")")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Export())
+		})
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	vm := setupVM(t)
+
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"test fails", `patch([{op: 'add', path: '/a', value: 1}, {op: 'test', path: '/a', value: 2}])({})`, `patch: operation 1 (test /a): test failed: value is not 2`},
+		{"missing path", `patch([{op: 'remove', path: '/b'}])({a: 1})`, `patch: operation 0 (remove /b): path not found`},
+		{"missing parent", `patch([{op: 'add', path: '/a/b', value: 1}])({})`, `patch: operation 0 (add /a/b): path not found`},
+		{"index out of bounds", `patch([{op: 'add', path: '/5', value: 1}])([])`, `patch: operation 0 (add /5): array index 5 is out of bounds`},
+		{"leading zero index", `patch([{op: 'remove', path: '/01'}])([1, 2])`, `patch: operation 0 (remove /01): invalid array index "01"`},
+		{"invalid pointer", `patch([{op: 'remove', path: 'a'}])({a: 1})`, `patch: operation 0 (remove a): invalid JSON pointer "a"`},
+		{"missing value", `patch([{op: 'add', path: '/a'}])({})`, `patch: operation 0 (add /a): missing "value"`},
+		{"unknown op", `patch([{op: 'frob', path: '/a'}])({})`, `patch: operation 0 (frob /a): unknown operation "frob"`},
+		{"move into itself", `patch([{op: 'move', from: '/a', path: '/a/b'}])({a: {}})`, `patch: operation 0 (move /a/b): cannot move /a into itself`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := run(t, vm, tc.code)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expected)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	vm := setupVM(t)
+
+	// Test cases from RFC 7396, appendix A.
+	tests := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.target+" "+tc.patch, func(t *testing.T) {
+			result, err := run(t, vm, "JSON.stringify(mergePatch("+tc.patch+")("+tc.target+"))")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Export())
+		})
+	}
+}
+
+func TestDiffPatch(t *testing.T) {
+	vm := setupVM(t)
+
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{`{"a":1}`, `{"a":1}`, `[]`},
+		{`{"a":1,"b":2}`, `{"a":3,"c":4}`, `[{"op":"remove","path":"/b"},{"op":"replace","path":"/a","value":3},{"op":"add","path":"/c","value":4}]`},
+		{`[1,2,3]`, `[1,5]`, `[{"op":"replace","path":"/1","value":5},{"op":"remove","path":"/2"}]`},
+		{`[1]`, `[1,2,3]`, `[{"op":"add","path":"/1","value":2},{"op":"add","path":"/2","value":3}]`},
+		{`{"a/b":{"m~n":1}}`, `{"a/b":{"m~n":2}}`, `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`},
+		{`{"a":1}`, `[1]`, `[{"op":"replace","path":"","value":[1]}]`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			result, err := run(t, vm, "JSON.stringify(diffPatch("+tc.a+", "+tc.b+"))")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.Export())
+
+			// Applying the diff to a gives b.
+			result, err = run(t, vm, "JSON.stringify(patch(diffPatch("+tc.a+", "+tc.b+"))("+tc.a+"))")
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.b, result.String())
+		})
+	}
+}
+
+func TestPatchHelpersNotGlobal(t *testing.T) {
+	// Names used inside the patch helpers are free for .fxrc.js.
+	vm := engine.NewVM(func(string) {})
+	_, err := vm.RunString(engine.Stdlib + "\nlet decimalPrototype = 1\nfunction isPlainObject() { return false }\nconst cloneJSON = null\n")
+	require.NoError(t, err)
+
+	for _, name := range []string{"isDecimal", "setKey", "equalJSON", "escapePointer"} {
+		assert.Nil(t, vm.GlobalObject().Get(name), name)
+	}
+	result, err := vm.RunString(`JSON.stringify(patch([{op: 'add', path: '/a/b', value: {c: 1}}])({a: {}}))`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":{"b":{"c":1}}}`, result.String())
+}
@@ -9,9 +9,15 @@ import (
 	"time"
 
 	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/jsonx"
 )
 
 func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
+	if text, ok := jsonx.DecimalText(value); ok {
+		return text
+	}
+
 	rtype := value.ExportType()
 	if rtype == nil {
 		// Convert both null and undefined to null (save as JSON.stringify)
@@ -28,7 +34,18 @@ func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
 		return quoted
 	}
 
-	switch rtype.Kind() {
+	kind := rtype.Kind()
+	if rtype == proxyType {
+		// Lazy values are proxies, see jsonx.ToLazyValue.
+		switch value.Export().(goja.Proxy).Target().ClassName() {
+		case "Object":
+			kind = reflect.Map
+		case "Array":
+			kind = reflect.Slice
+		}
+	}
+
+	switch kind {
 	case reflect.Bool:
 		if value.ToBoolean() {
 			return "true"
@@ -54,6 +71,7 @@ func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
 	case reflect.Map:
 		obj := value.ToObject(vm)
 		keys := obj.Keys()
+		numberText := jsonx.NumberTexts(obj)
 
 		if len(keys) == 0 {
 			return "{}"
@@ -71,7 +89,12 @@ func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
 			out.WriteString(Quote(key))
 			out.WriteString(":")
 			out.WriteString(" ")
-			out.WriteString(Stringify(obj.Get(key), vm, depth+1))
+			item := obj.Get(key)
+			if text, ok := numberText(key, item); ok {
+				out.WriteString(text)
+			} else {
+				out.WriteString(Stringify(item, vm, depth+1))
+			}
 			if i < len(keys)-1 {
 				out.WriteString(",")
 			}
@@ -86,6 +109,7 @@ func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
 	case reflect.Slice:
 		arr := value.ToObject(vm)
 		keys := arr.Keys()
+		numberText := jsonx.NumberTexts(arr)
 
 		if len(keys) == 0 {
 			return "[]"
@@ -98,7 +122,11 @@ func Stringify(value goja.Value, vm *goja.Runtime, depth int) string {
 		for i, key := range keys {
 			item := arr.Get(key)
 			out.WriteString(strings.Repeat("  ", depth+1))
-			out.WriteString(Stringify(item, vm, depth+1))
+			if text, ok := numberText(key, item); ok {
+				out.WriteString(text)
+			} else {
+				out.WriteString(Stringify(item, vm, depth+1))
+			}
 			if i < len(keys)-1 {
 				out.WriteString(",")
 			}
@@ -12,10 +12,11 @@ import (
 var (
 	bigIntType   = reflect.TypeOf((*big.Int)(nil))
 	timeTimeType = reflect.TypeOf((*time.Time)(nil)).Elem()
+	proxyType    = reflect.TypeOf(goja.Proxy{})
 )
 
 var (
-	syntaxErrorRe = regexp.MustCompile(`^SyntaxError: SyntaxError: \(anonymous\): Line \d+:\d+\s+`)
+	syntaxErrorRe = regexp.MustCompile(`^(SyntaxError: )+\(anonymous\): Line \d+:\d+\s+`)
 	andMoreErrors = regexp.MustCompile(`\(and \d+ more errors\)$`)
 )
 
@@ -31,5 +32,8 @@ func errorToString(err error) string {
 		message = extractErrorMessage(message)
 		return message
 	}
+	if _, ok := err.(*goja.CompilerSyntaxError); ok {
+		return extractErrorMessage(err.Error())
+	}
 	return err.Error()
 }
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// Var is a global variable passed with one of VarFlags.
+type Var struct {
+	Name  string
+	Value *jsonx.Node
+}
+
+// VarFlags take a name and a value: a string, a JSON value, a file to read
+// as a string or a file to read as an array of JSON values.
+var VarFlags = []string{"--arg", "--argjson", "--rawfile", "--slurpfile"}
+
+// Vars are defined as globals in every NewVM.
+var Vars []Var
+
+var reIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// reservedWords can't name a variable in strict mode code.
+var reservedWords = []string{
+	"arguments", "await", "break", "case", "catch", "class", "const", "continue",
+	"debugger", "default", "delete", "do", "else", "enum", "eval", "export",
+	"extends", "false", "finally", "for", "function", "if", "implements", "import",
+	"in", "instanceof", "interface", "let", "new", "null", "package", "private",
+	"protected", "public", "return", "static", "super", "switch", "this", "throw",
+	"true", "try", "typeof", "var", "void", "while", "with", "yield",
+}
+
+// globalNames are the globals a variable would overwrite or be hidden by:
+// JS builtins, those of NewVM and the declarations of Stdlib, and json,
+// the parameter of __main__.
+var globalNames = sync.OnceValue(func() map[string]bool {
+	names := map[string]bool{"json": true}
+	vm := newVM(func(string) {})
+	if program, err := stdlib(); err == nil {
+		_, _ = vm.RunProgram(program)
+	}
+	for _, name := range vm.GlobalObject().GetOwnPropertyNames() {
+		names[name] = true
+	}
+	// Lexical declarations, like const skip, aren't properties of the global object.
+	if program, err := parser.ParseFile(nil, "", Stdlib, 0); err == nil {
+		for _, stmt := range program.Body {
+			if decl, ok := stmt.(*ast.LexicalDeclaration); ok {
+				for _, binding := range decl.List {
+					if id, ok := binding.Target.(*ast.Identifier); ok {
+						names[id.Name.String()] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+})
+
+// NewVar creates a variable for one of VarFlags.
+func NewVar(flag, name, value string) (Var, error) {
+	if !reIdent.MatchString(name) {
+		return Var{}, fmt.Errorf("%s: invalid variable name %q", flag, name)
+	}
+	if slices.Contains(reservedWords, name) {
+		return Var{}, fmt.Errorf("%s: variable name %q is a reserved word", flag, name)
+	}
+	if globalNames()[name] {
+		return Var{}, fmt.Errorf("%s: variable name %q is taken by a global of fx or JavaScript", flag, name)
+	}
+
+	var node *jsonx.Node
+	var err error
+	switch flag {
+	case "--arg":
+		node, err = stringNode(value)
+
+	case "--argjson":
+		node, err = jsonx.ParseValue(value)
+
+	case "--rawfile":
+		var b []byte
+		if b, err = os.ReadFile(value); err == nil {
+			node, err = stringNode(string(b))
+		}
+
+	case "--slurpfile":
+		var b []byte
+		if b, err = os.ReadFile(value); err == nil {
+			node, err = slurpNode(b)
+		}
+
+	default:
+		err = fmt.Errorf("unknown flag")
+	}
+	if err != nil {
+		return Var{}, fmt.Errorf("%s %s: %w", flag, name, err)
+	}
+	return Var{Name: name, Value: node}, nil
+}
+
+func stringNode(s string) (*jsonx.Node, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return jsonx.ParseValue(string(b))
+}
+
+func slurpNode(data []byte) (*jsonx.Node, error) {
+	var docs []string
+	parser := jsonx.NewJsonParser(bytes.NewReader(data), true)
+	for {
+		node, err := parser.Parse()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, jsonx.Compact(node))
+	}
+	return jsonx.ParseValue("[" + strings.Join(docs, ",") + "]")
+}
+
+// DefineGlobals sets vars and the env object with the process environment.
+func DefineGlobals(vm *goja.Runtime, vars []Var) error {
+	env := vm.NewObject()
+	environ := os.Environ()
+	sort.Strings(environ)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if err := env.Set(name, value); err != nil {
+			return err
+		}
+	}
+	if err := vm.Set("env", env); err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		if err := vm.Set(v.Name, v.Value.ToValue(vm)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package engine_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestNewVar(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	require.NoError(t, os.WriteFile(raw, []byte("line\n"), 0644))
+	docs := filepath.Join(dir, "docs.json")
+	require.NoError(t, os.WriteFile(docs, []byte("{\"a\": 1}\n[2]\n"), 0644))
+
+	tests := []struct {
+		flag, value, want string
+	}{
+		{"--arg", `say "hi"`, `"say \"hi\""`},
+		{"--argjson", `{"a": [1, 2]}`, `{"a":[1,2]}`},
+		{"--rawfile", raw, `"line\n"`},
+		{"--slurpfile", docs, `[{"a":1},[2]]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.flag, func(t *testing.T) {
+			v, err := engine.NewVar(tt.flag, "x", tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, "x", v.Name)
+			assert.Equal(t, tt.want, jsonx.Compact(v.Value))
+		})
+	}
+}
+
+func TestNewVar_Errors(t *testing.T) {
+	_, err := engine.NewVar("--arg", "1x", "value")
+	assert.EqualError(t, err, `--arg: invalid variable name "1x"`)
+
+	for _, name := range []string{"map", "len", "skip", "YAML", "console", "println", "Object", "json"} {
+		_, err = engine.NewVar("--arg", name, "value")
+		assert.EqualError(t, err, `--arg: variable name "`+name+`" is taken by a global of fx or JavaScript`)
+	}
+
+	_, err = engine.NewVar("--argjson", "if", "1")
+	assert.EqualError(t, err, `--argjson: variable name "if" is a reserved word`)
+
+	_, err = engine.NewVar("--argjson", "x", "{")
+	assert.ErrorContains(t, err, "--argjson x: ")
+
+	_, err = engine.NewVar("--rawfile", "x", filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "--rawfile x: ")
+}
+
+func TestQuery_Vars(t *testing.T) {
+	t.Setenv("FX_TEST_VAR", "from env")
+	name, err := engine.NewVar("--arg", "name", "a")
+	require.NoError(t, err)
+	engine.Vars = []engine.Var{name}
+	defer func() { engine.Vars = nil }()
+
+	results, err := engine.Query(context.Background(), parseDocs(t, `{"a":1}`), []string{"x => [x[name], env.FX_TEST_VAR]"})
+	require.NoError(t, err)
+	assert.Equal(t, `[1,"from env"]`, jsonx.Compact(results[0]))
+}
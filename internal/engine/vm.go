@@ -7,17 +7,34 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/goccy/go-yaml"
+
+	"github.com/antonmedv/fx/internal/compress"
+	"github.com/antonmedv/fx/internal/jsonpath"
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
 )
 
 // FilePath is the file being processed, empty if stdin.
 var FilePath string
 
+// FileCompression is the compression of FilePath, save() writes it back compressed.
+var FileCompression compress.Kind
+
 // ExitError is used by exit() to signal a specific exit code.
 type ExitError struct {
 	Code int
 }
 
 func NewVM(writeOut func(string)) *goja.Runtime {
+	vm := newVM(writeOut)
+	if err := DefineGlobals(vm, Vars); err != nil {
+		panic(err)
+	}
+	return vm
+}
+
+// newVM is NewVM without Vars.
+func newVM(writeOut func(string)) *goja.Runtime {
 	vm := goja.New()
 
 	if err := vm.Set("println", func(s string) any {
@@ -34,7 +51,11 @@ func NewVM(writeOut func(string)) *goja.Runtime {
 		if info, err := os.Lstat(FilePath); err == nil && info.Mode()&os.ModeSymlink != 0 {
 			return fmt.Errorf("cannot save to a symbolic link: %s", FilePath)
 		}
-		if err := os.WriteFile(FilePath, []byte(json), 0644); err != nil {
+		data, err := compress.Compress(FileCompression, []byte(json))
+		if err != nil {
+			return fmt.Errorf("cannot save %s: %w", FilePath, err)
+		}
+		if err := utils.WriteFileAtomic(FilePath, data); err != nil {
 			return err
 		}
 		return nil
@@ -75,7 +96,11 @@ func NewVM(writeOut func(string)) *goja.Runtime {
 	}
 
 	if err := vm.Set("__yaml_stringify__", func(x goja.Value) string {
-		b, err := yaml.JSONToYAML([]byte(Stringify(x, vm, 0)))
+		n, err := jsonx.Parse([]byte(Stringify(x, vm, 0)))
+		if err != nil {
+			return ""
+		}
+		b, err := ToYAML(n)
 		if err != nil {
 			return ""
 		}
@@ -84,11 +109,29 @@ func NewVM(writeOut func(string)) *goja.Runtime {
 		panic(err)
 	}
 
+	if err := vm.Set("__pointer__", func(pointer string) ([]any, error) {
+		tokens, ok := jsonpath.ParsePointer(pointer)
+		if !ok {
+			return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+		}
+		out := make([]any, len(tokens))
+		for i, token := range tokens {
+			out[i] = token
+		}
+		return out, nil
+	}); err != nil {
+		panic(err)
+	}
+
 	if err := vm.Set("__exit__", func(code int) {
 		panic(ExitError{Code: code})
 	}); err != nil {
 		panic(err)
 	}
 
+	if err := defineDecimal(vm); err != nil {
+		panic(err)
+	}
+
 	return vm
 }
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/antonmedv/fx/internal/ident"
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// ToXML converts a node to XML, reversing the convention of jsonx.XmlParser:
+//   - an object with one key, other than an array, is the root element,
+//   - an array is a <root> element with an <item> element per item,
+//   - other values are wrapped in a <root> element,
+//   - "@name" keys are attributes and "#text" is text, where an array of texts
+//     is interleaved with the child elements, as for mixed content,
+//   - other keys are child elements, repeated for arrays.
+//
+// Scalars become text, so types, nested arrays and empty arrays can't round-trip.
+func ToXML(n *jsonx.Node) (string, error) {
+	var out strings.Builder
+	fields := children(n)
+	var err error
+	switch {
+	case n.Kind == jsonx.Object && len(fields) == 1 && fields[0].Kind != jsonx.Array && !isXMLSpecialKey(unquoteKey(fields[0])):
+		err = xmlElement(&out, unquoteKey(fields[0]), fields[0], 0)
+	case n.Kind == jsonx.Array && len(fields) > 0:
+		out.WriteString("<root>\n")
+		for _, item := range fields {
+			if err = xmlElement(&out, "item", item, 1); err != nil {
+				break
+			}
+		}
+		out.WriteString("</root>\n")
+	case n.Kind == jsonx.Array:
+		out.WriteString("<root/>\n")
+	default:
+		err = xmlElement(&out, "root", n, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func xmlElement(out *strings.Builder, name string, n *jsonx.Node, level int) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("xml: %q is not a valid element name", name)
+	}
+	indent := strings.Repeat(ident.Ident, level)
+
+	switch n.Kind {
+	case jsonx.Null:
+		out.WriteString(indent + "<" + name + "/>\n")
+
+	case jsonx.Array:
+		for _, item := range children(n) {
+			if err := xmlElement(out, name, item, level); err != nil {
+				return err
+			}
+		}
+
+	case jsonx.Object:
+		var attrs string
+		var texts []string
+		var elements []*jsonx.Node
+		var names []string
+		for _, field := range children(n) {
+			key := unquoteKey(field)
+			switch {
+			case key == "#text":
+				t, err := xmlTexts(field)
+				if err != nil {
+					return fmt.Errorf("xml: text of <%s> must be a scalar or an array of scalars", name)
+				}
+				texts = t
+			case strings.HasPrefix(key, "@"):
+				if !isXMLName(key[1:]) {
+					return fmt.Errorf("xml: %q is not a valid attribute name", key[1:])
+				}
+				value, err := xmlText(field)
+				if err != nil {
+					return fmt.Errorf("xml: attribute %q must not be an object or array", key)
+				}
+				attrs += " " + key[1:] + `="` + value + `"`
+			case field.Kind == jsonx.Array:
+				// Each item is an element, so texts go between them.
+				for _, item := range children(field) {
+					elements = append(elements, item)
+					names = append(names, key)
+				}
+			default:
+				elements = append(elements, field)
+				names = append(names, key)
+			}
+		}
+		switch {
+		case len(elements) > 0:
+			out.WriteString(indent + "<" + name + attrs + ">\n")
+			for i, element := range elements {
+				if i < len(texts) && texts[i] != "" {
+					out.WriteString(indent + ident.Ident + texts[i] + "\n")
+				}
+				if err := xmlElement(out, names[i], element, level+1); err != nil {
+					return err
+				}
+			}
+			if len(texts) > len(elements) {
+				if text := strings.Join(texts[len(elements):], " "); text != "" {
+					out.WriteString(indent + ident.Ident + text + "\n")
+				}
+			}
+			out.WriteString(indent + "</" + name + ">\n")
+		case strings.Join(texts, "") != "":
+			out.WriteString(indent + "<" + name + attrs + ">" + strings.Join(texts, " ") + "</" + name + ">\n")
+		default:
+			out.WriteString(indent + "<" + name + attrs + "/>\n")
+		}
+
+	default:
+		text, err := xmlText(n)
+		if err != nil {
+			return err
+		}
+		out.WriteString(indent + "<" + name + ">" + text + "</" + name + ">\n")
+	}
+	return nil
+}
+
+// xmlTexts returns the escaped texts of a scalar node or an array of scalars.
+func xmlTexts(n *jsonx.Node) ([]string, error) {
+	if n.Kind != jsonx.Array {
+		text, err := xmlText(n)
+		return []string{text}, err
+	}
+	var texts []string
+	for _, item := range children(n) {
+		text, err := xmlText(item)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, nil
+}
+
+// xmlText returns the escaped text of a scalar node.
+func xmlText(n *jsonx.Node) (string, error) {
+	var s string
+	switch n.Kind {
+	case jsonx.Object, jsonx.Array:
+		return "", fmt.Errorf("xml: %s is not a scalar", jsonx.Compact(n))
+	case jsonx.String:
+		var err error
+		s, err = utils.Unquote(n.Value)
+		if err != nil {
+			return "", err
+		}
+	default:
+		s = n.Value
+	}
+	var out strings.Builder
+	for _, r := range s {
+		switch r {
+		case '&':
+			out.WriteString("&amp;")
+		case '<':
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		case '"':
+			out.WriteString("&quot;")
+		case '\n':
+			out.WriteString("&#xA;")
+		case '\r':
+			out.WriteString("&#xD;")
+		case '\t':
+			out.WriteString("&#x9;")
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String(), nil
+}
+
+func isXMLSpecialKey(key string) bool {
+	return key == "#text" || strings.HasPrefix(key, "@")
+}
+
+// isXMLName reports whether s is an XML name, with an optional namespace prefix.
+func isXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(s, ":") {
+		return false
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || r == '_' || r == ':' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+		return false
+	}
+	return true
+}
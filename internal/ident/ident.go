@@ -1,7 +1,6 @@
 package ident
 
 import (
-	"os"
 	"strconv"
 	"strings"
 )
@@ -11,15 +10,18 @@ var IdentBytes []byte
 var IdentWidth int
 
 func init() {
-	identValue, ok := os.LookupEnv("FX_INDENT")
-	if ok {
-		identInt, err := strconv.Atoi(identValue)
-		if err == nil {
-			Ident = strings.Repeat(" ", identInt)
-		} else {
-			Ident = identValue
-		}
+	Set(Ident)
+}
+
+// Set changes the indent: a number of spaces or the indent string itself.
+func Set(value string) {
+	Ident = value
+	identInt, err := strconv.Atoi(value)
+	if err == nil {
+		Ident = strings.Repeat(" ", identInt)
 	}
+	IdentBytes = nil
+	IdentWidth = 0
 	for _, r := range Ident {
 		if r == '\n' {
 			continue
@@ -0,0 +1,158 @@
+package infer
+
+import (
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Go returns Go type definitions of the shape, with the root type named name.
+func Go(s *Shape, name string) string {
+	g := &goTypes{names: namer{}}
+	root := g.names.name(name)
+	var out strings.Builder
+	if s.object != nil && len(s.kinds()) == 1 && !s.nullable() {
+		g.queue = append(g.queue, named{root, s.object})
+	} else {
+		out.WriteString("type " + root + " " + g.typeOf(s, root) + "\n")
+	}
+	for i := 0; i < len(g.queue); i++ {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		g.writeStruct(&out, g.queue[i])
+	}
+	formatted, err := format.Source([]byte(out.String()))
+	if err != nil {
+		return out.String()
+	}
+	return string(formatted)
+}
+
+type goTypes struct {
+	names namer
+	queue []named // Structs to print, in order of appearance.
+}
+
+func (g *goTypes) writeStruct(out *strings.Builder, s named) {
+	out.WriteString("type " + s.name + " struct {\n")
+	fieldNames := namer{}
+	for _, key := range s.object.keys {
+		shape := s.object.fields[key]
+		fieldName := fieldNames.name(goName(key))
+		t := g.typeOf(shape, fieldName)
+		tag := key
+		if s.object.optional(key) {
+			tag += ",omitempty"
+			if !strings.HasPrefix(t, "*") && !strings.HasPrefix(t, "[]") && t != "any" {
+				t = "*" + t
+			}
+		}
+		tag = `json:"` + tag + `"`
+		if strings.ContainsAny(tag, "`") {
+			tag = strconv.Quote(tag)
+		} else {
+			tag = "`" + tag + "`"
+		}
+		out.WriteString("\t" + fieldName + " " + t + " " + tag + "\n")
+	}
+	out.WriteString("}\n")
+}
+
+func (g *goTypes) typeOf(s *Shape, name string) string {
+	kinds := s.kinds()
+	if len(kinds) != 1 {
+		return "any"
+	}
+	var t string
+	switch kinds[0] {
+	case objectKind:
+		t = g.names.name(name)
+		g.queue = append(g.queue, named{t, s.object})
+	case arrayKind:
+		items := s.elements()
+		if items == nil {
+			return "[]any"
+		}
+		return "[]" + g.typeOf(items, singular(name))
+	case stringKind:
+		t = "string"
+	case integerKind:
+		t = "int64"
+	case numberKind:
+		t = "float64"
+	case booleanKind:
+		t = "bool"
+	}
+	if s.nullable() {
+		t = "*" + t
+	}
+	return t
+}
+
+var initialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "https": true, "id": true, "ip": true,
+	"json": true, "sql": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// goName returns an exported Go identifier for a key: "user_id" is UserID.
+func goName(key string) string {
+	var out strings.Builder
+	for _, word := range words(key) {
+		if initialisms[strings.ToLower(word)] {
+			out.WriteString(strings.ToUpper(word))
+		} else {
+			runes := []rune(word)
+			out.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+		}
+	}
+	name := out.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "F" + name
+	}
+	return name
+}
+
+// words splits a key on non-alphanumeric characters and on camelCase humps.
+func words(key string) []string {
+	var words []string
+	var word []rune
+	var prev rune
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+		case unicode.IsUpper(r) && unicode.IsLower(prev) && len(word) > 0:
+			words = append(words, string(word))
+			word = []rune{r}
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// singular returns the name of an item of an array named name: Users is User.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"), strings.HasSuffix(name, "is"):
+		return name + "Item"
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	default:
+		return name + "Item"
+	}
+}
@@ -0,0 +1,195 @@
+// Package infer merges the shapes of sample documents and prints them as a JSON Schema,
+// Go types or TypeScript types.
+package infer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// maxEnum is the most distinct values of strings printed as an enum. Strings are
+// enum-like if each of their values was seen at least twice on average.
+const maxEnum = 8
+
+// Shape is the merged type of observed values.
+type Shape struct {
+	count    int
+	null     int
+	boolean  int
+	integer  int
+	float    int
+	string   int
+	values   []string // Distinct strings in order of appearance, up to maxEnum+1.
+	object   *object
+	array    int
+	items    *Shape
+	nonEmpty int // Arrays with items.
+}
+
+type object struct {
+	count  int
+	keys   []string // In order of first appearance.
+	fields map[string]*Shape
+	seen   map[string]int // Objects with the key.
+}
+
+// Add merges the shape of a document.
+func (s *Shape) Add(n *jsonx.Node) {
+	s.count++
+	switch n.Kind {
+	case jsonx.Null:
+		s.null++
+	case jsonx.Bool:
+		s.boolean++
+	case jsonx.Number:
+		if strings.ContainsAny(n.Value, ".eE") {
+			s.float++
+		} else {
+			s.integer++
+		}
+	case jsonx.String:
+		s.string++
+		value, err := utils.Unquote(n.Value)
+		if err != nil {
+			value = n.Value
+		}
+		if len(s.values) <= maxEnum && !contains(s.values, value) {
+			s.values = append(s.values, value)
+		}
+	case jsonx.Object:
+		if s.object == nil {
+			s.object = &object{fields: map[string]*Shape{}, seen: map[string]int{}}
+		}
+		o := s.object
+		o.count++
+		seen := map[string]bool{}
+		for _, child := range n.Elements() {
+			key, err := utils.Unquote(child.Key)
+			if err != nil {
+				key = child.Key
+			}
+			field, ok := o.fields[key]
+			if !ok {
+				field = &Shape{}
+				o.fields[key] = field
+				o.keys = append(o.keys, key)
+			}
+			field.Add(child)
+			if !seen[key] {
+				seen[key] = true
+				o.seen[key]++
+			}
+		}
+	case jsonx.Array:
+		s.array++
+		if s.items == nil {
+			s.items = &Shape{}
+		}
+		elements := n.Elements()
+		if len(elements) > 0 {
+			s.nonEmpty++
+		}
+		for _, item := range elements {
+			s.items.Add(item)
+		}
+	}
+}
+
+// ArrayOf returns the shape of an array of the values of items, as of --slurp.
+func ArrayOf(items Shape) Shape {
+	s := Shape{count: 1, array: 1, items: &items}
+	if items.count > 0 {
+		s.nonEmpty = 1
+	}
+	return s
+}
+
+type kind int
+
+const (
+	objectKind kind = iota
+	arrayKind
+	stringKind
+	integerKind
+	numberKind
+	booleanKind
+	nullKind
+)
+
+// kinds returns the observed kinds, except null. Integers and floats together are numbers.
+func (s *Shape) kinds() []kind {
+	var kinds []kind
+	if s.object != nil {
+		kinds = append(kinds, objectKind)
+	}
+	if s.array > 0 {
+		kinds = append(kinds, arrayKind)
+	}
+	if s.string > 0 {
+		kinds = append(kinds, stringKind)
+	}
+	if s.float > 0 {
+		kinds = append(kinds, numberKind)
+	} else if s.integer > 0 {
+		kinds = append(kinds, integerKind)
+	}
+	if s.boolean > 0 {
+		kinds = append(kinds, booleanKind)
+	}
+	return kinds
+}
+
+func (s *Shape) nullable() bool {
+	return s.null > 0
+}
+
+// enum returns the values of enum-like strings.
+func (s *Shape) enum() ([]string, bool) {
+	if len(s.values) == 0 || len(s.values) > maxEnum || s.string < 2*len(s.values) {
+		return nil, false
+	}
+	return s.values, true
+}
+
+// optional reports whether some objects lack the key.
+func (o *object) optional(key string) bool {
+	return o.seen[key] < o.count
+}
+
+// elements returns the shape of array items, or nil if all arrays were empty.
+func (s *Shape) elements() *Shape {
+	if s.nonEmpty == 0 {
+		return nil
+	}
+	return s.items
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// named is an object type to print.
+type named struct {
+	name   string
+	object *object
+}
+
+// namer gives unique type names.
+type namer map[string]bool
+
+func (n namer) name(want string) string {
+	name := want
+	for i := 2; n[name]; i++ {
+		name = want + strconv.Itoa(i)
+	}
+	n[name] = true
+	return name
+}
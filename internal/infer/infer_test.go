@@ -0,0 +1,126 @@
+package infer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/infer"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func shapeOf(t *testing.T, docs ...string) *infer.Shape {
+	t.Helper()
+	var s infer.Shape
+	for _, doc := range docs {
+		node, err := jsonx.Parse([]byte(doc))
+		require.NoError(t, err)
+		s.Add(node)
+	}
+	return &s
+}
+
+var users = []string{
+	`{"id": 1, "name": "Ann", "role": "admin", "score": 1.5, "address": {"city": "Oslo"}, "tags": ["a"]}`,
+	`{"id": 2, "name": "Bob", "role": "user", "score": 2, "email": null, "tags": []}`,
+	`{"id": 3, "name": "Eve", "role": "user", "score": null, "email": "eve@example.com", "tags": ["b", "c"]}`,
+	`{"id": 4, "name": "Joe", "role": "admin", "score": 3, "address": {"city": "Rome", "zip": "00100"}, "tags": []}`,
+}
+
+func TestSchema(t *testing.T) {
+	assert.Equal(t, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{`+
+		`"id":{"type":"integer"},`+
+		`"name":{"type":"string"},`+
+		`"role":{"type":"string","enum":["admin","user"]},`+
+		`"score":{"type":["number","null"]},`+
+		`"address":{"type":"object","properties":{"city":{"type":"string"},"zip":{"type":"string"}},"required":["city"]},`+
+		`"tags":{"type":"array","items":{"type":"string"}},`+
+		`"email":{"type":["string","null"]}},`+
+		`"required":["id","name","role","score","tags"]}`,
+		infer.Schema(shapeOf(t, users...)))
+}
+
+func TestSchema_Unions(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []string
+		want string
+	}{
+		{"scalars", []string{`1`, `"a"`, `true`}, `{"type":["string","integer","boolean"]}`},
+		{"object or array", []string{`{}`, `[]`, `null`}, `{"anyOf":[{"type":"object","properties":{}},{"type":"array"},{"type":"null"}]}`},
+		{"nullable enum", []string{`"a"`, `"a"`, `null`}, `{"type":["string","null"],"enum":["a",null]}`},
+		{"nothing", nil, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := `{"$schema":"https://json-schema.org/draft/2020-12/schema",` + tt.want[1:]
+			if tt.want == `{}` {
+				want = `{"$schema":"https://json-schema.org/draft/2020-12/schema"}`
+			}
+			assert.Equal(t, want, infer.Schema(shapeOf(t, tt.docs...)))
+		})
+	}
+}
+
+func TestGo(t *testing.T) {
+	assert.Equal(t, "type Root struct {\n"+
+		"\tID      int64    `json:\"id\"`\n"+
+		"\tName    string   `json:\"name\"`\n"+
+		"\tRole    string   `json:\"role\"`\n"+
+		"\tScore   *float64 `json:\"score\"`\n"+
+		"\tAddress *Address `json:\"address,omitempty\"`\n"+
+		"\tTags    []string `json:\"tags\"`\n"+
+		"\tEmail   *string  `json:\"email,omitempty\"`\n"+
+		"}\n"+
+		"\n"+
+		"type Address struct {\n"+
+		"\tCity string  `json:\"city\"`\n"+
+		"\tZip  *string `json:\"zip,omitempty\"`\n"+
+		"}\n",
+		infer.Go(shapeOf(t, users...), "Root"))
+}
+
+func TestGo_Root(t *testing.T) {
+	assert.Equal(t, "type Root []RootItem\n\n"+
+		"type RootItem struct {\n"+
+		"\tUserID    string `json:\"user-id\"`\n"+
+		"\tF2fa      bool   `json:\"2fa\"`\n"+
+		"\tValue     any    `json:\"value\"`\n"+
+		"\tUserID2   string `json:\"userId\"`\n"+
+		"\tCreatedAt string `json:\"created_at\"`\n"+
+		"}\n",
+		infer.Go(shapeOf(t, `[{"user-id": "1", "2fa": true, "value": 1, "userId": "1", "created_at": "2020"}, {"user-id": "2", "2fa": false, "value": "x", "userId": "2", "created_at": "2021"}]`), "Root"))
+}
+
+func TestTypeScript(t *testing.T) {
+	assert.Equal(t, `export interface Root {
+  id: number;
+  name: string;
+  role: "admin" | "user";
+  score: number | null;
+  address?: Address;
+  tags: string[];
+  email?: string | null;
+}
+
+export interface Address {
+  city: string;
+  zip?: string;
+}
+`, infer.TypeScript(shapeOf(t, users...), "Root"))
+}
+
+func TestTypeScript_Root(t *testing.T) {
+	assert.Equal(t, `export type Root = (string | number)[] | null;
+`, infer.TypeScript(shapeOf(t, `[1, "a"]`, `null`), "Root"))
+	assert.Equal(t, `export type Root = RootItem[];
+
+export interface RootItem {
+  "a-b": unknown[];
+}
+`, infer.TypeScript(func() *infer.Shape {
+		s := infer.ArrayOf(*shapeOf(t, `{"a-b": []}`))
+		return &s
+	}(), "Root"))
+}
@@ -0,0 +1,130 @@
+package infer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Schema returns a JSON Schema (draft 2020-12) of the shape, as JSON text.
+func Schema(s *Shape) string {
+	root := schemaOf(s)
+	root = append(fields{{"$schema", quote("https://json-schema.org/draft/2020-12/schema")}}, root...)
+	return root.String()
+}
+
+// fields is a JSON object with keys in order and values as JSON text.
+type fields []field
+
+type field struct {
+	key, value string
+}
+
+func (f fields) String() string {
+	var out strings.Builder
+	out.WriteByte('{')
+	for i, field := range f {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteString(quote(field.key))
+		out.WriteByte(':')
+		out.WriteString(field.value)
+	}
+	out.WriteByte('}')
+	return out.String()
+}
+
+func schemaOf(s *Shape) fields {
+	var types []string
+	var alternatives []fields
+	for _, k := range s.kinds() {
+		switch k {
+		case objectKind:
+			alternatives = append(alternatives, objectSchema(s.object))
+		case arrayKind:
+			array := fields{{"type", quote("array")}}
+			if items := s.elements(); items != nil {
+				array = append(array, field{"items", schemaOf(items).String()})
+			}
+			alternatives = append(alternatives, array)
+		case stringKind:
+			if values, ok := s.enum(); ok {
+				enum := make([]string, len(values))
+				for i, v := range values {
+					enum[i] = quote(v)
+				}
+				if s.nullable() && len(s.kinds()) == 1 {
+					// A nullable enum, the only case with both type and enum.
+					return fields{
+						{"type", `["string","null"]`},
+						{"enum", "[" + strings.Join(enum, ",") + ",null]"},
+					}
+				}
+				alternatives = append(alternatives, fields{{"type", quote("string")}, {"enum", "[" + strings.Join(enum, ",") + "]"}})
+			} else {
+				types = append(types, "string")
+			}
+		case integerKind:
+			types = append(types, "integer")
+		case numberKind:
+			types = append(types, "number")
+		case booleanKind:
+			types = append(types, "boolean")
+		}
+	}
+	if s.nullable() {
+		types = append(types, "null")
+	}
+
+	switch {
+	case len(alternatives) == 0 && len(types) == 0:
+		return fields{}
+	case len(alternatives) == 0:
+		return fields{{"type", typeList(types)}}
+	case len(alternatives) == 1 && len(types) == 0:
+		return alternatives[0]
+	}
+	var anyOf []string
+	for _, a := range alternatives {
+		anyOf = append(anyOf, a.String())
+	}
+	if len(types) > 0 {
+		anyOf = append(anyOf, fields{{"type", typeList(types)}}.String())
+	}
+	return fields{{"anyOf", "[" + strings.Join(anyOf, ",") + "]"}}
+}
+
+func objectSchema(o *object) fields {
+	var properties fields
+	var required []string
+	for _, key := range o.keys {
+		properties = append(properties, field{key, schemaOf(o.fields[key]).String()})
+		if !o.optional(key) {
+			required = append(required, quote(key))
+		}
+	}
+	out := fields{{"type", quote("object")}, {"properties", properties.String()}}
+	if len(required) > 0 {
+		out = append(out, field{"required", "[" + strings.Join(required, ",") + "]"})
+	}
+	return out
+}
+
+func typeList(types []string) string {
+	if len(types) == 1 {
+		return quote(types[0])
+	}
+	quoted := make([]string, len(types))
+	for i, t := range types {
+		quoted[i] = quote(t)
+	}
+	return "[" + strings.Join(quoted, ",") + "]"
+}
+
+func quote(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,89 @@
+package infer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TypeScript returns TypeScript interfaces of the shape, with the root type named name.
+func TypeScript(s *Shape, name string) string {
+	ts := &tsTypes{names: namer{}}
+	root := ts.names.name(name)
+	var out strings.Builder
+	if s.object != nil && len(s.kinds()) == 1 && !s.nullable() {
+		ts.queue = append(ts.queue, named{root, s.object})
+	} else {
+		out.WriteString("export type " + root + " = " + ts.typeOf(s, root) + ";\n")
+	}
+	for i := 0; i < len(ts.queue); i++ {
+		if out.Len() > 0 {
+			out.WriteString("\n")
+		}
+		ts.writeInterface(&out, ts.queue[i])
+	}
+	return out.String()
+}
+
+type tsTypes struct {
+	names namer
+	queue []named // Interfaces to print, in order of appearance.
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func (ts *tsTypes) writeInterface(out *strings.Builder, i named) {
+	out.WriteString("export interface " + i.name + " {\n")
+	for _, key := range i.object.keys {
+		name := key
+		if !tsIdentifier.MatchString(key) {
+			name = quote(key)
+		}
+		if i.object.optional(key) {
+			name += "?"
+		}
+		out.WriteString("  " + name + ": " + ts.typeOf(i.object.fields[key], goName(key)) + ";\n")
+	}
+	out.WriteString("}\n")
+}
+
+func (ts *tsTypes) typeOf(s *Shape, name string) string {
+	var union []string
+	for _, k := range s.kinds() {
+		switch k {
+		case objectKind:
+			t := ts.names.name(name)
+			ts.queue = append(ts.queue, named{t, s.object})
+			union = append(union, t)
+		case arrayKind:
+			items := s.elements()
+			if items == nil {
+				union = append(union, "unknown[]")
+				break
+			}
+			t := ts.typeOf(items, singular(name))
+			if strings.Contains(t, " | ") {
+				t = "(" + t + ")"
+			}
+			union = append(union, t+"[]")
+		case stringKind:
+			if values, ok := s.enum(); ok {
+				for _, v := range values {
+					union = append(union, quote(v))
+				}
+			} else {
+				union = append(union, "string")
+			}
+		case integerKind, numberKind:
+			union = append(union, "number")
+		case booleanKind:
+			union = append(union, "boolean")
+		}
+	}
+	if s.nullable() {
+		union = append(union, "null")
+	}
+	if len(union) == 0 {
+		return "unknown"
+	}
+	return strings.Join(union, " | ")
+}
@@ -0,0 +1,92 @@
+// Package jq translates a practical subset of jq filters into JS for the engine.
+package jq
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Runtime defines __jq, the helpers translated filters call. It needs the engine's stdlib.
+//
+//go:embed runtime.js
+var Runtime string
+
+// builtins are the functions Runtime implements, as name/arity.
+var builtins = func() map[string]bool {
+	m := map[string]bool{}
+	for _, match := range regexp.MustCompile(`(?m)^\s+'(\w+/\d)':`).FindAllStringSubmatch(Runtime, -1) {
+		m[match[1]] = true
+	}
+	return m
+}()
+
+var formats = map[string]bool{
+	"text": true, "json": true, "html": true, "uri": true, "csv": true,
+	"tsv": true, "sh": true, "base64": true, "base64d": true,
+}
+
+// Translate returns a JS expression of a function from an input to the array of
+// all outputs of filter. Globals are the names of variables defined by the engine,
+// which filter can use as $name.
+func Translate(filter string, globals []string) (string, error) {
+	p := &parser{src: filter, globals: globals}
+	code, err := p.pipe(false)
+	if err != nil {
+		return "", err
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return "", p.unexpected(t, "")
+	}
+	return code, nil
+}
+
+// Error is a jq syntax error or an unsupported construct, at a byte offset of Filter.
+type Error struct {
+	Filter string
+	Pos    int
+	End    int
+	Msg    string
+}
+
+// Error prints the line of the filter with the error marked, like engine errors do for JS args.
+func (e *Error) Error() string {
+	start := strings.LastIndexByte(e.Filter[:e.Pos], '\n') + 1
+	end := strings.IndexByte(e.Filter[e.Pos:], '\n')
+	if end < 0 {
+		end = len(e.Filter)
+	} else {
+		end += e.Pos
+	}
+	line := strings.ReplaceAll(e.Filter[start:end], "\t", " ")
+	width := runewidth.StringWidth(e.Filter[e.Pos:min(max(e.End, e.Pos), end)])
+
+	var sb strings.Builder
+	sb.WriteString("\n  ")
+	sb.WriteString(line)
+	sb.WriteString("\n  ")
+	sb.WriteString(strings.Repeat(" ", runewidth.StringWidth(line[:e.Pos-start])))
+	sb.WriteString(strings.Repeat("^", max(width, 1)))
+	sb.WriteString("\n\n")
+	sb.WriteString("jq: ")
+	sb.WriteString(e.Msg)
+	return sb.String()
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &Error{Filter: p.src, Pos: t.pos, End: t.end, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) unexpected(t token, expected string) error {
+	msg := "unexpected end of filter"
+	if t.kind != tEOF {
+		msg = fmt.Sprintf("unexpected %s", p.src[t.pos:t.end])
+	}
+	if expected != "" {
+		msg += ", " + expected
+	}
+	return &Error{Filter: p.src, Pos: t.pos, End: t.end, Msg: msg}
+}
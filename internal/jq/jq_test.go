@@ -0,0 +1,59 @@
+package jq_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jq"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []string{
+		`.`,
+		`.a.b[0]`,
+		`.[] | select(.a > 1) | {a, b: .c}`,
+		`map(.x) | add // 0`,
+		`reduce .[] as $x (0; . + $x)`,
+		`"\(.a) and \(.b)"`,
+		`@base64 "x=\(.)"`,
+		`to_entries | group_by(.value) | length`,
+		`. as [$a, {b: $c}] | $a + $c`,
+		`$ENV.HOME, $__loc__`,
+		"# comment\n.a",
+	}
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			_, err := jq.Translate(filter, nil)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`.a |`, "\n  .a |\n      ^\n\njq: unexpected end of filter"},
+		{`.a ]`, "\n  .a ]\n     ^\n\njq: unexpected ]"},
+		{`foo(1)`, "\n  foo(1)\n  ^^^\n\njq: foo/1 is not defined"},
+		{`.a = 1`, "jq: assignment = is not supported"},
+		{`def f: .; f`, "jq: def is not supported"},
+		{`$x`, "jq: $x is not defined"},
+		{`{1: 2}`, "\n  {1: 2}\n   ^\n\njq:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := jq.Translate(tt.filter, nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestTranslate_Globals(t *testing.T) {
+	_, err := jq.Translate(`$name`, []string{"name"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,169 @@
+package jq
+
+import (
+	"strings"
+)
+
+type tokenKind byte
+
+const (
+	tEOF tokenKind = iota
+	tOp
+	tNumber
+	tString // Only the opening quote, strings are read by the parser because of interpolation.
+	tField
+	tVar
+	tFormat
+	tIdent
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	end  int
+}
+
+// ops are sorted so that longer operators match first.
+var ops = []string{
+	"?//", "//=", "|=", "+=", "-=", "*=", "/=", "%=", "==", "!=", "<=", ">=", "//", "..",
+	".", "[", "]", "{", "}", "(", ")", "|", ",", ":", ";", "=", "<", ">", "+", "-", "*", "/", "%", "?",
+}
+
+var keywords = map[string]bool{
+	"def": true, "if": true, "then": true, "elif": true, "else": true, "end": true,
+	"as": true, "reduce": true, "foreach": true, "try": true, "catch": true,
+	"label": true, "import": true, "include": true, "and": true, "or": true, "__loc__": true,
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skip moves past whitespace and comments.
+func (p *parser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	p.skip()
+	start := p.pos
+	if start >= len(p.src) {
+		return token{kind: tEOF, pos: start, end: start}
+	}
+	ident := func(from int) int {
+		end := from
+		for end < len(p.src) && isIdentChar(p.src[end]) {
+			end++
+		}
+		// Namespaced names like mod::fn.
+		for strings.HasPrefix(p.src[end:], "::") && end+2 < len(p.src) && isIdentStart(p.src[end+2]) {
+			end += 2
+			for end < len(p.src) && isIdentChar(p.src[end]) {
+				end++
+			}
+		}
+		return end
+	}
+
+	c := p.src[start]
+	switch {
+	case c == '"':
+		return token{kind: tString, text: `"`, pos: start, end: start + 1}
+	case isDigit(c) || c == '.' && start+1 < len(p.src) && isDigit(p.src[start+1]):
+		end := start
+		for end < len(p.src) && isDigit(p.src[end]) {
+			end++
+		}
+		if end < len(p.src) && p.src[end] == '.' {
+			end++
+			for end < len(p.src) && isDigit(p.src[end]) {
+				end++
+			}
+		}
+		if end < len(p.src) && (p.src[end] == 'e' || p.src[end] == 'E') {
+			exp := end + 1
+			if exp < len(p.src) && (p.src[exp] == '+' || p.src[exp] == '-') {
+				exp++
+			}
+			if exp < len(p.src) && isDigit(p.src[exp]) {
+				end = exp
+				for end < len(p.src) && isDigit(p.src[end]) {
+					end++
+				}
+			}
+		}
+		return token{kind: tNumber, text: p.src[start:end], pos: start, end: end}
+	case c == '.' && start+1 < len(p.src) && isIdentStart(p.src[start+1]):
+		end := start + 1
+		for end < len(p.src) && isIdentChar(p.src[end]) {
+			end++
+		}
+		return token{kind: tField, text: p.src[start+1 : end], pos: start, end: end}
+	case (c == '$' || c == '@') && start+1 < len(p.src) && isIdentStart(p.src[start+1]):
+		end := ident(start + 1)
+		kind := tVar
+		if c == '@' {
+			kind = tFormat
+		}
+		return token{kind: kind, text: p.src[start+1 : end], pos: start, end: end}
+	case isIdentStart(c):
+		end := ident(start)
+		return token{kind: tIdent, text: p.src[start:end], pos: start, end: end}
+	}
+	for _, op := range ops {
+		if strings.HasPrefix(p.src[start:], op) {
+			return token{kind: tOp, text: op, pos: start, end: start + len(op)}
+		}
+	}
+	return token{kind: tOp, text: p.src[start : start+1], pos: start, end: start + 1}
+}
+
+// next consumes the next token.
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos = t.end
+	return t
+}
+
+// is reports whether the next token is the operator or the keyword s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.kind == tOp || t.kind == tIdent) && t.text == s
+}
+
+// accept consumes the next token if it is the operator or the keyword s.
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.unexpected(p.peek(), "expected "+s)
+	}
+	return nil
+}
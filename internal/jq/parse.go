@@ -0,0 +1,803 @@
+package jq
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// unsupported are jq builtins Runtime doesn't implement, reported as such rather than undefined.
+var unsupported = map[string]bool{
+	"path": true, "del": true, "delpaths": true, "setpath": true, "pick": true, "to_array": true,
+	"input": true, "inputs": true, "debug": true, "stderr": true, "input_filename": true,
+	"input_line_number": true, "tostream": true, "fromstream": true, "truncate_stream": true,
+	"halt": true, "halt_error": true, "match": true, "ascii": true, "combinations": true,
+	"todate": true, "fromdate": true, "strftime": true, "strptime": true, "mktime": true,
+	"gmtime": true, "localtime": true, "date": true, "dateadd": true, "datesub": true,
+	"significand": true, "gamma": true, "frexp": true, "ldexp": true, "builtins": true,
+	"repeat": true, "IN": true, "INDEX": true, "getpath": true, "splits": true,
+	"have_literal_numbers": true, "have_decnum": true, "get_search_list": true, "modulemeta": true,
+}
+
+type parser struct {
+	src     string
+	pos     int
+	globals []string // Variables defined by the engine.
+	scope   []string // Variables bound by the filter, innermost last.
+	tmp     int      // Counter for temporary JS names.
+	terms   map[int]parsed
+}
+
+// parsed is a postfix term parsed at some position, kept as pipe parses terms
+// once to look for `as` and again as the start of an expression.
+type parsed struct {
+	code string
+	err  error
+	end  int
+}
+
+// pipe parses a whole filter: pipes, commas and variable bindings.
+// In object values commas separate entries, so noComma stops at them.
+func (p *parser) pipe(noComma bool) (string, error) {
+	switch t := p.peek(); {
+	case t.kind == tIdent && (t.text == "def" || t.text == "label" || t.text == "import" || t.text == "include"):
+		return "", p.errorf(t, "%s is not supported", t.text)
+	}
+
+	start := p.pos
+	if term, err := p.postfix(); err == nil && p.is("as") {
+		return p.bind(term)
+	}
+	p.pos = start
+
+	left, err := p.comma(noComma)
+	if err != nil {
+		return "", err
+	}
+	if p.accept("|") {
+		right, err := p.pipe(noComma)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.pipe(%s, %s)", left, right), nil
+	}
+	return left, nil
+}
+
+// bind parses `as $pattern | body` after the source term.
+func (p *parser) bind(source string) (string, error) {
+	p.next() // as
+	if p.is("?//") {
+		return "", p.errorf(p.peek(), "destructuring alternatives are not supported")
+	}
+	v := p.temp()
+	vars, bindings, err := p.pattern(v)
+	if err != nil {
+		return "", err
+	}
+	if t := p.peek(); t.kind == tOp && t.text == "?//" {
+		return "", p.errorf(t, "destructuring alternatives are not supported")
+	}
+	if err := p.expect("|"); err != nil {
+		return "", err
+	}
+	body, err := p.scoped(vars, func() (string, error) { return p.pipe(false) })
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(x => %s(x).flatMap(%s => {%s return %s(x) }))", source, v, bindings, body), nil
+}
+
+// scoped parses with vars in scope.
+func (p *parser) scoped(vars []string, parse func() (string, error)) (string, error) {
+	n := len(p.scope)
+	p.scope = append(p.scope, vars...)
+	defer func() { p.scope = p.scope[:n] }()
+	return parse()
+}
+
+func (p *parser) temp() string {
+	p.tmp++
+	return fmt.Sprintf("__v%d", p.tmp)
+}
+
+// pattern parses a destructuring pattern of the value in the JS variable v.
+// It returns the bound variables, and the JS statements binding them.
+func (p *parser) pattern(v string) ([]string, string, error) {
+	accessors := map[string]string{}
+	var vars []string
+	bindVar := func(name, value string) {
+		if _, ok := accessors[name]; !ok {
+			vars = append(vars, name)
+		}
+		accessors[name] = value
+	}
+
+	var walk func(value string) error
+	walk = func(value string) error {
+		switch t := p.next(); {
+		case t.kind == tVar:
+			bindVar(t.text, value)
+			return nil
+
+		case t.kind == tOp && t.text == "[":
+			for i := 0; ; i++ {
+				if err := walk(fmt.Sprintf("__jq.destructure(%s, %d)", value, i)); err != nil {
+					return err
+				}
+				if p.accept("]") {
+					return nil
+				}
+				if err := p.expect(","); err != nil {
+					return err
+				}
+			}
+
+		case t.kind == tOp && t.text == "{":
+			for {
+				var key string
+				switch k := p.next(); {
+				case k.kind == tVar:
+					key = strconv.Quote(k.text)
+					bindVar(k.text, fmt.Sprintf("__jq.destructure(%s, %s)", value, key))
+					if !p.accept(":") {
+						goto next
+					}
+				case k.kind == tIdent:
+					key = strconv.Quote(k.text)
+					if err := p.expect(":"); err != nil {
+						return err
+					}
+				case k.kind == tString:
+					s, err := p.literalString(k)
+					if err != nil {
+						return err
+					}
+					key = s
+					if err := p.expect(":"); err != nil {
+						return err
+					}
+				case k.kind == tOp && k.text == "(":
+					return p.errorf(k, "computed keys in patterns are not supported")
+				default:
+					return p.unexpected(k, "expected an object pattern key")
+				}
+				if err := walk(fmt.Sprintf("__jq.destructure(%s, %s)", value, key)); err != nil {
+					return err
+				}
+			next:
+				if p.accept("}") {
+					return nil
+				}
+				if err := p.expect(","); err != nil {
+					return err
+				}
+			}
+		default:
+			return p.unexpected(t, "expected a pattern")
+		}
+	}
+	if err := walk(v); err != nil {
+		return nil, "", err
+	}
+
+	var bindings strings.Builder
+	for _, name := range vars {
+		fmt.Fprintf(&bindings, " const $%s = %s;", name, accessors[name])
+	}
+	return vars, bindings.String(), nil
+}
+
+func (p *parser) comma(noComma bool) (string, error) {
+	left, err := p.alternative()
+	if err != nil {
+		return "", err
+	}
+	for !noComma && p.accept(",") {
+		right, err := p.alternative()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("__jq.comma(%s, %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) alternative() (string, error) {
+	left, err := p.assignment()
+	if err != nil {
+		return "", err
+	}
+	if p.accept("//") {
+		right, err := p.alternative()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.alternative(%s, %s)", left, right), nil
+	}
+	return left, nil
+}
+
+func (p *parser) assignment() (string, error) {
+	left, err := p.or()
+	if err != nil {
+		return "", err
+	}
+	if t := p.peek(); t.kind == tOp {
+		switch t.text {
+		case "=", "|=", "+=", "-=", "*=", "/=", "%=", "//=":
+			return "", p.errorf(t, "assignment %s is not supported", t.text)
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) or() (string, error) {
+	left, err := p.and()
+	if err != nil {
+		return "", err
+	}
+	for p.accept("or") {
+		right, err := p.and()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("__jq.or(%s, %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) and() (string, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return "", err
+	}
+	for p.accept("and") {
+		right, err := p.comparison()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("__jq.and(%s, %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) comparison() (string, error) {
+	left, err := p.binary(0)
+	if err != nil {
+		return "", err
+	}
+	if t := p.peek(); t.kind == tOp {
+		switch t.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+			p.next()
+			right, err := p.binary(0)
+			if err != nil {
+				return "", err
+			}
+			if t := p.peek(); t.kind == tOp && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, t.text) {
+				return "", p.unexpected(t, "comparisons are not associative")
+			}
+			return fmt.Sprintf("__jq.binary(%q, %s, %s)", t.text, left, right), nil
+		}
+	}
+	return left, nil
+}
+
+// precedence lists left associative arithmetic operators, from the lowest.
+var precedence = [][]string{{"+", "-"}, {"*", "/", "%"}}
+
+func (p *parser) binary(level int) (string, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return "", err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tOp || !slices.Contains(precedence[level], t.text) {
+			return left, nil
+		}
+		p.next()
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("__jq.binary(%q, %s, %s)", t.text, left, right)
+	}
+}
+
+func (p *parser) unary() (string, error) {
+	if p.accept("-") {
+		term, err := p.postfix()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.negate(%s)", term), nil
+	}
+	return p.postfix()
+}
+
+// postfix parses a term followed by fields, indexes, slices, iterations and ?.
+func (p *parser) postfix() (string, error) {
+	p.skip()
+	start := p.pos
+	if t, ok := p.terms[start]; ok {
+		p.pos = t.end
+		return t.code, t.err
+	}
+	code, err := p.parsePostfix()
+	if p.terms == nil {
+		p.terms = map[int]parsed{}
+	}
+	p.terms[start] = parsed{code, err, p.pos}
+	return code, err
+}
+
+func (p *parser) parsePostfix() (string, error) {
+	term, err := p.term()
+	if err != nil {
+		return "", err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tField:
+			p.next()
+			term = fmt.Sprintf("__jq.field(%s, %s)", term, strconv.Quote(t.text))
+		case t.kind == tOp && t.text == "." && p.adjacent(t, "["):
+			p.next()
+		case t.kind == tOp && t.text == "." && p.adjacent(t, `"`):
+			p.pos = t.end + 1
+			s, err := p.string("")
+			if err != nil {
+				return "", err
+			}
+			term = fmt.Sprintf("__jq.index(%s, %s)", term, s)
+		case t.kind == tOp && t.text == "[":
+			if term, err = p.suffix(term); err != nil {
+				return "", err
+			}
+		case t.kind == tOp && t.text == "?":
+			p.next()
+			term = fmt.Sprintf("__jq.try(%s)", term)
+		default:
+			return term, nil
+		}
+	}
+}
+
+// adjacent reports whether s follows t without whitespace.
+func (p *parser) adjacent(t token, s string) bool {
+	return strings.HasPrefix(p.src[t.end:], s)
+}
+
+// suffix parses [], [e], [e:e], [:e] and [e:] applied to term.
+func (p *parser) suffix(term string) (string, error) {
+	p.next() // [
+	if p.accept("]") {
+		return fmt.Sprintf("__jq.iterate(%s)", term), nil
+	}
+	null := "__jq.constant(null)"
+	if p.accept(":") {
+		to, err := p.pipe(false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.slice(%s, %s, %s)", term, null, to), p.expect("]")
+	}
+	from, err := p.pipe(false)
+	if err != nil {
+		return "", err
+	}
+	if p.accept(":") {
+		to := null
+		if !p.is("]") {
+			if to, err = p.pipe(false); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("__jq.slice(%s, %s, %s)", term, from, to), p.expect("]")
+	}
+	return fmt.Sprintf("__jq.index(%s, %s)", term, from), p.expect("]")
+}
+
+func (p *parser) term() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tEOF:
+		return "", p.unexpected(t, "expected a filter")
+
+	case tNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return "", p.errorf(t, "invalid number %s", t.text)
+		}
+		return fmt.Sprintf("__jq.constant(%s)", strconv.FormatFloat(f, 'g', -1, 64)), nil
+
+	case tString:
+		return p.string("")
+
+	case tFormat:
+		if !formats[t.text] {
+			return "", p.errorf(t, "%s is not a valid format", t.text)
+		}
+		if s := p.peek(); s.kind == tString {
+			p.next()
+			return p.string(t.text)
+		}
+		return fmt.Sprintf("__jq.format(%q)", t.text), nil
+
+	case tField:
+		return fmt.Sprintf("__jq.field(__jq.id, %s)", strconv.Quote(t.text)), nil
+
+	case tVar:
+		return p.variable(t)
+
+	case tIdent:
+		return p.keyword(t)
+	}
+
+	switch t.text {
+	case ".":
+		if p.adjacent(t, `"`) {
+			p.pos++
+			s, err := p.string("")
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("__jq.index(__jq.id, %s)", s), nil
+		}
+		return "__jq.id", nil
+	case "..":
+		return "__jq.recurse", nil
+	case "(":
+		inner, err := p.pipe(false)
+		if err != nil {
+			return "", err
+		}
+		return inner, p.expect(")")
+	case "[":
+		if p.accept("]") {
+			return "__jq.constant([])", nil
+		}
+		inner, err := p.pipe(false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.collect(%s)", inner), p.expect("]")
+	case "{":
+		return p.object()
+	case "?//":
+		return "", p.errorf(t, "destructuring alternatives are not supported")
+	}
+	return "", p.unexpected(t, "")
+}
+
+func (p *parser) variable(t token) (string, error) {
+	switch {
+	case slices.Contains(p.scope, t.text):
+		return fmt.Sprintf("(x => [$%s])", t.text), nil
+	case t.text == "ENV":
+		return "(x => [env])", nil
+	case t.text == "__loc__":
+		line := strings.Count(p.src[:t.pos], "\n") + 1
+		return fmt.Sprintf(`__jq.constant({file: "<stdin>", line: %d})`, line), nil
+	case slices.Contains(p.globals, t.text):
+		return fmt.Sprintf("(x => [%s])", t.text), nil
+	}
+	return "", p.errorf(t, "$%s is not defined", t.text)
+}
+
+// keyword parses a term starting with an identifier: a literal, a keyword or a function call.
+func (p *parser) keyword(t token) (string, error) {
+	switch t.text {
+	case "true", "false", "null":
+		return fmt.Sprintf("__jq.constant(%s)", t.text), nil
+	case "if":
+		return p.ifThen()
+	case "try":
+		body, err := p.postfix()
+		if err != nil {
+			return "", err
+		}
+		if !p.accept("catch") {
+			return fmt.Sprintf("__jq.try(%s)", body), nil
+		}
+		handler, err := p.postfix()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.try(%s, %s)", body, handler), nil
+	case "reduce", "foreach":
+		return p.fold(t)
+	case "def", "label", "import", "include":
+		return "", p.errorf(t, "%s is not supported", t.text)
+	}
+	if keywords[t.text] {
+		return "", p.unexpected(t, "")
+	}
+	if strings.Contains(t.text, "::") {
+		return "", p.errorf(t, "modules are not supported")
+	}
+
+	var args []string
+	if p.adjacent(t, "(") {
+		p.next()
+		for {
+			arg, err := p.pipe(false)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(";"); err != nil {
+				return "", err
+			}
+		}
+	}
+	name := fmt.Sprintf("%s/%d", t.text, len(args))
+	if !builtins[name] {
+		if unsupported[t.text] || unsupported[name] {
+			return "", p.errorf(t, "%s is not supported", name)
+		}
+		return "", p.errorf(t, "%s is not defined", name)
+	}
+	return fmt.Sprintf("__jq.builtins[%q](%s)", name, strings.Join(args, ", ")), nil
+}
+
+func (p *parser) ifThen() (string, error) {
+	cond, err := p.pipe(false)
+	if err != nil {
+		return "", err
+	}
+	if err := p.expect("then"); err != nil {
+		return "", err
+	}
+	then, err := p.pipe(false)
+	if err != nil {
+		return "", err
+	}
+	otherwise := "__jq.id"
+	switch {
+	case p.accept("elif"):
+		if otherwise, err = p.ifThen(); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("__jq.if(%s, %s, %s)", cond, then, otherwise), nil
+	case p.accept("else"):
+		if otherwise, err = p.pipe(false); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("__jq.if(%s, %s, %s)", cond, then, otherwise), p.expect("end")
+}
+
+// fold parses reduce SOURCE as $x (INIT; UPDATE) and foreach SOURCE as $x (INIT; UPDATE; EXTRACT).
+func (p *parser) fold(t token) (string, error) {
+	source, err := p.postfix()
+	if err != nil {
+		return "", err
+	}
+	if err := p.expect("as"); err != nil {
+		return "", err
+	}
+	v := p.temp()
+	vars, bindings, err := p.pattern(v)
+	if err != nil {
+		return "", err
+	}
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+	init, err := p.pipe(false)
+	if err != nil {
+		return "", err
+	}
+	bound := func() (string, error) {
+		if err := p.expect(";"); err != nil {
+			return "", err
+		}
+		f, err := p.scoped(vars, func() (string, error) { return p.pipe(false) })
+		return fmt.Sprintf("(%s => {%s return %s })", v, bindings, f), err
+	}
+	update, err := bound()
+	if err != nil {
+		return "", err
+	}
+	if t.text == "reduce" {
+		return fmt.Sprintf("__jq.reduce(%s, %s, %s)", source, init, update), p.expect(")")
+	}
+	extract := "(() => __jq.id)"
+	if p.is(";") {
+		if extract, err = bound(); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("__jq.foreach(%s, %s, %s, %s)", source, init, update, extract), p.expect(")")
+}
+
+// object parses the entries of {...}.
+func (p *parser) object() (string, error) {
+	var entries []string
+	if p.accept("}") {
+		return "__jq.constant({})", nil
+	}
+	for {
+		var key, value string
+		var err error
+		switch t := p.next(); {
+		case t.kind == tVar:
+			if value, err = p.variable(t); err != nil {
+				return "", err
+			}
+			key = fmt.Sprintf("__jq.constant(%s)", strconv.Quote(t.text))
+		case t.kind == tIdent:
+			key = fmt.Sprintf("__jq.constant(%s)", strconv.Quote(t.text))
+			value = fmt.Sprintf("__jq.field(__jq.id, %s)", strconv.Quote(t.text))
+		case t.kind == tNumber:
+			return "", p.errorf(t, "object keys must be strings")
+		case t.kind == tString:
+			if key, err = p.string(""); err != nil {
+				return "", err
+			}
+			value = fmt.Sprintf("__jq.index(__jq.id, %s)", key)
+		case t.kind == tFormat:
+			if !formats[t.text] {
+				return "", p.errorf(t, "%s is not a valid format", t.text)
+			}
+			if err := p.expectString(); err != nil {
+				return "", err
+			}
+			if key, err = p.string(t.text); err != nil {
+				return "", err
+			}
+			value = fmt.Sprintf("__jq.index(__jq.id, %s)", key)
+		case t.kind == tOp && t.text == "(":
+			if key, err = p.pipe(false); err != nil {
+				return "", err
+			}
+			if err := p.expect(")"); err != nil {
+				return "", err
+			}
+			if !p.is(":") {
+				return "", p.unexpected(p.peek(), "expected :")
+			}
+		default:
+			return "", p.unexpected(t, "expected an object key")
+		}
+		if p.accept(":") {
+			if value, err = p.pipe(true); err != nil {
+				return "", err
+			}
+		}
+		entries = append(entries, fmt.Sprintf("[%s, %s]", key, value))
+		if p.accept("}") {
+			return fmt.Sprintf("__jq.object([%s])", strings.Join(entries, ", ")), nil
+		}
+		if err := p.expect(","); err != nil {
+			return "", err
+		}
+	}
+}
+
+func (p *parser) expectString() error {
+	if t := p.peek(); t.kind != tString {
+		return p.unexpected(t, "expected a string")
+	}
+	p.next()
+	return nil
+}
+
+// literalString parses a string without interpolation, returning it quoted for JS.
+func (p *parser) literalString(t token) (string, error) {
+	s, err := p.string("")
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(s, "__jq.constant(") {
+		return "", p.errorf(t, "string interpolation is not allowed here")
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(s, "__jq.constant("), ")"), nil
+}
+
+// string parses the rest of a string after the opening quote, with \(...) interpolations
+// formatted by format, e.g. @base64 "token: \(.token)".
+func (p *parser) string(format string) (string, error) {
+	start := p.pos - 1
+	var parts []string
+	var lit strings.Builder
+	flush := func() {
+		b, _ := json.Marshal(lit.String())
+		parts = append(parts, string(b))
+		lit.Reset()
+	}
+	interpolated := false
+	for {
+		if p.pos >= len(p.src) {
+			return "", &Error{Filter: p.src, Pos: start, End: p.pos, Msg: "unterminated string"}
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			flush()
+			if !interpolated {
+				return fmt.Sprintf("__jq.constant(%s)", parts[0]), nil
+			}
+			if format == "" {
+				format = "text"
+			}
+			return fmt.Sprintf("__jq.string(%q, [%s])", format, strings.Join(parts, ", ")), nil
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.src) {
+				continue
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case '"', '\\', '/':
+				lit.WriteByte(e)
+			case 'b':
+				lit.WriteByte('\b')
+			case 'f':
+				lit.WriteByte('\f')
+			case 'n':
+				lit.WriteByte('\n')
+			case 'r':
+				lit.WriteByte('\r')
+			case 't':
+				lit.WriteByte('\t')
+			case 'u':
+				r, ok := p.hex4()
+				if ok && utf16.IsSurrogate(r) && strings.HasPrefix(p.src[p.pos:], `\u`) {
+					p.pos += 2
+					low, _ := p.hex4()
+					r = utf16.DecodeRune(r, low)
+				}
+				if !ok {
+					return "", &Error{Filter: p.src, Pos: p.pos - 2, End: p.pos, Msg: "invalid \\u escape"}
+				}
+				lit.WriteRune(r)
+			case '(':
+				interpolated = true
+				flush()
+				inner, err := p.pipe(false)
+				if err != nil {
+					return "", err
+				}
+				if err := p.expect(")"); err != nil {
+					return "", err
+				}
+				parts = append(parts, inner)
+			default:
+				return "", &Error{Filter: p.src, Pos: p.pos - 2, End: p.pos, Msg: fmt.Sprintf("invalid escape \\%c", e)}
+			}
+		default:
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			lit.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+func (p *parser) hex4() (rune, bool) {
+	if p.pos+4 > len(p.src) {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	p.pos += 4
+	return rune(n), true
+}
@@ -0,0 +1,713 @@
+'use strict'
+
+// Every jq filter is translated to a function from an input to an array of outputs.
+const __jq = (function () {
+  const decimalPrototype = Object.getPrototypeOf(decimal('1.0'))
+
+  class JqError extends Error {
+    constructor(value) {
+      super(typeof value === 'string' ? value : JSON.stringify(value) + ' (not a string)')
+      this.value = value
+    }
+  }
+
+  function fail(message) {
+    throw new JqError(message)
+  }
+
+  function isNumber(v) {
+    return typeof v === 'number' || (v !== null && typeof v === 'object' && Object.getPrototypeOf(v) === decimalPrototype)
+  }
+
+  function type(v) {
+    if (v === null || v === undefined) return 'null'
+    if (typeof v === 'boolean') return 'boolean'
+    if (isNumber(v)) return 'number'
+    if (typeof v === 'string') return 'string'
+    if (Array.isArray(v)) return 'array'
+    return 'object'
+  }
+
+  function describe(v) {
+    const json = tojson(v)
+    return `${type(v)} (${json.length > 11 ? json.slice(0, 10) + '...' : json})`
+  }
+
+  function truthy(v) {
+    return v !== false && v !== null && v !== undefined
+  }
+
+  function tojson(v) {
+    return JSON.stringify(v === undefined ? null : v)
+  }
+
+  function tostring(v) {
+    return typeof v === 'string' ? v : tojson(v)
+  }
+
+  function has(obj, key) {
+    return Object.prototype.hasOwnProperty.call(obj, key)
+  }
+
+  const rank = {null: 0, false: 1, true: 2, number: 3, string: 4, array: 5, object: 6}
+
+  function rankOf(v) {
+    const t = type(v)
+    return t === 'boolean' ? rank[v] : rank[t]
+  }
+
+  // compare orders values as jq does: null < false < true < numbers < strings < arrays < objects.
+  function compare(a, b) {
+    const ra = rankOf(a), rb = rankOf(b)
+    if (ra !== rb) return ra < rb ? -1 : 1
+    switch (type(a)) {
+      case 'number': {
+        const x = Number(a), y = Number(b)
+        return x < y ? -1 : x > y ? 1 : 0
+      }
+      case 'string':
+        return a < b ? -1 : a > b ? 1 : 0
+      case 'array':
+        for (let i = 0; i < a.length && i < b.length; i++) {
+          const c = compare(a[i], b[i])
+          if (c !== 0) return c
+        }
+        return a.length < b.length ? -1 : a.length > b.length ? 1 : 0
+      case 'object': {
+        const ka = Object.keys(a).sort(), kb = Object.keys(b).sort()
+        const c = compare(ka, kb)
+        if (c !== 0) return c
+        for (const k of ka) {
+          const c = compare(a[k], b[k])
+          if (c !== 0) return c
+        }
+        return 0
+      }
+    }
+    return 0
+  }
+
+  function sorted(array, keyFn = v => v) {
+    return array
+      .map((v, i) => [keyFn(v), i, v])
+      .sort((a, b) => compare(a[0], b[0]) || a[1] - b[1])
+      .map(e => e[2])
+  }
+
+  // cartesian calls fn with every combination of outputs, the last one changing fastest.
+  function cartesian(outputs, fn) {
+    const result = []
+    const values = []
+    const loop = i => {
+      if (i === outputs.length) {
+        result.push(...fn(...values))
+        return
+      }
+      for (const v of outputs[i]) {
+        values[i] = v
+        loop(i + 1)
+      }
+    }
+    loop(0)
+    return result
+  }
+
+  function index(v, key) {
+    if (v === null || v === undefined) return null
+    if (typeof key === 'string') {
+      if (type(v) !== 'object') fail(`Cannot index ${type(v)} with "${key}"`)
+      return has(v, key) ? v[key] : null
+    }
+    if (isNumber(key)) {
+      if (!Array.isArray(v)) fail(`Cannot index ${type(v)} with number`)
+      let i = Math.floor(Number(key))
+      if (i < 0) i += v.length
+      return i >= 0 && i < v.length ? v[i] : null
+    }
+    if (key !== null && typeof key === 'object' && !Array.isArray(key) && ('start' in key || 'end' in key)) {
+      return slice(v, key.start, key.end)
+    }
+    if (Array.isArray(key) && Array.isArray(v)) return indices(v, key)
+    fail(`Cannot index ${type(v)} with ${type(key)}`)
+  }
+
+  function slice(v, from, to) {
+    if (v === null || v === undefined) return null
+    if (typeof v !== 'string' && !Array.isArray(v)) fail(`Cannot index ${type(v)} with object`)
+    if (from !== null && from !== undefined && !isNumber(from) || to !== null && to !== undefined && !isNumber(to)) {
+      fail('Start and end indices of an array slice must be numbers')
+    }
+    const chars = typeof v === 'string' ? [...v] : v
+    const clamp = (i, d) => {
+      if (i === null || i === undefined) return d
+      i = Math.floor(Number(i))
+      if (i < 0) i += chars.length
+      return Math.min(Math.max(i, 0), chars.length)
+    }
+    const result = chars.slice(clamp(from, 0), clamp(to, chars.length))
+    return typeof v === 'string' ? result.join('') : result
+  }
+
+  function iterate(v) {
+    if (Array.isArray(v)) return [...v]
+    if (type(v) === 'object') return Object.keys(v).map(k => v[k])
+    fail(`Cannot iterate over ${v === null || v === undefined ? 'null' : describe(v)}`)
+  }
+
+  function recurse(v, f = iterateOrEmpty) {
+    const result = []
+    const walk = v => {
+      result.push(v)
+      for (const child of f(v)) walk(child)
+    }
+    walk(v)
+    return result
+  }
+
+  function iterateOrEmpty(v) {
+    return Array.isArray(v) || type(v) === 'object' ? iterate(v) : []
+  }
+
+  function length(v) {
+    switch (type(v)) {
+      case 'null':
+        return 0
+      case 'boolean':
+        fail(`${describe(v)} has no length`)
+      case 'number':
+        return Math.abs(Number(v))
+      case 'string':
+        return [...v].length
+      case 'array':
+        return v.length
+    }
+    return Object.keys(v).length
+  }
+
+  function add(a, b) {
+    if (a === null || a === undefined) return b
+    if (b === null || b === undefined) return a
+    const ta = type(a), tb = type(b)
+    if (ta === 'number' && tb === 'number') return Number(a) + Number(b)
+    if (ta === 'string' && tb === 'string') return a + b
+    if (ta === 'array' && tb === 'array') return [...a, ...b]
+    if (ta === 'object' && tb === 'object') return {...a, ...b}
+    fail(`${describe(a)} and ${describe(b)} cannot be added`)
+  }
+
+  function merge(a, b) {
+    const result = {...a}
+    for (const k of Object.keys(b)) {
+      result[k] = type(result[k]) === 'object' && type(b[k]) === 'object' ? merge(result[k], b[k]) : b[k]
+    }
+    return result
+  }
+
+  function arithmetic(op, a, b) {
+    const ta = type(a), tb = type(b)
+    switch (op) {
+      case '+':
+        return add(a, b)
+      case '-':
+        if (ta === 'number' && tb === 'number') return Number(a) - Number(b)
+        if (ta === 'array' && tb === 'array') return a.filter(x => !b.some(y => compare(x, y) === 0))
+        break
+      case '*':
+        if (ta === 'number' && tb === 'number') return Number(a) * Number(b)
+        if (ta === 'object' && tb === 'object') return merge(a, b)
+        if (ta === 'string' && tb === 'number') return Number(b) > 0 ? a.repeat(Math.ceil(Number(b))) : null
+        if (ta === 'number' && tb === 'string') return Number(a) > 0 ? b.repeat(Math.ceil(Number(a))) : null
+        break
+      case '/':
+        if (ta === 'number' && tb === 'number') {
+          if (Number(b) === 0) fail(`${describe(a)} and ${describe(b)} cannot be divided because the divisor is zero`)
+          return Number(a) / Number(b)
+        }
+        if (ta === 'string' && tb === 'string') return split(a, b)
+        break
+      case '%':
+        if (ta === 'number' && tb === 'number') {
+          const y = Math.trunc(Number(b))
+          if (y === 0) fail(`${describe(a)} and ${describe(b)} cannot be divided because the divisor is zero`)
+          const r = Math.trunc(Number(a)) % y
+          return r === 0 ? 0 : r
+        }
+        break
+      case '==':
+        return compare(a, b) === 0
+      case '!=':
+        return compare(a, b) !== 0
+      case '<':
+        return compare(a, b) < 0
+      case '<=':
+        return compare(a, b) <= 0
+      case '>':
+        return compare(a, b) > 0
+      case '>=':
+        return compare(a, b) >= 0
+    }
+    const verb = {'-': 'subtracted', '*': 'multiplied', '/': 'divided', '%': 'divided'}[op]
+    fail(`${describe(a)} and ${describe(b)} cannot be ${verb}`)
+  }
+
+  function split(s, sep) {
+    if (typeof s !== 'string' || typeof sep !== 'string') fail('split input and separator must be strings')
+    if (s === '') return []
+    return s.split(sep)
+  }
+
+  function indices(v, x) {
+    const result = []
+    if (v === null || v === undefined) return null
+    if (typeof v === 'string' && typeof x === 'string') {
+      if (x === '') return null
+      for (let i = v.indexOf(x); i >= 0; i = v.indexOf(x, i + 1)) result.push(i)
+      return result
+    }
+    if (Array.isArray(v)) {
+      const needle = Array.isArray(x) ? x : [x]
+      if (needle.length === 0) return null
+      for (let i = 0; i + needle.length <= v.length; i++) {
+        if (needle.every((y, j) => compare(v[i + j], y) === 0)) result.push(i)
+      }
+      return result
+    }
+    fail(`Cannot determine indices of ${describe(x)} in ${describe(v)}`)
+  }
+
+  function contains(a, b) {
+    const ta = type(a), tb = type(b)
+    if (ta !== tb) fail(`${describe(a)} and ${describe(b)} cannot have their containment checked`)
+    switch (ta) {
+      case 'object':
+        return Object.keys(b).every(k => has(a, k) && contains(a[k], b[k]))
+      case 'array':
+        return b.every(y => a.some(x => type(x) === type(y) && contains(x, y)))
+      case 'string':
+        return a.includes(b)
+    }
+    return compare(a, b) === 0
+  }
+
+  function hasKey(v, key) {
+    if (Array.isArray(v) && isNumber(key)) return Number(key) >= 0 && Number(key) < v.length
+    if (type(v) === 'object' && typeof key === 'string') return has(v, key)
+    fail(`Cannot check whether ${type(v)} has a ${type(key)} key`)
+  }
+
+  function toEntries(v) {
+    if (type(v) !== 'object') fail(`${describe(v)} has no keys`)
+    return Object.keys(v).map(key => ({key, value: v[key]}))
+  }
+
+  function fromEntries(v) {
+    const result = {}
+    for (const e of iterate(v)) {
+      let key = ['key', 'k', 'name', 'Name', 'Key', 'K'].map(k => index(e, k)).find(k => k !== null && k !== undefined && k !== false)
+      const value = ['value', 'v', 'Value', 'V'].map(k => has(e, k) ? e[k] : undefined).find(v => v !== undefined)
+      if (key === undefined) key = null
+      if (typeof key !== 'string') {
+        if (type(key) === 'null' || type(key) === 'object' || type(key) === 'array') fail(`Cannot use ${describe(key)} as object key`)
+        key = tostring(key)
+      }
+      result[key] = value === undefined ? null : value
+    }
+    return result
+  }
+
+  function keys(v) {
+    if (Array.isArray(v)) return v.map((_, i) => i)
+    if (type(v) === 'object') return Object.keys(v)
+    fail(`${describe(v)} has no keys`)
+  }
+
+  function paths(v) {
+    const result = []
+    const walk = (v, path) => {
+      if (Array.isArray(v)) v.forEach((x, i) => (result.push([...path, i]), walk(x, [...path, i])))
+      else if (type(v) === 'object') Object.keys(v).forEach(k => (result.push([...path, k]), walk(v[k], [...path, k])))
+    }
+    walk(v, [])
+    return result
+  }
+
+  function getpath(v, path) {
+    if (!Array.isArray(path)) fail('Path must be specified as an array')
+    for (const key of path) {
+      if (v === null || v === undefined) return null
+      v = index(v, key)
+    }
+    return v
+  }
+
+  function flatten(v, depth) {
+    if (!Array.isArray(v)) fail(`Cannot flatten ${describe(v)}`)
+    if (Number(depth) < 0) fail('flatten depth must not be negative')
+    return v.flat(Number(depth))
+  }
+
+  function join(v, sep) {
+    return iterate(v).map(x => {
+      if (x === null || x === undefined) return ''
+      if (typeof x === 'string') return x
+      if (typeof x === 'boolean' || isNumber(x)) return tojson(x)
+      fail(`Cannot join with ${describe(x)}`)
+    }).join(sep)
+  }
+
+  function regexp(re, flags) {
+    if (typeof re !== 'string') fail(`${describe(re)} cannot be matched, as it is not a string`)
+    let js = 'u'
+    for (const f of flags === null || flags === undefined ? '' : flags) {
+      switch (f) {
+        case 'g':
+        case 'i':
+        case 's':
+          js += f
+          break
+        case 'x':
+          re = re.replace(/\\#|\s+|#.*$/gm, m => m === '\\#' ? m : '')
+          break
+        case 'n':
+          break
+        default:
+          fail(`${flags} is not a valid modifier string`)
+      }
+    }
+    return new RegExp(re, js)
+  }
+
+  function string(v, name) {
+    if (typeof v !== 'string') fail(`${describe(v)} cannot be ${name}, as it is not a string`)
+    return v
+  }
+
+  // groupNames lists the names of capture groups in re, null for unnamed ones,
+  // as goja doesn't set groups on matches.
+  function groupNames(re) {
+    const names = []
+    let inClass = false
+    for (let i = 0; i < re.length; i++) {
+      const c = re[i]
+      if (c === '\\') i++
+      else if (inClass) inClass = c !== ']'
+      else if (c === '[') inClass = true
+      else if (c === '(') {
+        const named = /^\?<([A-Za-z_]\w*)>/.exec(re.slice(i + 1))
+        if (named) names.push(named[1])
+        else if (re[i + 1] !== '?') names.push(null)
+      }
+    }
+    return names
+  }
+
+  function capture(m, names) {
+    const result = {}
+    names.forEach((name, i) => {
+      if (name !== null) result[name] = m[i + 1] === undefined ? null : m[i + 1]
+    })
+    return result
+  }
+
+  function replace(s, re, flags, repl, global) {
+    const regex = regexp(re, (flags === null || flags === undefined ? '' : flags) + (global ? 'g' : ''))
+    const names = groupNames(re)
+    return string(s, 'matched').replace(regex, (...m) => {
+      const out = repl(capture(m, names))
+      if (out.length === 0) return ''
+      return string(out[0], 'added')
+    })
+  }
+
+  function number(v) {
+    if (isNumber(v)) return v
+    if (typeof v === 'string' && /^\s*-?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?\s*$/.test(v)) return Number(v)
+    fail(`Cannot parse ${describe(v)} as a number`)
+  }
+
+  const formats = {
+    text: tostring,
+    json: tojson,
+    html: v => tostring(v).replace(/[<>&'"]/g, c => ({'<': '&lt;', '>': '&gt;', '&': '&amp;', "'": '&#39;', '"': '&quot;'})[c]),
+    uri: v => tostring(v).replace(/[^A-Za-z0-9\-_.~]/gu, c => encodeURIComponent(c).replace(/[!'()*]/g, c => '%' + c.charCodeAt(0).toString(16).toUpperCase())),
+    csv: v => row(v, 'csv', x => `"${x.replace(/"/g, '""')}"`),
+    tsv: v => row(v, 'tsv', x => x.replace(/[\\\t\n\r]/g, c => ({'\\': '\\\\', '\t': '\\t', '\n': '\\n', '\r': '\\r'})[c])),
+    sh: v => (Array.isArray(v) ? v : [v]).map(x => {
+      if (typeof x === 'string') return `'${x.replace(/'/g, `'\\''`)}'`
+      if (type(x) === 'array' || type(x) === 'object') fail(`${describe(x)} can not be escaped for shell`)
+      return tojson(x)
+    }).join(' '),
+    base64: v => toBase64(tostring(v)),
+    base64d: v => fromBase64(tostring(v)),
+  }
+
+  function row(v, name, quote) {
+    if (!Array.isArray(v)) fail(`${describe(v)} cannot be ${name}-formatted, only an array can be`)
+    return v.map(x => {
+      if (type(x) === 'array' || type(x) === 'object') fail(`${describe(x)} is not valid in a csv row`)
+      if (x === null || x === undefined) return ''
+      return typeof x === 'string' ? quote(x) : tojson(x)
+    }).join(name === 'csv' ? ',' : '\t')
+  }
+
+  function range(from, to, by) {
+    if (!isNumber(from) || !isNumber(to) || !isNumber(by)) fail('Range bounds must be numeric')
+    from = Number(from), to = Number(to), by = Number(by)
+    const result = []
+    if (by > 0) for (let i = from; i < to; i += by) result.push(i)
+    else if (by < 0) for (let i = from; i > to; i += by) result.push(i)
+    return result
+  }
+
+  // simple wraps a function of the input and argument values into a filter, called for
+  // every combination of the argument outputs.
+  const simple = fn => (...args) => x => cartesian(args.map(f => f(x)), (...values) => [fn(x, ...values)])
+
+  const builtins = {
+    'empty/0': () => x => [],
+    'not/0': simple(x => !truthy(x)),
+    'error/0': () => x => fail(x),
+    'error/1': simple((x, message) => fail(message)),
+    'length/0': simple(length),
+    'utf8bytelength/0': simple(x => [...string(x, 'measured')].reduce((n, c) => {
+      const code = c.codePointAt(0)
+      return n + (code < 0x80 ? 1 : code < 0x800 ? 2 : code < 0x10000 ? 3 : 4)
+    }, 0)),
+    'type/0': simple(type),
+    'keys/0': simple(x => sorted(keys(x))),
+    'keys_unsorted/0': simple(keys),
+    'values/0': () => x => x === null ? [] : [x],
+    'has/1': simple(hasKey),
+    'in/1': simple((x, obj) => hasKey(obj, x)),
+    'contains/1': simple(contains),
+    'inside/1': simple((x, y) => contains(y, x)),
+    'select/1': f => x => f(x).filter(truthy).map(() => x),
+    'map/1': f => x => [iterate(x).flatMap(f)],
+    'map_values/1': f => x => {
+      if (Array.isArray(x)) return [x.flatMap(v => f(v).slice(0, 1))]
+      const result = {}
+      for (const {key, value} of toEntries(x)) {
+        const out = f(value)
+        if (out.length > 0) result[key] = out[0]
+      }
+      return [result]
+    },
+    'to_entries/0': simple(toEntries),
+    'from_entries/0': simple(fromEntries),
+    'with_entries/1': f => x => [fromEntries(toEntries(x).flatMap(f))],
+    'add/0': simple(x => x === null ? null : iterate(x).reduce(add, null)),
+    'any/0': simple(x => iterate(x).some(truthy)),
+    'all/0': simple(x => iterate(x).every(truthy)),
+    'any/1': f => x => [iterate(x).some(v => f(v).some(truthy))],
+    'all/1': f => x => [iterate(x).every(v => f(v).every(truthy))],
+    'any/2': (g, f) => x => [g(x).some(v => f(v).some(truthy))],
+    'all/2': (g, f) => x => [g(x).every(v => f(v).every(truthy))],
+    'range/1': n => x => cartesian([n(x)], n => range(0, n, 1)),
+    'range/2': (from, to) => x => cartesian([from(x), to(x)], (from, to) => range(from, to, 1)),
+    'range/3': (from, to, by) => x => cartesian([from(x), to(x), by(x)], range),
+    'floor/0': simple(x => Math.floor(number(x))),
+    'ceil/0': simple(x => Math.ceil(number(x))),
+    'round/0': simple(x => Math.round(number(x))),
+    'sqrt/0': simple(x => Math.sqrt(number(x))),
+    'fabs/0': simple(x => Math.abs(number(x))),
+    'abs/0': simple(x => isNumber(x) && Number(x) < 0 ? -Number(x) : x),
+    'pow/2': simple((x, a, b) => Math.pow(Number(a), Number(b))),
+    'log/0': simple(x => Math.log(number(x))),
+    'log2/0': simple(x => Math.log2(number(x))),
+    'log10/0': simple(x => Math.log10(number(x))),
+    'exp/0': simple(x => Math.exp(number(x))),
+    'exp10/0': simple(x => Math.pow(10, number(x))),
+    'infinite/0': simple(() => Infinity),
+    'nan/0': simple(() => NaN),
+    'isinfinite/0': simple(x => Math.abs(number(x)) === Infinity),
+    'isnan/0': simple(x => Number.isNaN(Number(number(x)))),
+    'isnormal/0': simple(x => Number.isFinite(Number(number(x))) && Number(x) !== 0),
+    'tostring/0': simple(tostring),
+    'tonumber/0': simple(number),
+    'tojson/0': simple(tojson),
+    'fromjson/0': simple(x => JSON.parse(string(x, 'parsed'))),
+    'ascii_downcase/0': simple(x => string(x, 'lowercased').replace(/[A-Z]/g, c => c.toLowerCase())),
+    'ascii_upcase/0': simple(x => string(x, 'uppercased').replace(/[a-z]/g, c => c.toUpperCase())),
+    'ltrimstr/1': simple((x, s) => typeof x === 'string' && typeof s === 'string' && x.startsWith(s) ? x.slice(s.length) : x),
+    'rtrimstr/1': simple((x, s) => typeof x === 'string' && typeof s === 'string' && x.endsWith(s) && s !== '' ? x.slice(0, -s.length) : x),
+    'startswith/1': simple((x, s) => typeof x === 'string' && typeof s === 'string' ? x.startsWith(s) : fail('startswith() requires string inputs')),
+    'endswith/1': simple((x, s) => typeof x === 'string' && typeof s === 'string' ? x.endsWith(s) : fail('endswith() requires string inputs')),
+    'trim/0': simple(x => string(x, 'trimmed').trim()),
+    'ltrim/0': simple(x => string(x, 'trimmed').trimStart()),
+    'rtrim/0': simple(x => string(x, 'trimmed').trimEnd()),
+    'split/1': simple(split),
+    'join/1': simple(join),
+    'explode/0': simple(x => [...string(x, 'exploded')].map(c => c.codePointAt(0))),
+    'implode/0': simple(x => String.fromCodePoint(...iterate(x).map(Number))),
+    'test/1': simple((x, re) => regexp(re).test(string(x, 'matched'))),
+    'test/2': simple((x, re, flags) => regexp(re, flags).test(string(x, 'matched'))),
+    'capture/1': simple((x, re) => {
+      const m = string(x, 'matched').match(regexp(re))
+      return m ? capture(m, groupNames(re)) : null
+    }),
+    'scan/1': (re) => x => cartesian([re(x)], re => [...string(x, 'matched').matchAll(regexp(re, 'g'))].map(m => m.length > 1 ? m.slice(1) : m[0])),
+    'splits/1': (re) => x => cartesian([re(x)], re => string(x, 'split').split(regexp(re, 'g'))),
+    'sub/2': (re, repl) => x => cartesian([re(x)], re => [replace(x, re, '', repl, false)]),
+    'sub/3': (re, repl, flags) => x => cartesian([re(x), flags(x)], (re, flags) => [replace(x, re, flags, repl, false)]),
+    'gsub/2': (re, repl) => x => cartesian([re(x)], re => [replace(x, re, '', repl, true)]),
+    'gsub/3': (re, repl, flags) => x => cartesian([re(x), flags(x)], (re, flags) => [replace(x, re, flags, repl, true)]),
+    'indices/1': simple(indices),
+    'index/1': simple((x, s) => {
+      const i = indices(x, s)
+      return i === null || i.length === 0 ? null : i[0]
+    }),
+    'rindex/1': simple((x, s) => {
+      const i = indices(x, s)
+      return i === null || i.length === 0 ? null : i[i.length - 1]
+    }),
+    'sort/0': simple(x => sorted(iterate(x))),
+    'sort_by/1': f => x => [sorted(iterate(x), v => f(v))],
+    'group_by/1': f => x => {
+      const groups = []
+      let last
+      for (const [key, v] of sorted(iterate(x).map(v => [f(v), v]), e => e[0])) {
+        if (groups.length > 0 && compare(key, last) === 0) groups[groups.length - 1].push(v)
+        else groups.push([v])
+        last = key
+      }
+      return [groups]
+    },
+    'unique/0': simple(x => sorted(iterate(x)).filter((v, i, a) => i === 0 || compare(v, a[i - 1]) !== 0)),
+    'unique_by/1': f => x => {
+      const result = []
+      let last
+      for (const [key, v] of sorted(iterate(x).map(v => [f(v), v]), e => e[0])) {
+        if (result.length === 0 || compare(key, last) !== 0) result.push(v)
+        last = key
+      }
+      return [result]
+    },
+    'min/0': simple(x => iterate(x).reduce((a, b) => a === undefined || compare(b, a) < 0 ? b : a, undefined) ?? null),
+    'max/0': simple(x => iterate(x).reduce((a, b) => a === undefined || compare(b, a) >= 0 ? b : a, undefined) ?? null),
+    'min_by/1': f => x => [iterate(x).map(v => [f(v), v]).reduce((a, b) => a === undefined || compare(b[0], a[0]) < 0 ? b : a, undefined)?.[1] ?? null],
+    'max_by/1': f => x => [iterate(x).map(v => [f(v), v]).reduce((a, b) => a === undefined || compare(b[0], a[0]) >= 0 ? b : a, undefined)?.[1] ?? null],
+    'reverse/0': simple(x => typeof x === 'string' ? [...x].reverse().join('') : x === null ? [] : [...iterate(x)].reverse()),
+    'flatten/0': simple(x => flatten(x, 1e9)),
+    'flatten/1': simple(flatten),
+    'transpose/0': simple(x => {
+      const rows = iterate(x)
+      const width = Math.max(0, ...rows.map(r => length(r)))
+      return Array.from({length: width}, (_, i) => rows.map(r => index(r, i)))
+    }),
+    'first/0': simple(x => index(x, 0)),
+    'last/0': simple(x => index(x, -1)),
+    'nth/1': simple((x, n) => index(x, n)),
+    'first/1': f => x => f(x).slice(0, 1),
+    'last/1': f => x => f(x).slice(-1),
+    'nth/2': (n, f) => x => cartesian([n(x)], n => {
+      if (Number(n) < 0) fail('Out of bounds negative array index')
+      return f(x).slice(Number(n), Number(n) + 1)
+    }),
+    'limit/2': (n, f) => x => cartesian([n(x)], n => Number(n) > 0 ? f(x).slice(0, Number(n)) : []),
+    'isempty/1': f => x => [f(x).length === 0],
+    'until/2': (cond, update) => x => {
+      const loop = v => cond(v).flatMap(c => truthy(c) ? [v] : update(v).flatMap(loop))
+      return loop(x)
+    },
+    'while/2': (cond, update) => x => {
+      const loop = v => cond(v).flatMap(c => truthy(c) ? [v, ...update(v).flatMap(loop)] : [])
+      return loop(x)
+    },
+    'recurse/0': () => x => recurse(x),
+    'recurse/1': f => x => recurse(x, f),
+    'recurse/2': (f, cond) => x => recurse(x, v => f(v).filter(v => cond(v).some(truthy))),
+    'walk/1': f => x => {
+      const walk = v => {
+        if (Array.isArray(v)) v = v.flatMap(walk)
+        else if (type(v) === 'object') v = Object.fromEntries(Object.keys(v).flatMap(k => walk(v[k]).slice(-1).map(w => [k, w])))
+        return f(v)
+      }
+      return walk(x)
+    },
+    'paths/0': () => x => paths(x),
+    'paths/1': f => x => paths(x).filter(p => f(getpath(x, p)).some(truthy)),
+    'leaf_paths/0': () => x => paths(x).filter(p => !['array', 'object'].includes(type(getpath(x, p)))),
+    'getpath/1': simple(getpath),
+    'env/0': simple(() => env),
+    'now/0': simple(() => Date.now() / 1000),
+    'arrays/0': () => x => type(x) === 'array' ? [x] : [],
+    'objects/0': () => x => type(x) === 'object' ? [x] : [],
+    'iterables/0': () => x => ['array', 'object'].includes(type(x)) ? [x] : [],
+    'booleans/0': () => x => type(x) === 'boolean' ? [x] : [],
+    'numbers/0': () => x => type(x) === 'number' ? [x] : [],
+    'strings/0': () => x => type(x) === 'string' ? [x] : [],
+    'nulls/0': () => x => type(x) === 'null' ? [x] : [],
+    'scalars/0': () => x => !['array', 'object'].includes(type(x)) ? [x] : [],
+  }
+
+  return {
+    JqError,
+    builtins,
+    id: x => [x],
+    constant: v => x => [v],
+    field: (t, key) => x => t(x).map(v => index(v, key)),
+    index: (t, k) => x => cartesian([t(x), k(x)], (v, k) => [index(v, k)]),
+    slice: (t, from, to) => x => cartesian([t(x), from(x), to(x)], (v, from, to) => [slice(v, from, to)]),
+    iterate: t => x => t(x).flatMap(iterate),
+    recurse: x => recurse(x),
+    pipe: (a, b) => x => a(x).flatMap(b),
+    comma: (a, b) => x => [...a(x), ...b(x)],
+    binary: (op, a, b) => x => cartesian([b(x), a(x)], (b, a) => [arithmetic(op, a, b)]),
+    negate: t => x => t(x).map(v => {
+      if (!isNumber(v)) fail(`${describe(v)} cannot be negated`)
+      return -Number(v)
+    }),
+    and: (a, b) => x => a(x).flatMap(v => truthy(v) ? b(x).map(truthy) : [false]),
+    or: (a, b) => x => a(x).flatMap(v => truthy(v) ? [true] : b(x).map(truthy)),
+    alternative: (a, b) => x => {
+      let outputs = []
+      try {
+        outputs = a(x).filter(truthy)
+      } catch (e) {
+        if (!(e instanceof Error)) throw e
+      }
+      return outputs.length > 0 ? outputs : b(x)
+    },
+    if: (cond, then, otherwise) => x => cond(x).flatMap(c => truthy(c) ? then(x) : otherwise(x)),
+    try: (body, handler) => x => {
+      try {
+        return body(x)
+      } catch (e) {
+        if (!(e instanceof Error)) throw e
+        if (!handler) return []
+        return handler(e instanceof JqError ? e.value : e.message)
+      }
+    },
+    // update and extract take a value of source and return a filter with the variables bound.
+    reduce: (source, init, update) => x => init(x).map(acc => {
+      for (const v of source(x)) {
+        const out = update(v)(acc)
+        acc = out.length > 0 ? out[out.length - 1] : null
+      }
+      return acc
+    }),
+    foreach: (source, init, update, extract) => x => init(x).flatMap(acc => {
+      const result = []
+      for (const v of source(x)) {
+        for (const state of update(v)(acc)) {
+          acc = state
+          result.push(...extract(v)(state))
+        }
+      }
+      return result
+    }),
+    collect: t => x => [t(x)],
+    object: entries => x => cartesian(entries.flatMap(([k, v]) => [k(x), v(x)]), (...kv) => {
+      const obj = {}
+      for (let i = 0; i < kv.length; i += 2) {
+        if (typeof kv[i] !== 'string') fail(`Object keys must be strings`)
+        obj[kv[i]] = kv[i + 1]
+      }
+      return [obj]
+    }),
+    string: (format, parts) => x => cartesian(parts.map(p => typeof p === 'string' ? [p] : p(x)), (...values) =>
+      [values.map((v, i) => typeof parts[i] === 'string' ? v : formats[format](v)).join('')]),
+    format: name => x => [formats[name](x)],
+    destructure: index,
+  }
+})()
@@ -2,6 +2,7 @@ package jsonpath
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -21,14 +22,12 @@ func ParseSchemaRef(ref string) ([]any, bool) {
 		return nil, false
 	}
 
-	// Split the pointer without the leading '#/'
-	parts := strings.Split(ref[2:], "/")
+	// Split the pointer without the leading '#'
+	parts, _ := ParsePointer(ref[1:])
 	out := make([]any, len(parts))
 	for i, part := range parts {
-		// JSON Pointer unescaping
-		s := strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
 		// Percent-unescape
-		unescaped, err := url.PathUnescape(s)
+		unescaped, err := url.PathUnescape(part)
 		if err != nil {
 			return nil, false
 		}
@@ -36,3 +35,34 @@ func ParseSchemaRef(ref string) ([]any, bool) {
 	}
 	return out, true
 }
+
+// ParsePointer splits a JSON Pointer (RFC 6901) into unescaped reference tokens.
+func ParsePointer(pointer string) ([]string, bool) {
+	if pointer == "" {
+		return []string{}, true
+	}
+	if pointer[0] != '/' {
+		return nil, false
+	}
+	parts := strings.Split(pointer[1:], "/")
+	for i, part := range parts {
+		// JSON Pointer unescaping
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+	}
+	return parts, true
+}
+
+// JoinPointer returns the JSON Pointer of a path of keys and indexes.
+func JoinPointer(path []any) string {
+	var sb strings.Builder
+	for _, v := range path {
+		sb.WriteByte('/')
+		switch v := v.(type) {
+		case string:
+			sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(v, "~", "~0"), "/", "~1"))
+		case int:
+			sb.WriteString(strconv.Itoa(v))
+		}
+	}
+	return sb.String()
+}
@@ -71,3 +71,51 @@ func TestParseSchemaRef(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   []string
+		wantOk bool
+	}{
+		{"", []string{}, true},
+		{"/", []string{""}, true},
+		{"/a/0", []string{"a", "0"}, true},
+		{"/a~1b/m~0n", []string{"a/b", "m~n"}, true},
+		{"/~01", []string{"~1"}, true},
+		{"/c%d", []string{"c%d"}, true},
+		{"a", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := jsonpath.ParsePointer(tt.input)
+			if ok != tt.wantOk {
+				t.Errorf("ParsePointer(%q) ok = %v, want %v", tt.input, ok, tt.wantOk)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePointer(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinPointer(t *testing.T) {
+	tests := []struct {
+		input []any
+		want  string
+	}{
+		{[]any{}, ""},
+		{[]any{"a", 0}, "/a/0"},
+		{[]any{"a/b", "m~n", ""}, "/a~1b/m~0n/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := jsonpath.JoinPointer(tt.input); got != tt.want {
+				t.Errorf("JoinPointer(%v) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package jsonx
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type CsvOptions struct {
+	Comma      rune // Field delimiter, ',' if zero.
+	NoHeader   bool // Treat the first line as data, rows become arrays.
+	InferTypes bool // Convert numbers and booleans to non-string nodes.
+}
+
+// CsvParser streams CSV (or TSV) records as nodes, one row per Parse call.
+// Rows become objects keyed by the header line, or arrays if NoHeader is set.
+// Fields past the header are keyed by their index. A repeated key gets a suffix,
+// e.g. "a", "a_2", "a_3". Line numbers of nodes are lines of the CSV source.
+type CsvParser struct {
+	rd     *csv.Reader
+	opts   CsvOptions
+	header []string // Quoted keys, extended by rows longer than the header line.
+	keys   map[string]bool
+	line   int
+	err    error
+}
+
+func NewCsvParser(in io.Reader, opts CsvOptions) *CsvParser {
+	rd := csv.NewReader(in)
+	if opts.Comma != 0 {
+		rd.Comma = opts.Comma
+	}
+	rd.FieldsPerRecord = -1
+	rd.LazyQuotes = true
+	rd.ReuseRecord = true
+	return &CsvParser{
+		rd:   rd,
+		opts: opts,
+		keys: map[string]bool{},
+	}
+}
+
+func (p *CsvParser) Parse() (*Node, error) {
+	if p.header == nil && !p.opts.NoHeader {
+		record, err := p.read()
+		if err != nil {
+			return nil, err
+		}
+		p.header = []string{}
+		for i, name := range record {
+			if i == 0 {
+				name = strings.TrimPrefix(name, "\ufeff")
+			}
+			p.addKey(name)
+		}
+	}
+
+	record, err := p.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.opts.NoHeader {
+		return p.row(Array, record), nil
+	}
+	return p.row(Object, record), nil
+}
+
+func (p *CsvParser) read() ([]string, error) {
+	record, err := p.rd.Read()
+	if err != nil {
+		p.err = err
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			p.line = parseErr.Line
+		}
+		return nil, err
+	}
+	p.line, _ = p.rd.FieldPos(0)
+	return record, nil
+}
+
+// addKey appends name to the header, with a suffix if it is already taken.
+func (p *CsvParser) addKey(name string) {
+	key := name
+	for n := 2; p.keys[key]; n++ {
+		key = name + "_" + strconv.Itoa(n)
+	}
+	p.keys[key] = true
+	p.header = append(p.header, strconv.Quote(key))
+}
+
+func (p *CsvParser) Recover() *Node {
+	message := "invalid csv record"
+	if p.err != nil {
+		message = p.err.Error()
+	}
+	return &Node{
+		Kind:       Err,
+		Value:      message,
+		Index:      -1,
+		LineNumber: p.line,
+	}
+}
+
+func (p *CsvParser) row(kind Kind, record []string) *Node {
+	open, pair, closing := curlyBracketOpen, curlyBracketPair, curlyBracketClose
+	if kind == Array {
+		open, pair, closing = squareBracketOpen, squareBracketPair, squareBracketClose
+	}
+
+	row := &Node{
+		Kind:       kind,
+		Value:      open,
+		LineNumber: p.line,
+	}
+	if len(record) == 0 {
+		row.Value = pair
+		return row
+	}
+
+	line := p.line
+	for i, field := range record {
+		value := p.value(field)
+		value.Parent = row
+		value.Depth = 1
+		line, _ = p.rd.FieldPos(i)
+		value.LineNumber = line
+		value.Comma = i < len(record)-1
+		if kind == Array {
+			value.Index = i
+		} else {
+			for len(p.header) <= i {
+				p.addKey(strconv.Itoa(len(p.header)))
+			}
+			value.Key = p.header[i]
+		}
+		row.Append(value)
+		row.Size++
+	}
+
+	row.Append(&Node{
+		Kind:       kind,
+		Value:      closing,
+		Parent:     row,
+		Index:      -1,
+		LineNumber: line,
+	})
+	return row
+}
+
+var csvNumber = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+func (p *CsvParser) value(field string) *Node {
+	if p.opts.InferTypes {
+		switch {
+		case field == "true" || field == "false":
+			return &Node{Kind: Bool, Value: field}
+		case csvNumber.MatchString(field):
+			return &Node{Kind: Number, Value: field}
+		}
+	}
+	return &Node{Kind: String, Value: strconv.Quote(field)}
+}
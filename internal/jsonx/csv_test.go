@@ -0,0 +1,138 @@
+package jsonx_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func parseAllCsv(t *testing.T, input string, opts jsonx.CsvOptions) []string {
+	p := jsonx.NewCsvParser(strings.NewReader(input), opts)
+	var rows []string
+	for {
+		node, err := p.Parse()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rows = append(rows, node.String())
+	}
+	return rows
+}
+
+func TestCsvParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  jsonx.CsvOptions
+		want  []string
+	}{
+		{
+			name:  "header",
+			input: "name,age\nbob,42\nalice,7\n",
+			want:  []string{`{"name":"bob","age":"42"}`, `{"name":"alice","age":"7"}`},
+		},
+		{
+			name:  "quoted fields",
+			input: "a,b\n\"x, y\",\"say \"\"hi\"\"\"\n",
+			want:  []string{`{"a":"x, y","b":"say \"hi\""}`},
+		},
+		{
+			name:  "infer types",
+			input: "n,f,b,s,z\n42,-1.5e3,true,007,\n",
+			opts:  jsonx.CsvOptions{InferTypes: true},
+			want:  []string{`{"n":42,"f":-1.5e3,"b":true,"s":"007","z":""}`},
+		},
+		{
+			name:  "no header",
+			input: "1,2\n3,4\n",
+			opts:  jsonx.CsvOptions{NoHeader: true, InferTypes: true},
+			want:  []string{`[1,2]`, `[3,4]`},
+		},
+		{
+			name:  "tsv",
+			input: "a\tb\n1\t2\n",
+			opts:  jsonx.CsvOptions{Comma: '\t'},
+			want:  []string{`{"a":"1","b":"2"}`},
+		},
+		{
+			name:  "ragged rows",
+			input: "a,b\n1\n1,2,3\n",
+			want:  []string{`{"a":"1"}`, `{"a":"1","b":"2","2":"3"}`},
+		},
+		{
+			name:  "bom",
+			input: "\ufeffa\n1\n",
+			want:  []string{`{"a":"1"}`},
+		},
+		{
+			name:  "duplicate header",
+			input: "a,a,b,a_2,a\n1,2,3,4,5\n",
+			want:  []string{`{"a":"1","a_2":"2","b":"3","a_2_2":"4","a_3":"5"}`},
+		},
+		{
+			name:  "extra field named like the header",
+			input: "2,b\n1,2,3\n",
+			want:  []string{`{"2":"1","b":"2","2_2":"3"}`},
+		},
+		{
+			name:  "only header",
+			input: "a,b\n",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAllCsv(t, tt.input, tt.opts))
+		})
+	}
+}
+
+func TestCsvParser_nodes(t *testing.T) {
+	p := jsonx.NewCsvParser(strings.NewReader("a,b\n1,2\n3,4\n"), jsonx.CsvOptions{})
+
+	first, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, jsonx.Object, first.Kind)
+	assert.Equal(t, 2, first.Size)
+	assert.Equal(t, 2, first.LineNumber)
+	assert.Equal(t, 2, first.End.LineNumber)
+
+	b := first.FindByPath([]any{"b"})
+	require.NotNil(t, b)
+	assert.Equal(t, `"2"`, b.Value)
+	assert.Equal(t, uint8(1), b.Depth)
+	assert.Equal(t, first, b.Parent)
+
+	second, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, 3, second.LineNumber)
+
+	_, err = p.Parse()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCsvParser_LineNumbers(t *testing.T) {
+	p := jsonx.NewCsvParser(strings.NewReader("a,b\n\"x\ny\",1\n\n2,\"z\n\""), jsonx.CsvOptions{})
+
+	first, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, 2, first.LineNumber)
+	assert.Equal(t, 2, first.FindByPath([]any{"a"}).LineNumber)
+	assert.Equal(t, 3, first.FindByPath([]any{"b"}).LineNumber)
+	assert.Equal(t, 3, first.End.LineNumber)
+
+	second, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, 5, second.LineNumber)
+	assert.Equal(t, 5, second.End.LineNumber)
+
+	_, err = p.Parse()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,133 @@
+package jsonx
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+var (
+	decimalText      = goja.NewSymbol("decimal")
+	decimalPrototype = goja.NewSymbol("decimal.prototype")
+	numberTexts      = goja.NewSymbol("numberTexts")
+)
+
+// PrecisionLoss is called, if set, when a number is converted to a float64
+// with a different value, e.g. 19.990000000000001 as 19.990000000000002,
+// either from the input or from a decimal used in math.
+var PrecisionLoss func(text string, f float64)
+
+// NewDecimal returns a JS value for an exact decimal from the decimal global,
+// e.g. 19.990000000000001 or 1e999. It acts as a number in arithmetic
+// and comparisons, while Stringify writes it back verbatim.
+func NewDecimal(vm *goja.Runtime, text string) goja.Value {
+	obj := vm.NewObject()
+	if err := obj.SetPrototype(decimalPrototypeOf(vm)); err != nil {
+		panic(err)
+	}
+	if err := obj.DefineDataPropertySymbol(decimalText, vm.ToValue(text), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+// DecimalText returns the number text of a value created by NewDecimal.
+func DecimalText(v goja.Value) (string, bool) {
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return "", false
+	}
+	text := obj.GetSymbol(decimalText)
+	if text == nil {
+		return "", false
+	}
+	return text.String(), true
+}
+
+// setNumberTexts keeps texts, the source of numbers float64 formats differently
+// (e.g. 1.0 or 1e3) by key, in a hidden property of obj.
+func setNumberTexts(vm *goja.Runtime, obj *goja.Object, texts map[string]string) {
+	if len(texts) == 0 {
+		return
+	}
+	if err := obj.DefineDataPropertySymbol(numberTexts, vm.ToValue(texts), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+}
+
+// NumberTexts returns a function giving the source text of the number at key
+// of an object or array converted from a node, e.g. 1.0 or 1e3, as long as
+// the value at key is still that number.
+func NumberTexts(obj *goja.Object) func(key string, value goja.Value) (string, bool) {
+	var texts map[string]string
+	if v := obj.GetSymbol(numberTexts); v != nil {
+		texts, _ = v.Export().(map[string]string)
+	}
+	return func(key string, value goja.Value) (string, bool) {
+		text := texts[key]
+		if text == "" || value == nil || value.ExportType() == nil {
+			return "", false
+		}
+		switch value.ExportType().Kind() {
+		case reflect.Int64, reflect.Float64:
+			f, err := strconv.ParseFloat(text, 64)
+			return text, (err == nil || errors.Is(err, strconv.ErrRange)) && f == value.ToFloat()
+		}
+		return "", false
+	}
+}
+
+// decimalPrototypeOf returns the prototype of decimals, created once per VM.
+func decimalPrototypeOf(vm *goja.Runtime) *goja.Object {
+	global := vm.GlobalObject()
+	if proto, ok := global.GetSymbol(decimalPrototype).(*goja.Object); ok {
+		return proto
+	}
+
+	this := func(call goja.FunctionCall) string {
+		text, ok := DecimalText(call.This)
+		if !ok {
+			panic(vm.NewTypeError("not a decimal"))
+		}
+		return text
+	}
+	valueOf := func(call goja.FunctionCall) goja.Value {
+		text := this(call)
+		f, _ := strconv.ParseFloat(text, 64)
+		if PrecisionLoss != nil && lossy(text, f) {
+			PrecisionLoss(text, f)
+		}
+		return vm.ToValue(f)
+	}
+	methods := map[string]func(goja.FunctionCall) goja.Value{
+		"valueOf": valueOf,
+		"toJSON":  valueOf,
+		"toString": func(call goja.FunctionCall) goja.Value {
+			return vm.ToValue(this(call))
+		},
+	}
+
+	proto := vm.NewObject()
+	for name, fn := range methods {
+		if err := proto.DefineDataProperty(name, vm.ToValue(fn), goja.FLAG_TRUE, goja.FLAG_FALSE, goja.FLAG_TRUE); err != nil {
+			panic(err)
+		}
+	}
+	if err := global.DefineDataPropertySymbol(decimalPrototype, proto, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE); err != nil {
+		panic(err)
+	}
+	return proto
+}
+
+// lossy reports whether f has a different value than text, not just a different form.
+func lossy(text string, f float64) bool {
+	exact, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return false
+	}
+	shortest, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return !ok || exact.Cmp(shortest) != 0
+}
@@ -0,0 +1,77 @@
+package jsonx_test
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestToValue_NumberText(t *testing.T) {
+	tests := []struct {
+		input string
+		text  bool
+	}{
+		{"0.1", false},
+		{"19.99", false},
+		{"-2.5", false},
+		{"1.50", true},
+		{"1e3", true},
+		{"1.0", true},
+		{"19.990000000000001", true},
+		{"12345678901234567890.5", true},
+		{"1e999", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := jsonx.Parse([]byte("[" + tt.input + "]"))
+			require.NoError(t, err)
+			vm := goja.New()
+			for _, toValue := range []func(*jsonx.Node, *goja.Runtime) goja.Value{(*jsonx.Node).ToValue, (*jsonx.Node).ToLazyValue} {
+				arr := toValue(node, vm).ToObject(vm)
+				value := arr.Get("0")
+				_, isObject := value.(*goja.Object)
+				assert.False(t, isObject, "a primitive number")
+				text, ok := jsonx.NumberTexts(arr)("0", value)
+				assert.Equal(t, tt.text, ok)
+				if ok {
+					assert.Equal(t, tt.input, text)
+				}
+			}
+		})
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	vm := goja.New()
+	require.NoError(t, vm.Set("x", jsonx.NewDecimal(vm, "1.50")))
+
+	v, err := vm.RunString(`[x * 2, x + 1, x > 1, x == 1.5, typeof x, String(x), Object.keys(x).length]`)
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(3), 2.5, true, true, "object", "1.50", int64(0)}, v.Export())
+}
+
+func TestDecimal_PrecisionLoss(t *testing.T) {
+	var lost []string
+	jsonx.PrecisionLoss = func(text string, f float64) { lost = append(lost, text) }
+	defer func() { jsonx.PrecisionLoss = nil }()
+
+	vm := goja.New()
+	require.NoError(t, vm.Set("a", jsonx.NewDecimal(vm, "1.50")))
+	require.NoError(t, vm.Set("b", jsonx.NewDecimal(vm, "19.990000000000001")))
+	_, err := vm.RunString(`a * 1 + b * 1`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"19.990000000000001"}, lost)
+
+	lost = nil
+	node, err := jsonx.Parse([]byte(`{"a": 1.50, "b": 19.990000000000001, "c": 1e999}`))
+	require.NoError(t, err)
+	obj := node.ToLazyValue(vm).ToObject(vm)
+	assert.Empty(t, lost)
+	obj.Get("a")
+	obj.Get("b")
+	assert.Equal(t, []string{"19.990000000000001"}, lost)
+}
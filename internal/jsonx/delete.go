@@ -10,6 +10,10 @@ package jsonx
 // - Decrement parent.Size and reindex subsequent array siblings.
 // - Choose selection: prefer next; if next is nil or parent.End, prefer prev; else parent.
 func DeleteNode(at *Node) (*Node, bool) {
+	return apply(deleteChange(at))
+}
+
+func deleteChange(at *Node) (*change, bool) {
 	if at == nil {
 		return nil, false
 	}
@@ -17,58 +21,49 @@ func DeleteNode(at *Node) (*Node, bool) {
 	if at.Index == -1 {
 		return nil, false
 	}
+	// If current points to a wrap placeholder, move to its parent value
+	at = valueOf(at)
 	parent := at.Parent
 	if parent == nil { // avoid deleting root
 		return nil, false
 	}
-	// If current points to a wrap placeholder, move to its parent value
-	if at.Chunk != "" && at.Value == "" && at.Parent != nil {
-		at = at.Parent
-		parent = at.Parent
-		if parent == nil {
-			return nil, false
-		}
-	}
 
-	// Determine the last node of this item (to skip its subtree or chunks)
-	endOf := at
-	if at.End != nil {
-		endOf = at.End
-	} else if at.ChunkEnd != nil {
-		endOf = at.ChunkEnd
-	}
+	after := prevSibling(at)
+	return &change{
+		apply: func() *Node {
+			return unlink(at)
+		},
+		revert: func() *Node {
+			link(parent, after, at)
+			return at
+		},
+	}, true
+}
+
+// unlink removes at (with its children or wrap chunks) from its parent
+// and returns a node to select instead. The subtree of at is left intact,
+// so it can be linked back.
+func unlink(at *Node) *Node {
+	parent := at.Parent
+	parent.Expand()
+
+	sibling := prevSibling(at)
 	prev := at.Prev
-	next := endOf.Next
+	next := afterOf(at)
 
 	// If deleting the last child before parent's closing bracket, clear trailing comma on previous sibling
-	isLast := next == parent.End
-	if isLast && prev != nil && prev != parent {
-		prev.Comma = false
+	if next == parent.End && sibling != nil {
+		setComma(sibling, false)
 	}
 
-	// Relink to remove [at..endOf] from the chain
-	if prev != nil {
-		prev.Next = next
-	}
-	if next != nil {
-		next.Prev = prev
-	}
+	connect(prev, next)
 
 	// Update parent size and array indices if needed
 	if parent.Size > 0 {
 		parent.Size--
 	}
 	if parent.Kind == Array {
-		for it := next; it != nil && it != parent.End; {
-			if it.Parent == parent && it.Index >= 0 {
-				it.Index = it.Index - 1
-			}
-			if it.HasChildren() {
-				it = it.End.Next
-			} else {
-				it = it.Next
-			}
-		}
+		reindex(parent, next, -1)
 	}
 
 	// Select a sensible node after deletion
@@ -80,5 +75,58 @@ func DeleteNode(at *Node) (*Node, bool) {
 			selectTo = parent
 		}
 	}
-	return selectTo, true
+	return selectTo
+}
+
+// link inserts n into parent after the sibling after, or as the first child if after is nil.
+// The depth and key of n must already be set, and parent must have brackets (see openEmpty).
+func link(parent, after, n *Node) {
+	parent.Expand()
+
+	var prev *Node
+	if after == nil {
+		prev = parent
+		setComma(n, parent.Size > 0)
+	} else {
+		prev = after
+		if !after.IsCollapsed() {
+			prev = lastOf(after)
+		}
+		setComma(n, hasComma(after))
+		setComma(after, true)
+	}
+	next := prev.Next
+
+	if parent.Kind == Array {
+		n.Index = 0
+		if after != nil {
+			n.Index = after.Index + 1
+		}
+		reindex(parent, next, +1)
+	}
+
+	n.Parent = parent
+	splice(prev, n, lastOf(n), next)
+	parent.Size++
+}
+
+// reindex shifts indices of array elements starting from the node from.
+func reindex(parent, from *Node, delta int) {
+	for it := from; it != nil && it != parent.End; it = afterOf(it) {
+		if it.Parent == parent && it.Index >= 0 {
+			it.Index += delta
+		}
+	}
+}
+
+// prevSibling returns the value node before n in its parent, or nil if n is the first child.
+func prevSibling(n *Node) *Node {
+	prev := n.Prev
+	if prev == nil || prev == n.Parent {
+		return nil
+	}
+	if prev.IsWrap() || isClosing(prev) {
+		return prev.Parent
+	}
+	return prev
 }
@@ -0,0 +1,286 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ParseValue parses s as exactly one strict JSON value.
+func ParseValue(s string) (*Node, error) {
+	return parseValue(s, true)
+}
+
+func parseValue(s string, strict bool) (*Node, error) {
+	p := NewJsonParser(strings.NewReader(s), strict)
+	node, err := p.Parse()
+	if err == io.EOF {
+		return nil, errors.New("empty value")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.Parse(); err != io.EOF {
+		return nil, errors.New("expected a single value")
+	}
+	return node, nil
+}
+
+// ReplaceValue puts value in place of at, keeping the key, index and trailing comma of at.
+// It returns the new node, or (nil, false) if at is a root or a closing bracket.
+func ReplaceValue(at, value *Node) (*Node, bool) {
+	return apply(replaceChange(at, value))
+}
+
+func replaceChange(at, value *Node) (*change, bool) {
+	at = valueOf(at)
+	if !editable(at) {
+		return nil, false
+	}
+	parent := at.Parent
+	adopt(value, parent.Depth+1)
+	value.Key = at.Key
+	value.LineNumber = at.LineNumber
+	return &change{
+		apply: func() *Node {
+			link(parent, at, value)
+			unlink(at)
+			return value
+		},
+		revert: func() *Node {
+			link(parent, value, at)
+			unlink(value)
+			return at
+		},
+	}, true
+}
+
+// RenameKey sets a new key on an object member.
+func RenameKey(at *Node, key string) bool {
+	_, ok := apply(renameChange(at, key))
+	return ok
+}
+
+func renameChange(at *Node, key string) (*change, bool) {
+	at = valueOf(at)
+	if !editable(at) || at.Key == "" {
+		return nil, false
+	}
+	oldKey, newKey := at.Key, quote(key)
+	return &change{
+		apply: func() *Node {
+			at.Key = newKey
+			return at
+		},
+		revert: func() *Node {
+			at.Key = oldKey
+			return at
+		},
+	}, true
+}
+
+// InsertAfter inserts value as the next sibling of at. The key is used
+// only if the parent of at is an object.
+func InsertAfter(at *Node, key string, value *Node) (*Node, bool) {
+	return apply(insertAfterChange(at, key, value))
+}
+
+func insertAfterChange(at *Node, key string, value *Node) (*change, bool) {
+	at = valueOf(at)
+	if !editable(at) {
+		return nil, false
+	}
+	return insertChange(at.Parent, at, quote(key), value), true
+}
+
+// InsertFirst inserts value as the first child of the object or array at.
+func InsertFirst(at *Node, key string, value *Node) (*Node, bool) {
+	return apply(insertFirstChange(at, key, value))
+}
+
+func insertFirstChange(at *Node, key string, value *Node) (*change, bool) {
+	at = valueOf(at)
+	if at == nil || (at.Kind != Object && at.Kind != Array) || isClosing(at) {
+		return nil, false
+	}
+	return insertChange(at, nil, quote(key), value), true
+}
+
+// Duplicate inserts a copy of at right after it.
+func Duplicate(at *Node) (*Node, bool) {
+	return apply(duplicateChange(at))
+}
+
+func duplicateChange(at *Node) (*change, bool) {
+	at = valueOf(at)
+	if !editable(at) {
+		return nil, false
+	}
+	value, err := parseValue(Compact(at), false)
+	if err != nil {
+		return nil, false
+	}
+	return insertChange(at.Parent, at, at.Key, value), true
+}
+
+// insertChange links value into parent after the sibling after, or as the first child if after is nil.
+func insertChange(parent, after *Node, key string, value *Node) *change {
+	adopt(value, parent.Depth+1)
+	if parent.Kind == Object {
+		value.Key = key
+	}
+	var opened bool
+	var closing *Node
+	return &change{
+		apply: func() *Node {
+			opened = !parent.HasChildren()
+			if opened {
+				closing = openEmpty(parent, closing)
+			}
+			link(parent, after, value)
+			return value
+		},
+		revert: func() *Node {
+			unlink(value)
+			if opened {
+				closeEmpty(parent)
+			}
+			if after != nil {
+				return after
+			}
+			return parent
+		},
+	}
+}
+
+// openEmpty turns {} or [] into a pair of bracket nodes, so children can be added.
+// The closing node is created unless one from an earlier closeEmpty is given.
+func openEmpty(n, closing *Node) *Node {
+	if closing == nil {
+		closing = &Node{
+			Kind:   n.Kind,
+			Depth:  n.Depth,
+			Parent: n,
+			Index:  -1,
+		}
+	}
+	closing.Comma = n.Comma
+	if n.Kind == Object {
+		n.Value, closing.Value = curlyBracketOpen, curlyBracketClose
+	} else {
+		n.Value, closing.Value = squareBracketOpen, squareBracketClose
+	}
+	n.Comma = false
+	splice(n, closing, closing, n.Next)
+	n.End = closing
+	return closing
+}
+
+// closeEmpty turns a pair of bracket nodes without children back into {} or [].
+func closeEmpty(n *Node) {
+	closing := n.End
+	n.End = nil
+	n.Comma = closing.Comma
+	if n.Kind == Object {
+		n.Value = curlyBracketPair
+	} else {
+		n.Value = squareBracketPair
+	}
+	connect(n, closing.Next)
+}
+
+// adopt shifts a freshly parsed value to the given depth.
+func adopt(value *Node, depth uint8) {
+	last := lastOf(value)
+	for it := value; it != nil; it = it.Next {
+		it.Depth += depth
+		it.LineNumber = 0
+		if it == last {
+			break
+		}
+	}
+}
+
+// splice links the range [first..last] between prev and next.
+func splice(prev, first, last, next *Node) {
+	connect(prev, first)
+	if first.IsCollapsed() {
+		connect(first, next)
+	} else {
+		connect(last, next)
+	}
+}
+
+// connect makes next follow prev. A collapsed prev is linked
+// both from itself and from its closing bracket.
+func connect(prev, next *Node) {
+	if prev != nil {
+		prev.Next = next
+		if prev.IsCollapsed() {
+			prev.End.Next = next
+		}
+	}
+	if next != nil {
+		next.Prev = prev
+	}
+}
+
+func valueOf(n *Node) *Node {
+	if n != nil && n.IsWrap() {
+		return n.Parent
+	}
+	return n
+}
+
+func editable(n *Node) bool {
+	return n != nil && n.Parent != nil && !isClosing(n)
+}
+
+func isClosing(n *Node) bool {
+	return n.Parent != nil && n.Parent.End == n
+}
+
+// lastOf returns the last node of n: its closing bracket or its last wrap chunk.
+func lastOf(n *Node) *Node {
+	if n.End != nil {
+		return n.End
+	}
+	if n.ChunkEnd != nil {
+		return n.ChunkEnd
+	}
+	return n
+}
+
+// afterOf returns the node following n and all of its children.
+func afterOf(n *Node) *Node {
+	return lastOf(n).Next
+}
+
+// The trailing comma of a container is kept on its closing bracket.
+func hasComma(n *Node) bool {
+	if n.End != nil {
+		return n.End.Comma
+	}
+	return n.Comma
+}
+
+func setComma(n *Node, comma bool) {
+	if n.End != nil {
+		n.End.Comma = comma
+		return
+	}
+	n.Comma = comma
+	if n.ChunkEnd != nil {
+		n.ChunkEnd.Comma = comma
+	}
+}
+
+func quote(s string) string {
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(out.String(), "\n")
+}
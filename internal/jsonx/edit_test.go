@@ -0,0 +1,199 @@
+package jsonx_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/antonmedv/fx/internal/jsonx"
+)
+
+// requireConsistent walks the list and checks links, depths and parents.
+func requireConsistent(t *testing.T, root *Node) {
+	t.Helper()
+	for it := root; it != nil; it = it.Next {
+		if it.Next != nil {
+			require.Equal(t, it, it.Next.Prev, "broken link after %s", it.Value)
+		}
+		if it.Parent != nil && !it.IsWrap() && it.Parent.End != it {
+			require.Equal(t, it.Parent.Depth+1, it.Depth, "depth of %s", it.Value)
+		}
+		if it.End != nil {
+			require.Equal(t, it, it.End.Parent)
+			require.Equal(t, -1, it.End.Index)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) *Node {
+	root, err := Parse([]byte(s))
+	require.NoError(t, err)
+	return root
+}
+
+func mustParseValue(t *testing.T, s string) *Node {
+	value, err := ParseValue(s)
+	require.NoError(t, err)
+	return value
+}
+
+func TestParseValue(t *testing.T) {
+	_, err := ParseValue(`{"a": [1, 2]}`)
+	assert.NoError(t, err)
+
+	for _, input := range []string{``, `1 2`, `{a: 1}`, `'x'`, `[1,]`, `NaN`} {
+		_, err := ParseValue(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestReplaceValue(t *testing.T) {
+	root := mustParse(t, `{"a":1,"b":[2,3],"c":"x"}`)
+
+	b := root.FindByPath([]any{"b"})
+	n, ok := ReplaceValue(b, mustParseValue(t, `{"d":true}`))
+	require.True(t, ok)
+	assert.Equal(t, `"b"`, n.Key)
+	assert.Equal(t, root, n.Parent)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":1,"b":{"d":true},"c":"x"}`, root.String())
+
+	c := root.FindByPath([]any{"c"})
+	_, ok = ReplaceValue(c, mustParseValue(t, `[null]`))
+	require.True(t, ok)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":1,"b":{"d":true},"c":[null]}`, root.String())
+
+	one := root.FindByPath([]any{"c", 0})
+	_, ok = ReplaceValue(one, mustParseValue(t, `42`))
+	require.True(t, ok)
+	assert.Equal(t, 0, root.FindByPath([]any{"c", 0}).Index)
+	assert.Equal(t, `{"a":1,"b":{"d":true},"c":[42]}`, root.String())
+
+	_, ok = ReplaceValue(root, mustParseValue(t, `1`))
+	assert.False(t, ok, "root")
+	_, ok = ReplaceValue(root.End, mustParseValue(t, `1`))
+	assert.False(t, ok, "closing bracket")
+}
+
+func TestReplaceValue_Collapsed(t *testing.T) {
+	root := mustParse(t, `[[1,2],{"x":3},4]`)
+
+	first := root.FindByPath([]any{0})
+	first.Collapse()
+	second := root.FindByPath([]any{1})
+	second.Collapse()
+
+	_, ok := ReplaceValue(second, mustParseValue(t, `"y"`))
+	require.True(t, ok)
+	first.Expand()
+	requireConsistent(t, root)
+	assert.Equal(t, `[[1,2],"y",4]`, root.String())
+}
+
+func TestReplaceValue_Wrapped(t *testing.T) {
+	root := mustParse(t, `{"a":"a long string that wraps","b":1}`)
+	Wrap(root, 10)
+
+	a := root.FindByPath([]any{"a"})
+	require.NotNil(t, a.ChunkEnd)
+	_, ok := ReplaceValue(a.ChunkEnd, mustParseValue(t, `2`))
+	require.True(t, ok)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":2,"b":1}`, root.String())
+}
+
+func TestRenameKey(t *testing.T) {
+	root := mustParse(t, `{"a":1,"b":[2]}`)
+
+	require.True(t, RenameKey(root.FindByPath([]any{"a"}), `new "key"`))
+	assert.Equal(t, `{"new \"key\"":1,"b":[2]}`, root.String())
+
+	assert.False(t, RenameKey(root.FindByPath([]any{"b", 0}), "x"), "array element")
+	assert.False(t, RenameKey(root, "x"), "root")
+}
+
+func TestInsertAfter(t *testing.T) {
+	root := mustParse(t, `{"a":1,"b":2}`)
+
+	n, ok := InsertAfter(root.FindByPath([]any{"a"}), "x", mustParseValue(t, `[true]`))
+	require.True(t, ok)
+	assert.Equal(t, `"x"`, n.Key)
+	assert.Equal(t, 3, root.Size)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":1,"x":[true],"b":2}`, root.String())
+
+	_, ok = InsertAfter(root.FindByPath([]any{"b"}), "y", mustParseValue(t, `null`))
+	require.True(t, ok)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":1,"x":[true],"b":2,"y":null}`, root.String())
+}
+
+func TestInsertAfter_Array(t *testing.T) {
+	root := mustParse(t, `[0,[1],2]`)
+	root.FindByPath([]any{1}).Collapse()
+
+	n, ok := InsertAfter(root.FindByPath([]any{1}), "ignored", mustParseValue(t, `"new"`))
+	require.True(t, ok)
+	assert.Equal(t, "", n.Key)
+	assert.Equal(t, 2, n.Index)
+	assert.Equal(t, "2", root.FindByPath([]any{3}).Value)
+
+	root.FindByPath([]any{1}).Expand()
+	requireConsistent(t, root)
+	assert.Equal(t, `[0,[1],"new",2]`, root.String())
+}
+
+func TestInsertFirst(t *testing.T) {
+	root := mustParse(t, `{"a":{},"b":[1]}`)
+
+	a := root.FindByPath([]any{"a"})
+	_, ok := InsertFirst(a, "k", mustParseValue(t, `"v"`))
+	require.True(t, ok)
+	require.NotNil(t, a.End)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":{"k":"v"},"b":[1]}`, root.String())
+
+	_, ok = InsertFirst(root.FindByPath([]any{"b"}), "", mustParseValue(t, `0`))
+	require.True(t, ok)
+	requireConsistent(t, root)
+	assert.Equal(t, 1, root.FindByPath([]any{"b", 1}).Index)
+	assert.Equal(t, `{"a":{"k":"v"},"b":[0,1]}`, root.String())
+
+	empty := mustParse(t, `[]`)
+	_, ok = InsertFirst(empty, "", mustParseValue(t, `{}`))
+	require.True(t, ok)
+	requireConsistent(t, empty)
+	assert.Equal(t, `[{}]`, empty.String())
+
+	_, ok = InsertFirst(root.FindByPath([]any{"a", "k"}), "", mustParseValue(t, `0`))
+	assert.False(t, ok, "scalar")
+}
+
+func TestDuplicate(t *testing.T) {
+	root := mustParse(t, `{"a":{"b":[1,2]},"c":3}`)
+
+	n, ok := Duplicate(root.FindByPath([]any{"a"}))
+	require.True(t, ok)
+	assert.Equal(t, `"a"`, n.Key)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":{"b":[1,2]},"a":{"b":[1,2]},"c":3}`, root.String())
+
+	_, ok = Duplicate(root.FindByPath([]any{"c"}))
+	require.True(t, ok)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":{"b":[1,2]},"a":{"b":[1,2]},"c":3,"c":3}`, root.String())
+	assert.Equal(t, 4, root.Size)
+}
+
+func TestFormatJSON(t *testing.T) {
+	root := mustParse(t, `{"a":{"b":[1,2]},"c":"long string value"}`)
+	Wrap(root, 10)
+	root.FindByPath([]any{"a", "b"}).Collapse()
+
+	a := root.FindByPath([]any{"a"})
+	assert.Equal(t, "{\n  \"b\": [\n    1,\n    2\n  ]\n}", FormatJSON(a, "  "))
+	assert.Equal(t, `{"b":[1,2]}`, Compact(a))
+	assert.Equal(t, `"long string value"`, Compact(root.FindByPath([]any{"c"})))
+}
@@ -0,0 +1,266 @@
+package jsonx
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antonmedv/fx/internal/jsonpath"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// Flatten writes a line "path = value" for every scalar and empty object or array
+// of a document, walking the node list. The root path is ".".
+func Flatten(n *Node, writeOut func(string)) {
+	var path []any
+	var containers []*Node
+	for it := n; it != nil; it = it.Next {
+		if len(containers) > 0 && it == containers[len(containers)-1].End {
+			containers = containers[:len(containers)-1]
+			if len(containers) == 0 {
+				break
+			}
+			path = path[:len(path)-1]
+			continue
+		}
+		if it.Chunk != "" && it.Value == "" {
+			continue // Wrapped string parts.
+		}
+
+		p := path
+		if it != n {
+			var part any = it.Index
+			if it.Key != "" {
+				key, err := utils.Unquote(it.Key)
+				if err != nil {
+					key = it.Key
+				}
+				part = key
+			}
+			p = append(path[:len(path):len(path)], part)
+		}
+
+		if it.HasChildren() && it.Next != it.End {
+			containers = append(containers, it)
+			path = p
+			continue
+		}
+
+		line := jsonpath.Join(p)
+		if line == "" {
+			line = "."
+		}
+		writeOut(line + " = " + Compact(it))
+		if it == n {
+			break
+		}
+	}
+}
+
+// FlatParser rebuilds documents from lines written by Flatten, in any order and
+// with lines left out, for example by grep. Missing array items become null.
+// Documents are separated by blank lines.
+type FlatParser struct {
+	scanner    *bufio.Scanner
+	lineNumber int
+	err        error
+}
+
+func NewFlatParser(in io.Reader) *FlatParser {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	return &FlatParser{scanner: scanner}
+}
+
+func (p *FlatParser) Parse() (*Node, error) {
+	var root *flatNode
+	for p.scanner.Scan() {
+		p.lineNumber++
+		line := strings.TrimRight(p.scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			if root != nil {
+				break
+			}
+			continue
+		}
+		path, value, err := splitFlatLine(line)
+		if err == nil {
+			root, err = root.set(path, value)
+		}
+		if err != nil {
+			p.err = fmt.Errorf("line %d: %w", p.lineNumber, err)
+			return nil, p.err
+		}
+	}
+	if err := p.scanner.Err(); err != nil {
+		p.err = err
+		return nil, err
+	}
+	if root == nil {
+		return nil, io.EOF
+	}
+	var out strings.Builder
+	root.write(&out)
+	return Parse([]byte(out.String()))
+}
+
+func (p *FlatParser) Recover() *Node {
+	message := "invalid line"
+	if p.err != nil {
+		message = p.err.Error()
+	}
+	return &Node{
+		Kind:       Err,
+		Value:      message,
+		Index:      -1,
+		LineNumber: p.lineNumber,
+	}
+}
+
+// splitFlatLine parses "path = value", with paths as printed by jsonpath.Join.
+func splitFlatLine(line string) ([]any, string, error) {
+	var path []any
+	rest := line
+	if strings.HasPrefix(rest, ". = ") {
+		rest = rest[1:]
+	}
+	for !strings.HasPrefix(rest, " = ") {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			i := 1
+			for i < len(rest) && isIdentifierByte(rest[i]) {
+				i++
+			}
+			if i == 1 {
+				return nil, "", fmt.Errorf("invalid path %q", line)
+			}
+			path = append(path, rest[1:i])
+			rest = rest[i:]
+		case strings.HasPrefix(rest, `["`):
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil || !strings.HasPrefix(rest[1+len(quoted):], "]") {
+				return nil, "", fmt.Errorf("invalid path %q", line)
+			}
+			key, _ := strconv.Unquote(quoted)
+			path = append(path, key)
+			rest = rest[1+len(quoted)+1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			index, err := strconv.Atoi(rest[1:max(end, 1)])
+			if end < 0 || err != nil || index < 0 {
+				return nil, "", fmt.Errorf("invalid path %q", line)
+			}
+			path = append(path, index)
+			rest = rest[end+1:]
+		default:
+			return nil, "", fmt.Errorf("expected \"path = value\", got %q", line)
+		}
+	}
+	value := strings.TrimSpace(rest[len(" = "):])
+	if !json.Valid([]byte(value)) {
+		return nil, "", fmt.Errorf("invalid value %q", value)
+	}
+	return path, value, nil
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '$' || b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+type flatNode struct {
+	kind   Kind   // Object, Array, or Err for scalars.
+	value  string // Of scalars.
+	keys   []string
+	fields map[string]*flatNode
+	items  []*flatNode // Nil items are null.
+}
+
+// set puts the value at path, creating objects and arrays on the way.
+func (n *flatNode) set(path []any, value string) (*flatNode, error) {
+	if len(path) == 0 {
+		switch value {
+		case "{}":
+			if n == nil || n.kind == Object {
+				return orNew(n, Object), nil
+			}
+		case "[]":
+			if n == nil || n.kind == Array {
+				return orNew(n, Array), nil
+			}
+		default:
+			if n == nil || n.kind != Object && n.kind != Array {
+				return &flatNode{kind: Err, value: value}, nil
+			}
+		}
+		return nil, fmt.Errorf("value %s conflicts with an earlier line", value)
+	}
+
+	switch part := path[0].(type) {
+	case string:
+		if n != nil && n.kind != Object {
+			return nil, fmt.Errorf("key %q conflicts with an earlier line", part)
+		}
+		n = orNew(n, Object)
+		child, err := n.fields[part].set(path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := n.fields[part]; !ok {
+			n.keys = append(n.keys, part)
+		}
+		n.fields[part] = child
+	case int:
+		if n != nil && n.kind != Array {
+			return nil, fmt.Errorf("index %d conflicts with an earlier line", part)
+		}
+		n = orNew(n, Array)
+		for len(n.items) <= part {
+			n.items = append(n.items, nil)
+		}
+		child, err := n.items[part].set(path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n.items[part] = child
+	}
+	return n, nil
+}
+
+func orNew(n *flatNode, kind Kind) *flatNode {
+	if n != nil {
+		return n
+	}
+	return &flatNode{kind: kind, fields: map[string]*flatNode{}}
+}
+
+func (n *flatNode) write(out *strings.Builder) {
+	switch {
+	case n == nil:
+		out.WriteString("null")
+	case n.kind == Object:
+		out.WriteByte('{')
+		for i, key := range n.keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(quote(key))
+			out.WriteByte(':')
+			n.fields[key].write(out)
+		}
+		out.WriteByte('}')
+	case n.kind == Array:
+		out.WriteByte('[')
+		for i, item := range n.items {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			item.write(out)
+		}
+		out.WriteByte(']')
+	default:
+		out.WriteString(n.value)
+	}
+}
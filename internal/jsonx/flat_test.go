@@ -0,0 +1,94 @@
+package jsonx_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func flatten(t *testing.T, input string) []string {
+	node, err := jsonx.Parse([]byte(input))
+	require.NoError(t, err)
+	var lines []string
+	jsonx.Flatten(node, func(s string) { lines = append(lines, s) })
+	return lines
+}
+
+func unflatten(t *testing.T, input string) []string {
+	p := jsonx.NewFlatParser(strings.NewReader(input))
+	var docs []string
+	for {
+		node, err := p.Parse()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		docs = append(docs, jsonx.Compact(node))
+	}
+	return docs
+}
+
+const flatDoc = `{"users":[{"name":"bob","tags":[]},{"name":"ann","meta":{}}],` +
+	`"a b":{"x.y":1.50,"":null,"\"q\"\n":true},"zeta":"é","$id":1e3}`
+
+func TestFlatten(t *testing.T) {
+	assert.Equal(t, []string{
+		`.users[0].name = "bob"`,
+		`.users[0].tags = []`,
+		`.users[1].name = "ann"`,
+		`.users[1].meta = {}`,
+		`["a b"]["x.y"] = 1.50`,
+		`["a b"][""] = null`,
+		`["a b"]["\"q\"\n"] = true`,
+		`.zeta = "é"`,
+		`.$id = 1e3`,
+	}, flatten(t, flatDoc))
+}
+
+func TestFlatten_Roots(t *testing.T) {
+	assert.Equal(t, []string{`. = 1`}, flatten(t, `1`))
+	assert.Equal(t, []string{`. = {}`}, flatten(t, `{}`))
+	assert.Equal(t, []string{`[0] = []`, `[1][0] = "x"`}, flatten(t, `[[], ["x"]]`))
+}
+
+func TestFlatParser_RoundTrip(t *testing.T) {
+	for _, doc := range []string{flatDoc, `1`, `"s"`, `{}`, `[]`, `[[], [{}], {"a": [[1]]}]`} {
+		t.Run(doc, func(t *testing.T) {
+			node, err := jsonx.Parse([]byte(doc))
+			require.NoError(t, err)
+			want := jsonx.Compact(node)
+			assert.Equal(t, []string{want}, unflatten(t, strings.Join(flatten(t, doc), "\n")))
+		})
+	}
+}
+
+func TestFlatParser(t *testing.T) {
+	assert.Equal(t, []string{`{"users":[null,null,{"name":"bob"}]}`, `{"a":1}`},
+		unflatten(t, ".users[2].name = \"bob\"\r\n\n\n.a = 1\n"))
+	assert.Equal(t, []string{`{"b":{"c":2},"a":1}`}, unflatten(t, ".b.c = 2\n.a = 1\n.b = {}"))
+}
+
+func TestFlatParser_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`.a.b = 1` + "\n" + `.a = 2`, `line 2: value 2 conflicts with an earlier line`},
+		{`.a = 1` + "\n" + `.a[0] = 2`, `line 2: index 0 conflicts with an earlier line`},
+		{`.a = nope`, `line 1: invalid value "nope"`},
+		{`.a-b = 1`, `line 1: expected "path = value", got ".a-b = 1"`},
+		{`["a] = 1`, `line 1: invalid path "[\"a] = 1"`},
+		{`.a`, `line 1: expected "path = value", got ".a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := jsonx.NewFlatParser(strings.NewReader(tt.input)).Parse()
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package jsonx
+
+// change is a reversible mutation of the node list.
+// Both functions return a node to select afterward.
+type change struct {
+	apply, revert func() *Node
+}
+
+func apply(c *change, ok bool) (*Node, bool) {
+	if !ok {
+		return nil, false
+	}
+	return c.apply(), true
+}
+
+// History records mutations of the node list, so they can be undone and redone.
+// Methods mirror the package functions of the same name.
+type History struct {
+	done, undone []*change
+}
+
+func (h *History) record(c *change, ok bool) (*Node, bool) {
+	if !ok {
+		return nil, false
+	}
+	h.done = append(h.done, c)
+	h.undone = nil
+	return c.apply(), true
+}
+
+func (h *History) DeleteNode(at *Node) (*Node, bool) {
+	return h.record(deleteChange(at))
+}
+
+func (h *History) ReplaceValue(at, value *Node) (*Node, bool) {
+	return h.record(replaceChange(at, value))
+}
+
+func (h *History) RenameKey(at *Node, key string) bool {
+	_, ok := h.record(renameChange(at, key))
+	return ok
+}
+
+func (h *History) InsertAfter(at *Node, key string, value *Node) (*Node, bool) {
+	return h.record(insertAfterChange(at, key, value))
+}
+
+func (h *History) InsertFirst(at *Node, key string, value *Node) (*Node, bool) {
+	return h.record(insertFirstChange(at, key, value))
+}
+
+func (h *History) Duplicate(at *Node) (*Node, bool) {
+	return h.record(duplicateChange(at))
+}
+
+// SortKeys sorts keys of all nodes as one change, so a single Undo reverts them all.
+func (h *History) SortKeys(nodes ...*Node) (*Node, bool) {
+	var changes []*change
+	for _, n := range nodes {
+		if c, ok := sortKeysChange(n); ok {
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return nil, false
+	}
+	return h.record(&change{
+		apply: func() *Node {
+			at := changes[0].apply()
+			for _, c := range changes[1:] {
+				c.apply()
+			}
+			return at
+		},
+		revert: func() *Node {
+			for i := len(changes) - 1; i > 0; i-- {
+				changes[i].revert()
+			}
+			return changes[0].revert()
+		},
+	}, true)
+}
+
+// Undo reverts the last change and returns the node it was applied at.
+func (h *History) Undo() (*Node, bool) {
+	if len(h.done) == 0 {
+		return nil, false
+	}
+	c := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, c)
+	return c.revert(), true
+}
+
+// Redo applies the last undone change again.
+func (h *History) Redo() (*Node, bool) {
+	if len(h.undone) == 0 {
+		return nil, false
+	}
+	c := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, c)
+	return c.apply(), true
+}
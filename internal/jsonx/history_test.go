@@ -0,0 +1,189 @@
+package jsonx_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/antonmedv/fx/internal/jsonx"
+)
+
+// snapshot dumps every node reachable from root, including collapsed ones,
+// with all of its links, so the exact linkage can be compared.
+func snapshot(root *Node) []string {
+	var out []string
+	for it := root; it != nil; {
+		out = append(out, fmt.Sprintf(
+			"%p prev=%p next=%p end=%p parent=%p collapsed=%p chunkEnd=%p depth=%d key=%s value=%s chunk=%q size=%d comma=%v index=%d",
+			it, it.Prev, it.Next, it.End, it.Parent, it.Collapsed, it.ChunkEnd,
+			it.Depth, it.Key, it.Value, it.Chunk, it.Size, it.Comma, it.Index,
+		))
+		if it.IsCollapsed() {
+			it = it.Collapsed
+		} else {
+			it = it.Next
+		}
+	}
+	return out
+}
+
+func TestHistory_DeleteArray(t *testing.T) {
+	for _, path := range [][]any{{0}, {1}, {3}} {
+		t.Run(fmt.Sprint(path), func(t *testing.T) {
+			root := mustParse(t, `[10,[20,21],30,40]`)
+			before := snapshot(root)
+
+			var h History
+			_, ok := h.DeleteNode(root.FindByPath(path))
+			require.True(t, ok)
+			after := snapshot(root)
+			require.NotEqual(t, before, after)
+
+			n, ok := h.Undo()
+			require.True(t, ok)
+			assert.Equal(t, root.FindByPath(path), n)
+			assert.Equal(t, before, snapshot(root))
+
+			_, ok = h.Redo()
+			require.True(t, ok)
+			assert.Equal(t, after, snapshot(root))
+
+			_, ok = h.Undo()
+			require.True(t, ok)
+			assert.Equal(t, before, snapshot(root))
+		})
+	}
+}
+
+func TestHistory_DeleteObject(t *testing.T) {
+	for _, key := range []string{"a", "b", "c"} {
+		t.Run(key, func(t *testing.T) {
+			root := mustParse(t, `{"a":{"x":1},"b":2,"c":[3]}`)
+			before := snapshot(root)
+
+			var h History
+			_, ok := h.DeleteNode(root.FindByPath([]any{key}))
+			require.True(t, ok)
+			requireConsistent(t, root)
+
+			_, ok = h.Undo()
+			require.True(t, ok)
+			assert.Equal(t, before, snapshot(root))
+		})
+	}
+}
+
+func TestHistory_DeleteAll(t *testing.T) {
+	root := mustParse(t, `{"a":1,"b":2}`)
+	before := snapshot(root)
+
+	var h History
+	for _, key := range []string{"b", "a"} {
+		_, ok := h.DeleteNode(root.FindByPath([]any{key}))
+		require.True(t, ok)
+	}
+	assert.Equal(t, 0, root.Size)
+
+	for range 2 {
+		_, ok := h.Undo()
+		require.True(t, ok)
+	}
+	assert.Equal(t, before, snapshot(root))
+
+	_, ok := h.Undo()
+	assert.False(t, ok, "nothing to undo")
+}
+
+func TestHistory_DeleteWrapped(t *testing.T) {
+	root := mustParse(t, `["a long string that wraps","another long string",1]`)
+	Wrap(root, 10)
+	before := snapshot(root)
+
+	first := root.FindByPath([]any{0})
+	second := root.FindByPath([]any{1})
+	require.NotNil(t, first.ChunkEnd)
+	require.NotNil(t, second.ChunkEnd)
+
+	var h History
+	_, ok := h.DeleteNode(second.ChunkEnd) // Delete through a wrap chunk.
+	require.True(t, ok)
+	assert.Equal(t, `["a long string that wraps",1]`, Compact(root))
+
+	_, ok = h.DeleteNode(first)
+	require.True(t, ok)
+	assert.Equal(t, `[1]`, Compact(root))
+
+	h.Undo()
+	h.Undo()
+	assert.Equal(t, before, snapshot(root))
+}
+
+func TestHistory_DeleteCollapsed(t *testing.T) {
+	root := mustParse(t, `{"a":[1,2],"b":{"c":3},"d":4}`)
+	root.FindByPath([]any{"a"}).Collapse()
+	root.FindByPath([]any{"b"}).Collapse()
+	before := snapshot(root)
+
+	var h History
+	_, ok := h.DeleteNode(root.FindByPath([]any{"d"}))
+	require.True(t, ok)
+	_, ok = h.DeleteNode(root.FindByPath([]any{"b"}))
+	require.True(t, ok)
+
+	// The collapsed sibling before the deleted node must keep a valid closing bracket link.
+	a := root.FindByPath([]any{"a"})
+	a.Expand()
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":[1,2]}`, root.String())
+	a.Collapse()
+
+	h.Undo()
+	h.Undo()
+	assert.Equal(t, before, snapshot(root))
+}
+
+func TestHistory_Edits(t *testing.T) {
+	root := mustParse(t, `{"a":1,"b":[],"c":"x"}`)
+	before := snapshot(root)
+
+	var h History
+	_, ok := h.ReplaceValue(root.FindByPath([]any{"a"}), mustParseValue(t, `{"z":true}`))
+	require.True(t, ok)
+	require.True(t, h.RenameKey(root.FindByPath([]any{"c"}), "renamed"))
+	_, ok = h.InsertFirst(root.FindByPath([]any{"b"}), "", mustParseValue(t, `0`))
+	require.True(t, ok)
+	_, ok = h.InsertAfter(root.FindByPath([]any{"b", 0}), "", mustParseValue(t, `1`))
+	require.True(t, ok)
+	_, ok = h.Duplicate(root.FindByPath([]any{"a"}))
+	require.True(t, ok)
+
+	after := snapshot(root)
+	assert.Equal(t, `{"a":{"z":true},"a":{"z":true},"b":[0,1],"renamed":"x"}`, root.String())
+
+	for range 5 {
+		_, ok := h.Undo()
+		require.True(t, ok)
+	}
+	assert.Equal(t, before, snapshot(root))
+
+	for range 5 {
+		_, ok := h.Redo()
+		require.True(t, ok)
+	}
+	assert.Equal(t, after, snapshot(root))
+}
+
+func TestHistory_NewChangeClearsRedo(t *testing.T) {
+	root := mustParse(t, `[1,2,3]`)
+
+	var h History
+	h.DeleteNode(root.FindByPath([]any{0}))
+	h.Undo()
+	h.DeleteNode(root.FindByPath([]any{2}))
+
+	_, ok := h.Redo()
+	assert.False(t, ok)
+	assert.Equal(t, `[1,2]`, root.String())
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math/big"
 	"strconv"
 	"strings"
 	"unicode"
@@ -109,7 +110,8 @@ func (p *JsonParser) Recover() *Node {
 
 func (p *JsonParser) refill() {
 	n, err := p.rd.Read(p.buf)
-	if err != nil {
+	p.data = append(p.data, p.buf[:n]...)
+	if err != nil && n == 0 {
 		if err == io.EOF {
 			p.eof = true
 			return
@@ -117,11 +119,10 @@ func (p *JsonParser) refill() {
 			panic(err)
 		}
 	}
-	p.data = append(p.data, p.buf[:n]...)
 }
 
 func (p *JsonParser) next() {
-	if p.end >= len(p.data) {
+	for p.end >= len(p.data) && !p.eof {
 		p.refill()
 	}
 	if p.eof {
@@ -157,11 +158,13 @@ func (p *JsonParser) parseValue(root bool) *Node {
 
 	var l *Node
 	switch p.char {
-	case '"':
+	case '"', '\'':
 		l = p.parseString()
 	case '-':
 		l = p.parseMinus()
-	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+	case '+':
+		l = p.parsePlus()
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
 		l = p.parseNumber(p.end - 1)
 	case '{':
 		l = p.parseObject()
@@ -205,6 +208,9 @@ func (p *JsonParser) parseString() *Node {
 }
 
 func (p *JsonParser) scanString() string {
+	if p.char == '\'' {
+		return p.scanSingleQuotedString()
+	}
 	start := p.end - 1
 	p.next()
 	escaped := false
@@ -246,9 +252,98 @@ func (p *JsonParser) scanString() string {
 	str := string(p.data[start:p.end])
 	p.next()
 
+	if !p.strict {
+		str = normalizeEscapes(str)
+	}
 	return str
 }
 
+// scanSingleQuotedString scans a JSON5 single-quoted string
+// and returns it as a double-quoted JSON string.
+func (p *JsonParser) scanSingleQuotedString() string {
+	if p.strict {
+		panic("Single-quoted strings are not allowed in strict mode")
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	p.next()
+	for p.char != '\'' {
+		switch p.char {
+		case 0:
+			panic("Unexpected end of input in string")
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteByte(p.char)
+			p.next()
+			if p.char == 0 {
+				panic("Unexpected end of input in string")
+			}
+			b.WriteByte(p.char)
+		default:
+			b.WriteByte(p.char)
+		}
+		p.next()
+	}
+	p.next()
+	b.WriteByte('"')
+	return normalizeEscapes(b.String())
+}
+
+// normalizeEscapes rewrites JSON5-only escape sequences (\', \0, \v, \xFF,
+// and escaped line breaks) and raw control characters into valid JSON.
+func normalizeEscapes(str string) string {
+	if !strings.ContainsAny(str, "\\\n\r\t") {
+		return str
+	}
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		ch := str[i]
+		switch {
+		case ch == '\\' && i+1 < len(str):
+			i++
+			switch str[i] {
+			case '\'':
+				b.WriteByte('\'')
+			case '0':
+				// JSON5 disallows a digit after \0, so it is kept as is.
+				if i+1 < len(str) && str[i+1] >= '0' && str[i+1] <= '9' {
+					b.WriteString(`\0`)
+				} else {
+					b.WriteString(`\u0000`)
+				}
+			case 'v':
+				b.WriteString(`\u000b`)
+			case '\n':
+			case '\r':
+				if i+1 < len(str) && str[i+1] == '\n' {
+					i++
+				}
+			case 'x':
+				if i+2 < len(str) && utils.IsHexDigit(str[i+1]) && utils.IsHexDigit(str[i+2]) {
+					b.WriteString(`\u00`)
+					b.WriteString(str[i+1 : i+3])
+					i += 2
+				} else {
+					b.WriteString(`\x`)
+				}
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(str[i])
+			}
+		case ch == '\n':
+			b.WriteString(`\n`)
+		case ch == '\r':
+			b.WriteString(`\r`)
+		case ch == '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
 func (p *JsonParser) parseMinus() *Node {
 	start := p.end - 1
 	p.next()
@@ -258,6 +353,8 @@ func (p *JsonParser) parseMinus() *Node {
 	}
 	if !p.strict {
 		switch p.char {
+		case '.':
+			return p.parseNumber(start)
 		case 'n', 'N':
 			return p.parseNan(start)
 		case 'i', 'I':
@@ -267,6 +364,23 @@ func (p *JsonParser) parseMinus() *Node {
 	panic(fmt.Sprintf("Invalid character %q in number", p.char))
 }
 
+// parsePlus parses JSON5 numbers with an explicit plus sign, the sign is dropped.
+func (p *JsonParser) parsePlus() *Node {
+	if p.strict {
+		panic(fmt.Sprintf("Unexpected character %q", p.char))
+	}
+	p.next()
+	switch p.char {
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
+		return p.parseNumber(p.end - 1)
+	case 'n', 'N':
+		return p.parseNan(p.end - 1)
+	case 'i', 'I':
+		return p.parseInfinity(p.end - 1)
+	}
+	panic(fmt.Sprintf("Invalid character %q in number", p.char))
+}
+
 func (p *JsonParser) parseNumber(start int) *Node {
 	num := &Node{
 		Kind:       Number,
@@ -274,9 +388,20 @@ func (p *JsonParser) parseNumber(start int) *Node {
 		LineNumber: p.lineNumberPlusPlus(),
 	}
 
+	if p.char == '.' && p.strict {
+		panic(fmt.Sprintf("Unexpected character %q", p.char))
+	}
+	intDigits := utils.IsDigit(p.char)
+
 	// Leading zero
 	if p.char == '0' {
 		p.next()
+		if p.char == 'x' || p.char == 'X' {
+			if p.strict {
+				panic("Hexadecimal numbers are not allowed in strict mode")
+			}
+			return p.parseHex(num, start)
+		}
 	} else {
 		for utils.IsDigit(p.char) {
 			p.next()
@@ -286,7 +411,7 @@ func (p *JsonParser) parseNumber(start int) *Node {
 	// Decimal portion
 	if p.char == '.' {
 		p.next()
-		if !utils.IsDigit(p.char) {
+		if !utils.IsDigit(p.char) && (p.strict || !intDigits) {
 			panic(fmt.Sprintf("Invalid character %q in number", p.char))
 		}
 		for utils.IsDigit(p.char) {
@@ -309,9 +434,46 @@ func (p *JsonParser) parseNumber(start int) *Node {
 	}
 
 	num.Value = string(p.data[start : p.end-1])
+	if !p.strict {
+		num.Value = normalizeNumber(num.Value)
+	}
+	return num
+}
+
+// parseHex parses JSON5 hexadecimal integers and stores them in decimal.
+func (p *JsonParser) parseHex(num *Node, start int) *Node {
+	p.next()
+	digitsStart := p.end - 1
+	for utils.IsHexDigit(p.char) {
+		p.next()
+	}
+	digits := string(p.data[digitsStart : p.end-1])
+	if digits == "" {
+		panic(fmt.Sprintf("Invalid character %q in number", p.char))
+	}
+	bi, _ := new(big.Int).SetString(digits, 16)
+	if p.data[start] == '-' {
+		bi.Neg(bi)
+	}
+	num.Value = bi.String()
 	return num
 }
 
+// normalizeNumber rewrites JSON5 leading and trailing decimal points: -.5 → -0.5, 5. → 5.
+func normalizeNumber(s string) string {
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	if s[0] == '.' {
+		s = "0" + s
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 && (i == len(s)-1 || s[i+1] == 'e' || s[i+1] == 'E') {
+		s = s[:i] + s[i+1:]
+	}
+	return sign + s
+}
+
 func (p *JsonParser) parseObject() *Node {
 	object := &Node{
 		Kind:       Object,
@@ -331,13 +493,15 @@ func (p *JsonParser) parseObject() *Node {
 	}
 
 	for {
-		// Expecting a key which should be a string
-		if p.char != '"' {
+		var keyBytes string
+		if p.char == '"' || p.char == '\'' {
+			keyBytes = p.scanString()
+		} else if !p.strict && isIdentifierStart(p.char) {
+			keyBytes = p.scanIdentifier()
+		} else {
 			panic(fmt.Sprintf("Expected object key to be a string, got %q", p.char))
 		}
 
-		keyBytes := p.scanString()
-
 		p.skipWhitespace()
 
 		// Expecting colon after key
@@ -569,6 +733,19 @@ func (p *JsonParser) parseInfinity(start int) *Node {
 	panic(fmt.Sprintf("Unexpected character %q", p.char))
 }
 
+// scanIdentifier scans a JSON5 unquoted object key and returns it quoted.
+func (p *JsonParser) scanIdentifier() string {
+	start := p.end - 1
+	for isIdentifierStart(p.char) || utils.IsDigit(p.char) {
+		p.next()
+	}
+	return `"` + string(p.data[start:p.end-1]) + `"`
+}
+
+func isIdentifierStart(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch == '$' || ch >= 0x80
+}
+
 func isEndOfValue(ch byte) bool {
 	return isWhitespace(ch) || ch == ',' || ch == '}' || ch == ']' || ch == 0 // 0 is EOF
 }
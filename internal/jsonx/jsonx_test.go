@@ -3,6 +3,7 @@ package jsonx_test
 import (
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -98,6 +99,12 @@ func TestJsonParser_Parse_strict(t *testing.T) {
 		{`/*comment*/ 42`},
 		{`undefined`},
 		{`"\g"`},
+		{`'single'`},
+		{`{key: 1}`},
+		{`0x1F`},
+		{`+1`},
+		{`.5`},
+		{`5.`},
 	}
 
 	for _, tt := range tests {
@@ -109,6 +116,87 @@ func TestJsonParser_Parse_strict(t *testing.T) {
 	}
 }
 
+func TestJsonParser_JSON5(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`'single'`, `"single"`},
+		{`'say "hi"'`, `"say \"hi\""`},
+		{`'it\'s'`, `"it's"`},
+		{`"it\'s"`, `"it's"`},
+		{`'\x41'`, `"\u0041"`},
+		{`'a\0b'`, `"a\u0000b"`},
+		{`"\0"`, `"\u0000"`},
+		{`'\v'`, `"\u000b"`},
+		{`"tab\vend"`, `"tab\u000bend"`},
+		{"'line \\\nbreak'", `"line break"`},
+		{`{key: 1, $ref: 2, _x1: 3}`, `{"key":1,"$ref":2,"_x1":3}`},
+		{`{'a': 'b',}`, `{"a":"b"}`},
+		{`0x1F`, `31`},
+		{`-0XFF`, `-255`},
+		{`+0x10`, `16`},
+		{`+1`, `1`},
+		{`+Infinity`, `Infinity`},
+		{`.5`, `0.5`},
+		{`-.5e1`, `-0.5e1`},
+		{`5.`, `5`},
+		{`5.e3`, `5e3`},
+		{`[1, +2, .3, 0x4,]`, `[1,2,0.3,4]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := jsonx.Parse([]byte(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, node.String())
+		})
+	}
+}
+
+func TestJsonParser_JSON5_LineNumbers(t *testing.T) {
+	input := `{
+  unquoted: 'value',
+  hex: 0xFF,
+  list: [
+    +1,
+    .5,
+  ],
+  'quoted': 'multi\
+line',
+  last: true,
+}`
+	node, err := jsonx.Parse([]byte(input))
+	require.NoError(t, err)
+
+	tests := []struct {
+		path []any
+		line int
+	}{
+		{[]any{"unquoted"}, 2},
+		{[]any{"hex"}, 3},
+		{[]any{"list"}, 4},
+		{[]any{"list", 0}, 5},
+		{[]any{"list", 1}, 6},
+		{[]any{"quoted"}, 8},
+		{[]any{"last"}, 9},
+	}
+	for _, tt := range tests {
+		n := node.FindByPath(tt.path)
+		require.NotNil(t, n, "%v", tt.path)
+		assert.Equal(t, tt.line, n.LineNumber, "%v", tt.path)
+	}
+	assert.Equal(t, `"multiline"`, node.FindByPath([]any{"quoted"}).Value)
+}
+
+func TestJsonParser_DataWithEOF(t *testing.T) {
+	// Readers like gzip return the last chunk of data together with io.EOF.
+	p := jsonx.NewJsonParser(iotest.DataErrReader(strings.NewReader(`{"a": [1, 2]}`)), false)
+	node, err := p.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":[1,2]}`, node.String())
+}
+
 func TestJsonParser_Recovery(t *testing.T) {
 	brokenJSON := `{ "a": 1 }here goes the text`
 	t.Run("Recover", func(t *testing.T) {
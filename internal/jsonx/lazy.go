@@ -0,0 +1,176 @@
+package jsonx
+
+import (
+	"strconv"
+
+	"github.com/dop251/goja"
+
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// ToLazyValue is like ToValue, but objects and arrays convert their children
+// on first access, so a script reading a few fields doesn't pay for the whole tree.
+//
+// The value is a proxy over a regular object or array, which has every key
+// from the start, so key order and mutations behave as with ToValue.
+// A child is converted when its value is read, or before the key is redefined
+// or the object is made non-extensible (e.g. by Object.freeze).
+func (n *Node) ToLazyValue(vm *goja.Runtime) goja.Value {
+	var target *goja.Object
+	l := &lazy{vm: vm, pending: map[string]*Node{}}
+	texts := map[string]string{}
+
+	switch n.Kind {
+	case Object:
+		target = vm.NewObject()
+		for _, child := range childrenOf(n) {
+			key, err := utils.Unquote(child.Key)
+			if err != nil {
+				panic(err)
+			}
+			// The last duplicate key wins, as with ToValue.
+			if err := target.Set(key, goja.Undefined()); err != nil {
+				panic(err)
+			}
+			l.pending[key] = child
+			if text, ok := numberText(vm, child); ok {
+				texts[key] = text
+			}
+		}
+
+	case Array:
+		children := childrenOf(n)
+		l.array = true
+		target = vm.NewArray(make([]any, len(children))...)
+		for i, child := range children {
+			key := strconv.Itoa(i)
+			l.pending[key] = child
+			if text, ok := numberText(vm, child); ok {
+				texts[key] = text
+			}
+		}
+
+	default:
+		return n.ToValue(vm)
+	}
+
+	setNumberTexts(vm, target, texts)
+	if len(l.pending) == 0 {
+		return target
+	}
+	return vm.ToValue(vm.NewProxy(target, &goja.ProxyTrapConfig{
+		Get:                      l.get,
+		GetOwnPropertyDescriptor: l.getOwnPropertyDescriptor,
+		DefineProperty:           l.defineProperty,
+		DeleteProperty:           l.deleteProperty,
+		PreventExtensions:        l.preventExtensions,
+	}))
+}
+
+// numberText returns the source of a number node float64 formats differently.
+func numberText(vm *goja.Runtime, n *Node) (string, bool) {
+	if n.Kind != Number {
+		return "", false
+	}
+	if v, _, _ := numberValue(vm, n.Value); v.String() == n.Value {
+		return "", false
+	}
+	return n.Value, true
+}
+
+// lazy holds the proxy traps of a value from ToLazyValue. Pending keys
+// hold undefined on the target until their node is converted.
+type lazy struct {
+	vm      *goja.Runtime
+	array   bool
+	pending map[string]*Node
+}
+
+// materialize converts the pending node of key, if any, and stores it on the target.
+func (l *lazy) materialize(target *goja.Object, key string) {
+	node, ok := l.pending[key]
+	if !ok {
+		return
+	}
+	delete(l.pending, key)
+	if err := target.Set(key, node.ToLazyValue(l.vm)); err != nil {
+		panic(err)
+	}
+}
+
+func (l *lazy) get(target *goja.Object, key string, _ goja.Value) goja.Value {
+	l.materialize(target, key)
+	return target.Get(key)
+}
+
+func (l *lazy) getOwnPropertyDescriptor(target *goja.Object, key string) goja.PropertyDescriptor {
+	l.materialize(target, key)
+	desc := l.reflect("getOwnPropertyDescriptor", target, l.vm.ToValue(key))
+	if goja.IsUndefined(desc) {
+		return goja.PropertyDescriptor{}
+	}
+	obj := desc.ToObject(l.vm)
+	flag := func(name string) goja.Flag {
+		if v := obj.Get(name); v != nil {
+			return goja.ToFlag(v.ToBoolean())
+		}
+		return goja.FLAG_NOT_SET
+	}
+	return goja.PropertyDescriptor{
+		Value:        obj.Get("value"),
+		Writable:     flag("writable"),
+		Configurable: flag("configurable"),
+		Enumerable:   flag("enumerable"),
+		Getter:       obj.Get("get"),
+		Setter:       obj.Get("set"),
+	}
+}
+
+func (l *lazy) defineProperty(target *goja.Object, key string, desc goja.PropertyDescriptor) bool {
+	if desc.Value == nil && desc.Getter == nil && desc.Setter == nil {
+		// Only the flags change, e.g. by Object.freeze, so keep the value.
+		l.materialize(target, key)
+	}
+	delete(l.pending, key)
+	if l.array && key == "length" && desc.Value != nil {
+		// Truncating drops the elements past the new length.
+		for k := range l.pending {
+			if i, _ := strconv.ParseInt(k, 10, 64); i >= desc.Value.ToInteger() {
+				delete(l.pending, k)
+			}
+		}
+	}
+
+	var err error
+	if desc.Getter != nil || desc.Setter != nil {
+		err = target.DefineAccessorProperty(key, desc.Getter, desc.Setter, desc.Configurable, desc.Enumerable)
+	} else {
+		err = target.DefineDataProperty(key, desc.Value, desc.Writable, desc.Configurable, desc.Enumerable)
+	}
+	return err == nil
+}
+
+func (l *lazy) deleteProperty(target *goja.Object, key string) bool {
+	delete(l.pending, key)
+	return target.Delete(key) == nil
+}
+
+func (l *lazy) preventExtensions(target *goja.Object) bool {
+	for key := range l.pending {
+		l.materialize(target, key)
+	}
+	return l.reflect("preventExtensions", target).ToBoolean()
+}
+
+// reflect calls the Reflect method name, for traps with no Go counterpart on goja.Object.
+func (l *lazy) reflect(name string, args ...goja.Value) goja.Value {
+	fn, ok := goja.AssertFunction(l.vm.Get("Reflect").ToObject(l.vm).Get(name))
+	if !ok {
+		panic(l.vm.NewTypeError("Reflect.%s is not a function", name))
+	}
+	v, err := fn(goja.Undefined(), args...)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
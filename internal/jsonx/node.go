@@ -356,6 +356,11 @@ func (n *Node) Paths(paths *[]string, nodes *[]*Node) {
 	}
 }
 
+// Elements returns the children of an object or an array, even if it is collapsed.
+func (n *Node) Elements() []*Node {
+	return childrenOf(n)
+}
+
 func (n *Node) ForEach(cb func(*Node)) {
 	it := n.Next
 	for it != nil && it != n.End {
@@ -0,0 +1,104 @@
+package jsonx
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// SortKeys sorts keys of n and all objects nested in it.
+// It returns (n, true) if any keys were reordered.
+func SortKeys(n *Node) (*Node, bool) {
+	return apply(sortKeysChange(n))
+}
+
+func sortKeysChange(n *Node) (*change, bool) {
+	if n == nil {
+		return nil, false
+	}
+	n = valueOf(n)
+	if isClosing(n) {
+		n = n.Parent
+	}
+
+	type reordering struct {
+		parent        *Node
+		before, after []*Node
+	}
+	var changes []reordering
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		children := childrenOf(n)
+		for _, child := range children {
+			walk(child)
+		}
+		if n.Kind != Object || len(children) < 2 {
+			return
+		}
+		sorted := slices.Clone(children)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return unquoteKey(sorted[i].Key) < unquoteKey(sorted[j].Key)
+		})
+		if !slices.Equal(children, sorted) {
+			changes = append(changes, reordering{n, children, sorted})
+		}
+	}
+	walk(n)
+	if len(changes) == 0 {
+		return nil, false
+	}
+
+	return &change{
+		apply: func() *Node {
+			for _, c := range changes {
+				reorder(c.parent, c.after)
+			}
+			return n
+		},
+		revert: func() *Node {
+			for i := len(changes) - 1; i >= 0; i-- {
+				reorder(changes[i].parent, changes[i].before)
+			}
+			return n
+		},
+	}, true
+}
+
+// childrenOf returns the child values of n, including the ones hidden by collapsing.
+func childrenOf(n *Node) []*Node {
+	if !n.HasChildren() {
+		return nil
+	}
+	it := n.Next
+	if n.IsCollapsed() {
+		it = n.Collapsed
+	}
+	var children []*Node
+	for ; it != nil && it != n.End; it = afterOf(it) {
+		children = append(children, it)
+	}
+	return children
+}
+
+// reorder relinks all children of parent in the given order, keeping parent collapsed if it was.
+func reorder(parent *Node, children []*Node) {
+	collapsed := parent.IsCollapsed()
+	for _, child := range children {
+		unlink(child)
+	}
+	var after *Node
+	for _, child := range children {
+		link(parent, after, child)
+		after = child
+	}
+	if collapsed {
+		parent.Collapse()
+	}
+}
+
+func unquoteKey(key string) string {
+	if s, err := strconv.Unquote(key); err == nil {
+		return s
+	}
+	return key
+}
@@ -0,0 +1,52 @@
+package jsonx_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/antonmedv/fx/internal/jsonx"
+)
+
+func TestSortKeys(t *testing.T) {
+	root := mustParse(t, `{"b":{"z":1,"y":[{"d":1,"c":2}]},"a":"x","c":[]}`)
+
+	n, ok := SortKeys(root)
+	require.True(t, ok)
+	assert.Equal(t, root, n)
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":"x","b":{"y":[{"c":2,"d":1}],"z":1},"c":[]}`, root.String())
+
+	_, ok = SortKeys(root)
+	assert.False(t, ok, "already sorted")
+}
+
+func TestSortKeys_Collapsed(t *testing.T) {
+	root := mustParse(t, `{"b":{"y":1,"x":2},"a":1}`)
+	b := root.FindByPath([]any{"b"})
+	b.Collapse()
+
+	_, ok := SortKeys(root)
+	require.True(t, ok)
+	assert.True(t, b.IsCollapsed())
+	b.Expand()
+	requireConsistent(t, root)
+	assert.Equal(t, `{"a":1,"b":{"x":2,"y":1}}`, root.String())
+}
+
+func TestHistory_SortKeys(t *testing.T) {
+	root := mustParse(t, `{"b":{"d":1,"c":2},"a":[{"f":1,"e":2}]}`)
+	root.FindByPath([]any{"a"}).Collapse()
+	before := snapshot(root)
+
+	var h History
+	_, ok := h.SortKeys(root)
+	require.True(t, ok)
+	after := snapshot(root)
+
+	h.Undo()
+	assert.Equal(t, before, snapshot(root))
+	h.Redo()
+	assert.Equal(t, after, snapshot(root))
+}
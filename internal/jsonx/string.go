@@ -37,3 +37,58 @@ func (n *Node) String() string {
 
 	return out.String()
 }
+
+// FormatJSON prints the node as indented, uncolored JSON.
+func FormatJSON(n *Node, indent string) string {
+	var out strings.Builder
+	stop := afterOf(n)
+	for it := n; it != nil && it != stop; {
+		if it.IsWrap() {
+			it = it.Next
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Repeat(indent, int(it.Depth-n.Depth)))
+		if it.Key != "" && it != n {
+			out.WriteString(it.Key)
+			out.WriteString(": ")
+		}
+		out.WriteString(it.Value)
+		if it.Comma && it != n && it != n.End {
+			out.WriteByte(',')
+		}
+		it = it.nextExpanded()
+	}
+	return out.String()
+}
+
+// Compact prints the node as JSON on a single line.
+func Compact(n *Node) string {
+	var out strings.Builder
+	stop := afterOf(n)
+	for it := n; it != nil && it != stop; {
+		if it.IsWrap() {
+			it = it.Next
+			continue
+		}
+		if it.Key != "" && it != n {
+			out.WriteString(it.Key)
+			out.WriteByte(':')
+		}
+		out.WriteString(it.Value)
+		if it.Comma && it != n && it != n.End {
+			out.WriteByte(',')
+		}
+		it = it.nextExpanded()
+	}
+	return out.String()
+}
+
+func (n *Node) nextExpanded() *Node {
+	if n.IsCollapsed() {
+		return n.Collapsed
+	}
+	return n.Next
+}
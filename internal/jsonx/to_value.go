@@ -1,6 +1,7 @@
 package jsonx
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
@@ -24,15 +25,12 @@ func (n *Node) ToValue(vm *goja.Runtime) goja.Value {
 		}
 
 	case Number:
-		i, ok := ParseNumber(n.Value)
-		if ok {
-			return vm.ToValue(i)
+		v, f, lost := numberValue(vm, n.Value)
+		// The parent keeps the source text, see setNumberTexts.
+		if lost && PrecisionLoss != nil {
+			PrecisionLoss(n.Value, f)
 		}
-		f, err := strconv.ParseFloat(n.Value, 64)
-		if err == nil {
-			return vm.ToValue(f)
-		}
-		panic(err)
+		return v
 
 	case String:
 		unquoted, err := utils.Unquote(n.Value)
@@ -43,6 +41,7 @@ func (n *Node) ToValue(vm *goja.Runtime) goja.Value {
 
 	case Object:
 		obj := vm.NewObject()
+		texts := map[string]string{}
 
 		if n.HasChildren() {
 			it := n
@@ -58,23 +57,31 @@ func (n *Node) ToValue(vm *goja.Runtime) goja.Value {
 					panic(err)
 				}
 
-				err = obj.Set(unquotedKey, it.ToValue(vm))
+				value := it.ToValue(vm)
+				err = obj.Set(unquotedKey, value)
 				if err != nil {
 					panic(err)
 				}
+				if it.Kind == Number && value.String() != it.Value {
+					texts[unquotedKey] = it.Value
+				}
 
 				if it.HasChildren() {
 					it = it.End.Next
+				} else if it.ChunkEnd != nil {
+					it = it.ChunkEnd.Next
 				} else {
 					it = it.Next
 				}
 			}
 		}
 
+		setNumberTexts(vm, obj, texts)
 		return obj
 
 	case Array:
 		var arr []any
+		texts := map[string]string{}
 
 		if n.HasChildren() {
 			it := n
@@ -85,17 +92,25 @@ func (n *Node) ToValue(vm *goja.Runtime) goja.Value {
 			}
 
 			for it != nil && it != n.End {
-				arr = append(arr, it.ToValue(vm))
+				value := it.ToValue(vm)
+				if it.Kind == Number && value.String() != it.Value {
+					texts[strconv.Itoa(len(arr))] = it.Value
+				}
+				arr = append(arr, value)
 
 				if it.HasChildren() {
 					it = it.End.Next
+				} else if it.ChunkEnd != nil {
+					it = it.ChunkEnd.Next
 				} else {
 					it = it.Next
 				}
 			}
 		}
 
-		return vm.NewArray(arr...)
+		obj := vm.NewArray(arr...)
+		setNumberTexts(vm, obj, texts)
+		return obj
 
 	case NaN:
 		return vm.ToValue(math.NaN())
@@ -138,3 +153,16 @@ func ParseNumber(s string) (interface{}, bool) {
 
 	return bi, true
 }
+
+// numberValue converts number text to a JS number, reporting whether
+// float64 changes its value, e.g. 19.990000000000001 or 1e999.
+func numberValue(vm *goja.Runtime, text string) (v goja.Value, f float64, lost bool) {
+	if i, ok := ParseNumber(text); ok {
+		return vm.ToValue(i), 0, false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		panic(err)
+	}
+	return vm.ToValue(f), f, err != nil || lossy(text, f)
+}
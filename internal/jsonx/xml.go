@@ -0,0 +1,263 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XmlParser streams top-level XML elements as objects with one key, the element name.
+// An element becomes:
+//   - null if it is self-closing (<a/>) without attributes,
+//   - a string of its text if it has neither attributes nor child elements,
+//   - otherwise an object of attributes as "@name" keys, text as "#text", and child
+//     elements by name, as arrays if repeated.
+//
+// Attribute values and texts are strings. Texts around child elements are trimmed.
+// If text follows a child element (mixed content), "#text" is an array of the texts
+// before each child element and after the last one, so their order is kept. Otherwise,
+// or if repeated child elements are not adjacent, the texts are joined with spaces. Comments, processing instructions and directives are
+// skipped. Line numbers of nodes are lines of the XML source.
+type XmlParser struct {
+	in      io.Reader
+	data    []byte
+	decoder *xml.Decoder
+	line    int
+	err     error
+}
+
+func NewXmlParser(in io.Reader) *XmlParser {
+	return &XmlParser{in: in}
+}
+
+type xmlElement struct {
+	name        string
+	line        int
+	endLine     int
+	attrs       []xml.Attr
+	children    []*xmlElement
+	text        []string
+	runs        []string // Text before each child, and after the last one.
+	textLine    int
+	selfClosing bool
+}
+
+func (p *XmlParser) Parse() (*Node, error) {
+	if p.err != nil {
+		return nil, io.EOF // Already reported.
+	}
+	if p.decoder == nil {
+		data, err := io.ReadAll(p.in)
+		if err != nil {
+			p.err = err
+			return nil, err
+		}
+		p.data = data
+		p.decoder = xml.NewDecoder(bytes.NewReader(data))
+		p.decoder.Strict = true
+	}
+
+	var stack []*xmlElement
+	for {
+		line, _ := p.decoder.InputPos()
+		token, err := p.decoder.RawToken()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return nil, p.fail(fmt.Errorf("xml: element <%s> on line %d is not closed", stack[len(stack)-1].name, stack[len(stack)-1].line))
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, p.fail(err)
+		}
+		p.line = line
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			offset := p.decoder.InputOffset()
+			el := &xmlElement{
+				name:        xmlName(t.Name),
+				line:        line,
+				attrs:       t.Attr,
+				selfClosing: offset >= 2 && string(p.data[offset-2:offset]) == "/>",
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			}
+			stack = append(stack, el)
+
+		case xml.EndElement:
+			endLine, _ := p.decoder.InputPos()
+			if len(stack) == 0 {
+				return nil, p.fail(fmt.Errorf("xml: unexpected </%s> on line %d", xmlName(t.Name), endLine))
+			}
+			el := stack[len(stack)-1]
+			if name := xmlName(t.Name); name != el.name {
+				return nil, p.fail(fmt.Errorf("xml: element <%s> on line %d is closed by </%s>", el.name, el.line, name))
+			}
+			el.endLine = endLine
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				root := &Node{Kind: Object, Value: curlyBracketOpen, LineNumber: el.line}
+				xmlAdd(root, el.name, el.value(1))
+				xmlClose(root, el.endLine)
+				return root, nil
+			}
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				el := stack[len(stack)-1]
+				if el.textLine == 0 && len(bytes.TrimSpace(t)) > 0 {
+					el.textLine = line
+				}
+				el.text = append(el.text, string(t))
+				for len(el.runs) <= len(el.children) {
+					el.runs = append(el.runs, "")
+				}
+				el.runs[len(el.children)] += string(t)
+			}
+		}
+	}
+}
+
+func (p *XmlParser) fail(err error) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		err = fmt.Errorf("xml: line %d: %s", syntaxErr.Line, syntaxErr.Msg)
+	}
+	p.err = err
+	return err
+}
+
+func (p *XmlParser) Recover() *Node {
+	message := "invalid xml"
+	if p.err != nil {
+		message = p.err.Error()
+	}
+	return &Node{
+		Kind:       Err,
+		Value:      message,
+		Index:      -1,
+		LineNumber: p.line,
+	}
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+func (el *xmlElement) value(depth uint8) *Node {
+	if len(el.attrs) == 0 && len(el.children) == 0 {
+		if el.selfClosing {
+			return &Node{Kind: Null, Value: "null", Depth: depth, LineNumber: el.line}
+		}
+		return &Node{Kind: String, Value: quote(strings.Join(el.text, "")), Depth: depth, LineNumber: el.line}
+	}
+
+	object := &Node{Kind: Object, Value: curlyBracketOpen, Depth: depth, LineNumber: el.line}
+	for _, attr := range el.attrs {
+		xmlAdd(object, "@"+xmlName(attr.Name), &Node{Kind: String, Value: quote(attr.Value), Depth: depth + 1, LineNumber: el.line})
+	}
+	var texts []string
+	for _, t := range el.text {
+		if t = strings.TrimSpace(t); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	if runs, ok := el.mixedText(); ok {
+		array := &Node{Kind: Array, Value: squareBracketOpen, Depth: depth + 1, LineNumber: el.textLine}
+		for i, run := range runs {
+			xmlAdd(array, "", &Node{Kind: String, Value: quote(run), Depth: depth + 2, Index: i, LineNumber: el.textLine})
+		}
+		xmlClose(array, el.textLine)
+		xmlAdd(object, "#text", array)
+	} else if text := strings.Join(texts, " "); text != "" {
+		xmlAdd(object, "#text", &Node{Kind: String, Value: quote(text), Depth: depth + 1, LineNumber: el.textLine})
+	}
+
+	var names []string
+	groups := map[string][]*xmlElement{}
+	for _, child := range el.children {
+		if _, ok := groups[child.name]; !ok {
+			names = append(names, child.name)
+		}
+		groups[child.name] = append(groups[child.name], child)
+	}
+	for _, name := range names {
+		group := groups[name]
+		if len(group) == 1 {
+			xmlAdd(object, name, group[0].value(depth+1))
+			continue
+		}
+		array := &Node{Kind: Array, Value: squareBracketOpen, Depth: depth + 1, LineNumber: group[0].line}
+		for i, child := range group {
+			item := child.value(depth + 2)
+			item.Index = i
+			xmlAdd(array, "", item)
+		}
+		xmlClose(array, group[len(group)-1].endLine)
+		xmlAdd(object, name, array)
+	}
+	xmlClose(object, el.endLine)
+	return object
+}
+
+// mixedText returns the trimmed texts before each child element and after the
+// last one, if there is text after a child and the order of children is kept by
+// grouping them by name.
+func (el *xmlElement) mixedText() ([]string, bool) {
+	runs := make([]string, len(el.children)+1)
+	mixed := false
+	for i, run := range el.runs {
+		runs[i] = strings.TrimSpace(run)
+		if i > 0 && runs[i] != "" {
+			mixed = true
+		}
+	}
+	if !mixed {
+		return nil, false
+	}
+	seen := map[string]bool{}
+	for i, child := range el.children {
+		if seen[child.name] && el.children[i-1].name != child.name {
+			return nil, false
+		}
+		seen[child.name] = true
+	}
+	return runs, true
+}
+
+// xmlAdd appends a complete child node to an object, or to an array if key is empty.
+func xmlAdd(parent *Node, key string, child *Node) {
+	if parent.Size > 0 {
+		parent.End.Comma = true
+	}
+	if key != "" {
+		child.Key = quote(key)
+	}
+	child.Parent = parent
+	parent.Append(child)
+	parent.Size++
+}
+
+func xmlClose(n *Node, line int) {
+	closing := curlyBracketClose
+	if n.Kind == Array {
+		closing = squareBracketClose
+	}
+	n.Append(&Node{
+		Kind:       n.Kind,
+		Value:      closing,
+		Parent:     n,
+		Depth:      n.Depth,
+		Index:      -1,
+		LineNumber: line,
+	})
+}
@@ -0,0 +1,112 @@
+package jsonx_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+func parseAllXml(t *testing.T, input string) []*jsonx.Node {
+	p := jsonx.NewXmlParser(strings.NewReader(input))
+	var docs []*jsonx.Node
+	for {
+		node, err := p.Parse()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		docs = append(docs, node)
+	}
+	return docs
+}
+
+const xmlDoc = `<?xml version="1.0"?>
+<!-- catalog -->
+<catalog xmlns:x="urn:x" id="1">
+  <book lang="en">
+    <title>Go &amp; XML</title>
+    <x:tag/>
+    <note></note>
+  </book>
+  <book>
+    <title> Padded </title>
+  </book>
+  Some <![CDATA[<text>]]>
+</catalog>
+<log>done</log>
+`
+
+func TestXmlParser(t *testing.T) {
+	docs := parseAllXml(t, xmlDoc)
+	require.Len(t, docs, 2)
+	assert.Equal(t, `{"catalog":{"@xmlns:x":"urn:x","@id":"1","#text":["","","Some <text>"],"book":[`+
+		`{"@lang":"en","title":"Go & XML","x:tag":null,"note":""},`+
+		`{"title":" Padded "}]}}`, jsonx.Compact(docs[0]))
+	assert.Equal(t, `{"log":"done"}`, jsonx.Compact(docs[1]))
+}
+
+func TestXmlParser_MixedContent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`<p>Hi <b>you</b></p>`, `{"p":{"#text":"Hi","b":"you"}}`},
+		{`<p>Hi <b>you</b> and <i>me</i>!</p>`, `{"p":{"#text":["Hi","and","!"],"b":"you","i":"me"}}`},
+		{`<p><b>1</b>, <b>2</b>.</p>`, `{"p":{"#text":["",",","."],"b":["1","2"]}}`},
+		{`<p><b>1</b>, <i>2</i>, <b>3</b></p>`, `{"p":{"#text":", ,","b":["1","3"],"i":"2"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			docs := parseAllXml(t, tt.input)
+			require.Len(t, docs, 1)
+			assert.Equal(t, tt.want, jsonx.Compact(docs[0]))
+		})
+	}
+}
+
+func TestXmlParser_LineNumbers(t *testing.T) {
+	docs := parseAllXml(t, xmlDoc)
+	lines := map[string]int{}
+	for it := docs[0]; it != nil; it = it.Next {
+		if _, ok := lines[it.Key+it.Value]; !ok {
+			lines[it.Key+it.Value] = it.LineNumber
+		}
+	}
+	assert.Equal(t, 3, lines[`{`])
+	assert.Equal(t, 3, lines[`"@id""1"`])
+	assert.Equal(t, 4, lines[`"book"[`])
+	assert.Equal(t, 5, lines[`"title""Go & XML"`])
+	assert.Equal(t, 6, lines[`"x:tag"null`])
+	assert.Equal(t, 10, lines[`"title"" Padded "`])
+	assert.Equal(t, 11, lines[`]`])
+	assert.Equal(t, 8, lines[`}`])
+	assert.Equal(t, 13, docs[0].End.LineNumber)
+	assert.Equal(t, 14, docs[1].LineNumber)
+}
+
+func TestXmlParser_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"<a>\n<b></a>", "xml: element <b> on line 2 is closed by </a>"},
+		{"<a>\n<b>", "xml: element <b> on line 2 is not closed"},
+		{"</a>", "xml: unexpected </a> on line 1"},
+		{"<a>\n<b x=1/></a>", "xml: line 2: unquoted or missing attribute value in element"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p := jsonx.NewXmlParser(strings.NewReader(tt.input))
+			_, err := p.Parse()
+			require.EqualError(t, err, tt.err)
+			assert.Equal(t, tt.err, p.Recover().Value)
+			_, err = p.Parse()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
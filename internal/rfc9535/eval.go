@@ -0,0 +1,350 @@
+package rfc9535
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// maxExponent bounds exponents of numbers compared exactly, so 1e999999999 doesn't eat all memory.
+const maxExponent = 10000
+
+// Select returns the nodes the query selects in root, in document order of the segments.
+func (q *Query) Select(root *jsonx.Node) []*jsonx.Node {
+	return selectSegments(q.segments, root, root)
+}
+
+func selectSegments(segments []segment, current, root *jsonx.Node) []*jsonx.Node {
+	nodes := []*jsonx.Node{current}
+	for _, seg := range segments {
+		var next []*jsonx.Node
+		for _, n := range nodes {
+			if seg.descendant {
+				for _, d := range descendants(n, nil) {
+					next = seg.apply(next, d, root)
+				}
+			} else {
+				next = seg.apply(next, n, root)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants returns n and all its descendants in preorder.
+func descendants(n *jsonx.Node, out []*jsonx.Node) []*jsonx.Node {
+	out = append(out, n)
+	for _, child := range n.Elements() {
+		out = descendants(child, out)
+	}
+	return out
+}
+
+func (seg segment) apply(out []*jsonx.Node, n, root *jsonx.Node) []*jsonx.Node {
+	for _, sel := range seg.selectors {
+		switch sel := sel.(type) {
+		case nameSelector:
+			if n.Kind != jsonx.Object {
+				continue
+			}
+			var found *jsonx.Node
+			for _, child := range n.Elements() {
+				if keyOf(child) == string(sel) {
+					found = child // The last duplicate key wins, as in JS.
+				}
+			}
+			if found != nil {
+				out = append(out, found)
+			}
+
+		case wildcardSelector:
+			out = append(out, n.Elements()...)
+
+		case indexSelector:
+			if n.Kind != jsonx.Array {
+				continue
+			}
+			elements := n.Elements()
+			i := int(sel)
+			if i < 0 {
+				i += len(elements)
+			}
+			if i >= 0 && i < len(elements) {
+				out = append(out, elements[i])
+			}
+
+		case sliceSelector:
+			if n.Kind != jsonx.Array {
+				continue
+			}
+			out = sel.apply(out, n.Elements())
+
+		case filterSelector:
+			for _, child := range n.Elements() {
+				if test(sel.expr, child, root) {
+					out = append(out, child)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// apply selects elements as described in RFC 9535, section 2.3.4.2.2.
+func (s sliceSelector) apply(out []*jsonx.Node, elements []*jsonx.Node) []*jsonx.Node {
+	length := len(elements)
+	if s.step == 0 {
+		return out
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	start, end := 0, length
+	if s.step < 0 {
+		start, end = length-1, -length-1
+	}
+	if s.start != nil {
+		start = *s.start
+	}
+	if s.end != nil {
+		end = *s.end
+	}
+	start, end = normalize(start), normalize(end)
+	if s.step > 0 {
+		lower, upper := min(max(start, 0), length), min(max(end, 0), length)
+		for i := lower; i < upper; i += s.step {
+			out = append(out, elements[i])
+		}
+	} else {
+		upper, lower := min(max(start, -1), length-1), min(max(end, -1), length-1)
+		for i := upper; lower < i; i += s.step {
+			out = append(out, elements[i])
+		}
+	}
+	return out
+}
+
+func test(expr logical, current, root *jsonx.Node) bool {
+	switch expr := expr.(type) {
+	case orExpr:
+		for _, e := range expr {
+			if test(e, current, root) {
+				return true
+			}
+		}
+		return false
+	case andExpr:
+		for _, e := range expr {
+			if !test(e, current, root) {
+				return false
+			}
+		}
+		return true
+	case notExpr:
+		return !test(expr.expr, current, root)
+	case testExpr:
+		switch e := expr.expr.(type) {
+		case *filterQuery:
+			return len(e.eval(current, root)) > 0
+		case *functionExpr:
+			switch result := e.eval(current, root).(type) {
+			case bool:
+				return result
+			case []*jsonx.Node:
+				return len(result) > 0
+			}
+		}
+		return false
+	case comparisonExpr:
+		left := valueOf(expr.left, current, root)
+		right := valueOf(expr.right, current, root)
+		switch expr.op {
+		case "==":
+			return equal(left, right)
+		case "!=":
+			return !equal(left, right)
+		case "<":
+			return less(left, right)
+		case "<=":
+			return less(left, right) || equal(left, right)
+		case ">":
+			return less(right, left)
+		case ">=":
+			return less(right, left) || equal(left, right)
+		}
+	}
+	return false
+}
+
+func (q *filterQuery) eval(current, root *jsonx.Node) []*jsonx.Node {
+	if q.relative {
+		return selectSegments(q.segments, current, root)
+	}
+	return selectSegments(q.segments, root, root)
+}
+
+func (f *functionExpr) eval(current, root *jsonx.Node) any {
+	args := make([]any, len(f.args))
+	for i, arg := range f.args {
+		switch f.fn.params[i] {
+		case valueType:
+			args[i] = valueOf(arg, current, root)
+		case nodesType:
+			switch arg := arg.(type) {
+			case *filterQuery:
+				args[i] = arg.eval(current, root)
+			case *functionExpr:
+				args[i] = arg.eval(current, root)
+			}
+		case logicalType:
+			args[i] = test(arg, current, root)
+		}
+	}
+	return f.fn.call(args)
+}
+
+// valueOf evaluates a comparand to a literal value, a node or nothing.
+func valueOf(c comparand, current, root *jsonx.Node) any {
+	switch c := c.(type) {
+	case literal:
+		return c.value
+	case *filterQuery:
+		if nodes := c.eval(current, root); len(nodes) == 1 {
+			return nodes[0]
+		}
+		return nothing{}
+	case *functionExpr:
+		return c.eval(current, root)
+	}
+	return nothing{}
+}
+
+// scalar converts primitive nodes to literal values, leaving objects and arrays as is.
+func scalar(v any) any {
+	n, ok := v.(*jsonx.Node)
+	if !ok {
+		return v
+	}
+	switch n.Kind {
+	case jsonx.Null:
+		return nil
+	case jsonx.Bool:
+		return n.Value == "true"
+	case jsonx.Number:
+		if r, ok := numberOf(n.Value); ok {
+			return r
+		}
+	case jsonx.String:
+		return stringOf(n)
+	}
+	return n
+}
+
+func equal(a, b any) bool {
+	a, b = scalar(a), scalar(b)
+	switch a := a.(type) {
+	case nothing:
+		_, ok := b.(nothing)
+		return ok
+	case nil:
+		return b == nil
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case *big.Rat:
+		b, ok := b.(*big.Rat)
+		return ok && a.Cmp(b) == 0
+	case *jsonx.Node:
+		b, ok := b.(*jsonx.Node)
+		return ok && deepEqual(a, b)
+	}
+	return false
+}
+
+func less(a, b any) bool {
+	a, b = scalar(a), scalar(b)
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return ok && a < b
+	case *big.Rat:
+		b, ok := b.(*big.Rat)
+		return ok && a.Cmp(b) < 0
+	}
+	return false
+}
+
+func deepEqual(a, b *jsonx.Node) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case jsonx.Object:
+		x, y := members(a), members(b)
+		if len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case jsonx.Array:
+		x, y := a.Elements(), b.Elements()
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Value == b.Value
+}
+
+func members(n *jsonx.Node) map[string]*jsonx.Node {
+	m := map[string]*jsonx.Node{}
+	for _, child := range n.Elements() {
+		m[keyOf(child)] = child
+	}
+	return m
+}
+
+func keyOf(n *jsonx.Node) string {
+	key, err := utils.Unquote(n.Key)
+	if err != nil {
+		return n.Key
+	}
+	return key
+}
+
+func stringOf(n *jsonx.Node) string {
+	s, err := utils.Unquote(n.Value)
+	if err != nil {
+		return n.Value
+	}
+	return s
+}
+
+func numberOf(text string) (*big.Rat, bool) {
+	if _, exp, ok := strings.Cut(strings.ToLower(text), "e"); ok {
+		if e, err := strconv.Atoi(exp); err != nil || e > maxExponent || e < -maxExponent {
+			return nil, false
+		}
+	}
+	return new(big.Rat).SetString(text)
+}
@@ -0,0 +1,155 @@
+package rfc9535
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+type paramType byte
+
+const (
+	valueType paramType = iota
+	logicalType
+	nodesType
+)
+
+type function struct {
+	name   string
+	params []paramType
+	result paramType
+	call   func(args []any) any
+}
+
+// nothing is the absence of a value, e.g. the result of a query selecting no nodes.
+type nothing struct{}
+
+var functions = map[string]*function{
+	"length": {
+		name:   "length",
+		params: []paramType{valueType},
+		result: valueType,
+		call: func(args []any) any {
+			switch v := args[0].(type) {
+			case string:
+				return big.NewRat(int64(utf8.RuneCountInString(v)), 1)
+			case *jsonx.Node:
+				switch v.Kind {
+				case jsonx.String:
+					return big.NewRat(int64(utf8.RuneCountInString(stringOf(v))), 1)
+				case jsonx.Object, jsonx.Array:
+					return big.NewRat(int64(len(v.Elements())), 1)
+				}
+			}
+			return nothing{}
+		},
+	},
+	"count": {
+		name:   "count",
+		params: []paramType{nodesType},
+		result: valueType,
+		call: func(args []any) any {
+			return big.NewRat(int64(len(args[0].([]*jsonx.Node))), 1)
+		},
+	},
+	"match": {
+		name:   "match",
+		params: []paramType{valueType, valueType},
+		result: logicalType,
+		call: func(args []any) any {
+			return matches(args[0], args[1], true)
+		},
+	},
+	"search": {
+		name:   "search",
+		params: []paramType{valueType, valueType},
+		result: logicalType,
+		call: func(args []any) any {
+			return matches(args[0], args[1], false)
+		},
+	},
+	"value": {
+		name:   "value",
+		params: []paramType{nodesType},
+		result: valueType,
+		call: func(args []any) any {
+			if nodes := args[0].([]*jsonx.Node); len(nodes) == 1 {
+				return nodes[0]
+			}
+			return nothing{}
+		},
+	},
+}
+
+// regexps caches compiled I-Regexp patterns, as filters run them on every node.
+var regexps sync.Map
+
+func matches(value, pattern any, full bool) bool {
+	s, ok := asString(value)
+	if !ok {
+		return false
+	}
+	p, ok := asString(pattern)
+	if !ok {
+		return false
+	}
+	key := p
+	if full {
+		key = "^(?:" + p + ")$"
+	}
+	re, ok := regexps.Load(key)
+	if !ok {
+		compiled, err := regexp.Compile(iregexp(key))
+		if err != nil {
+			regexps.Store(key, (*regexp.Regexp)(nil))
+			return false
+		}
+		re, _ = regexps.LoadOrStore(key, compiled)
+	}
+	if re.(*regexp.Regexp) == nil {
+		return false
+	}
+	return re.(*regexp.Regexp).MatchString(s)
+}
+
+// iregexp translates an I-Regexp (RFC 9485) to Go syntax, where the only
+// difference is that . matches any character except \n and \r.
+func iregexp(pattern string) string {
+	var out strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			out.WriteByte(c)
+			i++
+			out.WriteByte(pattern[i])
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '.' && !inClass:
+			out.WriteString(`[^\n\r]`)
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
+
+func asString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case *jsonx.Node:
+		if v.Kind == jsonx.String {
+			return stringOf(v), true
+		}
+	}
+	return "", false
+}
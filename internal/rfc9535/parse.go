@@ -0,0 +1,670 @@
+// Package rfc9535 implements JSONPath queries as specified in RFC 9535,
+// evaluated directly on jsonx nodes.
+package rfc9535
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxInt is the largest integer an index or a slice can have, 2^53 - 1.
+const maxInt = 1<<53 - 1
+
+// Query is a parsed JSONPath query.
+type Query struct {
+	source   string
+	segments []segment
+}
+
+func (q *Query) String() string {
+	return q.source
+}
+
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+type selector interface{}
+
+type (
+	nameSelector     string
+	wildcardSelector struct{}
+	indexSelector    int
+	sliceSelector    struct {
+		start, end *int
+		step       int
+	}
+	filterSelector struct{ expr logical }
+)
+
+// logical is an expression of LogicalType.
+type logical interface{}
+
+type (
+	orExpr  []logical
+	andExpr []logical
+	notExpr struct{ expr logical }
+	// testExpr is a filter query or a function returning LogicalType or NodesType.
+	testExpr       struct{ expr any }
+	comparisonExpr struct {
+		left, right comparand
+		op          string
+	}
+)
+
+// comparand is a literal, a singular query or a function returning ValueType.
+type comparand interface{}
+
+type literal struct{ value any } // nil, bool, string or *big.Rat.
+
+type filterQuery struct {
+	relative bool
+	segments []segment
+}
+
+type functionExpr struct {
+	fn   *function
+	args []any
+}
+
+// Parse parses a JSONPath query, e.g. $.store.book[?@.price < 10].title.
+func Parse(s string) (*Query, error) {
+	p := &parser{input: s}
+	if !p.consume("$") {
+		return nil, p.errorf("query must start with $")
+	}
+	segments, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, p.unexpected()
+	}
+	return &Query{source: s, segments: segments}, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("jsonpath: %s at position %d", fmt.Sprintf(format, args...), p.pos+1)
+}
+
+func (p *parser) rest() string {
+	r := p.input[p.pos:]
+	if len(r) > 10 {
+		r = r[:10] + "..."
+	}
+	return r
+}
+
+func (p *parser) unexpected() error {
+	if p.pos >= len(p.input) {
+		return p.errorf("unexpected end of query")
+	}
+	return p.errorf("unexpected %q", p.rest())
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) has(s string) bool {
+	return strings.HasPrefix(p.input[p.pos:], s)
+}
+
+func (p *parser) consume(s string) bool {
+	if p.has(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.consume(s) {
+		if p.pos >= len(p.input) {
+			return p.errorf("expected %q, got end of query", s)
+		}
+		return p.errorf("expected %q, got %q", s, p.rest())
+	}
+	return nil
+}
+
+func (p *parser) blank() {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) segments() ([]segment, error) {
+	var segments []segment
+	for {
+		start := p.pos
+		p.blank()
+		if !p.has(".") && !p.has("[") {
+			p.pos = start
+			return segments, nil
+		}
+		seg, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+}
+
+func (p *parser) segment() (segment, error) {
+	if p.consume("..") {
+		if p.has("[") {
+			selectors, err := p.bracketed()
+			return segment{descendant: true, selectors: selectors}, err
+		}
+		sel, err := p.shorthand()
+		return segment{descendant: true, selectors: []selector{sel}}, err
+	}
+	if p.consume(".") {
+		sel, err := p.shorthand()
+		return segment{selectors: []selector{sel}}, err
+	}
+	selectors, err := p.bracketed()
+	return segment{selectors: selectors}, err
+}
+
+// shorthand parses a wildcard or a member name after a dot.
+func (p *parser) shorthand() (selector, error) {
+	if p.consume("*") {
+		return wildcardSelector{}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if !isNameFirst(r) && (p.pos == start || !isDigit(r)) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a member name or *")
+	}
+	return nameSelector(p.input[start:p.pos]), nil
+}
+
+func isNameFirst(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' ||
+		r >= 0x80 && r <= 0xD7FF || r >= 0xE000 && r <= 0x10FFFF
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (p *parser) bracketed() ([]selector, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	var selectors []selector
+	for {
+		p.blank()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+		p.blank()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) selector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.string()
+		return nameSelector(s), err
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '?':
+		p.pos++
+		p.blank()
+		expr, err := p.logicalOr()
+		return filterSelector{expr}, err
+	case c == ':' || c == '-' || isDigit(rune(c)):
+		return p.indexOrSlice()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) indexOrSlice() (selector, error) {
+	var bounds [3]*int
+	for i := 0; i < 3; i++ {
+		p.blank()
+		if c := p.peek(); c == '-' || isDigit(rune(c)) {
+			n, err := p.int()
+			if err != nil {
+				return nil, err
+			}
+			bounds[i] = &n
+			p.blank()
+		}
+		if i == 0 && !p.has(":") {
+			if bounds[0] == nil {
+				return nil, p.errorf("expected an index")
+			}
+			return indexSelector(*bounds[0]), nil
+		}
+		if i == 2 || !p.consume(":") {
+			break
+		}
+	}
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	return sliceSelector{start: bounds[0], end: bounds[1], step: step}, nil
+}
+
+func (p *parser) int() (int, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for isDigit(rune(p.peek())) {
+		p.pos++
+	}
+	s := p.input[start:p.pos]
+	switch {
+	case p.pos == digits:
+		return 0, p.errorf("expected a digit")
+	case p.input[digits] == '0' && (p.pos-digits > 1 || digits > start):
+		return 0, p.errorf("invalid integer %s", s)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n > maxInt || n < -maxInt {
+		return 0, p.errorf("integer %s out of range", s)
+	}
+	return n, nil
+}
+
+func (p *parser) string() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+	var out strings.Builder
+	for {
+		if p.pos >= len(p.input) {
+			return "", p.errorf("unterminated string")
+		}
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		switch {
+		case r == rune(quote):
+			p.pos++
+			return out.String(), nil
+		case r < 0x20:
+			return "", p.errorf("control character in string")
+		case r == '\\':
+			p.pos++
+			r, err := p.escape(quote)
+			if err != nil {
+				return "", err
+			}
+			out.WriteRune(r)
+		default:
+			p.pos += size
+			out.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) escape(quote byte) (rune, error) {
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case '/', '\\':
+		return rune(c), nil
+	case quote:
+		return rune(quote), nil
+	case 'u':
+		r, err := p.hex4()
+		if err != nil {
+			return 0, err
+		}
+		if utf16.IsSurrogate(r) {
+			if r >= 0xDC00 || !p.consume(`\u`) {
+				return 0, p.errorf("invalid surrogate pair")
+			}
+			low, err := p.hex4()
+			if err != nil {
+				return 0, err
+			}
+			r = utf16.DecodeRune(r, low)
+			if r == utf8.RuneError {
+				return 0, p.errorf("invalid surrogate pair")
+			}
+		}
+		return r, nil
+	}
+	p.pos--
+	return 0, p.errorf("invalid escape \\%c", c)
+}
+
+func (p *parser) hex4() (rune, error) {
+	if p.pos+4 > len(p.input) {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	n, err := strconv.ParseUint(p.input[p.pos:p.pos+4], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid unicode escape")
+	}
+	p.pos += 4
+	return rune(n), nil
+}
+
+func (p *parser) logicalOr() (logical, error) {
+	or := orExpr{}
+	for {
+		expr, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+		start := p.pos
+		p.blank()
+		if !p.consume("||") {
+			p.pos = start
+			break
+		}
+		p.blank()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) logicalAnd() (logical, error) {
+	and := andExpr{}
+	for {
+		expr, err := p.basic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		start := p.pos
+		p.blank()
+		if !p.consume("&&") {
+			p.pos = start
+			break
+		}
+		p.blank()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) basic() (logical, error) {
+	if p.consume("!") {
+		p.blank()
+		if p.consume("(") {
+			expr, err := p.paren()
+			return notExpr{expr}, err
+		}
+		expr, err := p.test()
+		return notExpr{expr}, err
+	}
+	if p.consume("(") {
+		return p.paren()
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	start := p.pos
+	p.blank()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			p.blank()
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.checkComparable(left); err != nil {
+				return nil, err
+			}
+			if err := p.checkComparable(right); err != nil {
+				return nil, err
+			}
+			return comparisonExpr{left: left, op: op, right: right}, nil
+		}
+	}
+	p.pos = start
+
+	switch left := left.(type) {
+	case *filterQuery:
+		return testExpr{left}, nil
+	case *functionExpr:
+		if left.fn.result == valueType {
+			return nil, p.errorf("%s() result must be compared", left.fn.name)
+		}
+		return testExpr{left}, nil
+	}
+	return nil, p.errorf("literal must be compared")
+}
+
+func (p *parser) paren() (logical, error) {
+	p.blank()
+	expr, err := p.logicalOr()
+	if err != nil {
+		return nil, err
+	}
+	p.blank()
+	return expr, p.expect(")")
+}
+
+func (p *parser) test() (logical, error) {
+	operand, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch operand := operand.(type) {
+	case *filterQuery:
+		return testExpr{operand}, nil
+	case *functionExpr:
+		if operand.fn.result == valueType {
+			return nil, p.errorf("%s() result must be compared", operand.fn.name)
+		}
+		return testExpr{operand}, nil
+	}
+	return nil, p.errorf("expected a query or a function")
+}
+
+func (p *parser) checkComparable(operand any) error {
+	switch operand := operand.(type) {
+	case *filterQuery:
+		if !operand.singular() {
+			return p.errorf("query in comparison must be singular")
+		}
+	case *functionExpr:
+		if operand.fn.result != valueType {
+			return p.errorf("%s() result can't be compared", operand.fn.name)
+		}
+	}
+	return nil
+}
+
+// operand parses a literal, a filter query or a function expression.
+func (p *parser) operand() (any, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments()
+		return &filterQuery{relative: c == '@', segments: segments}, err
+	case c == '\'' || c == '"':
+		s, err := p.string()
+		return literal{s}, err
+	case c == '-' || isDigit(rune(c)):
+		return p.number()
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for c := p.peek(); c >= 'a' && c <= 'z' || c == '_' || isDigit(rune(c)); c = p.peek() {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		if p.has("(") {
+			return p.function(name, start)
+		}
+		switch name {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		p.pos = start
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) number() (any, error) {
+	start := p.pos
+	p.consume("-")
+	switch {
+	case p.consume("0"):
+		if isDigit(rune(p.peek())) {
+			return nil, p.errorf("invalid number")
+		}
+	case isDigit(rune(p.peek())):
+		for isDigit(rune(p.peek())) {
+			p.pos++
+		}
+	default:
+		return nil, p.errorf("expected a digit")
+	}
+	if p.consume(".") {
+		if !isDigit(rune(p.peek())) {
+			return nil, p.errorf("expected a digit")
+		}
+		for isDigit(rune(p.peek())) {
+			p.pos++
+		}
+	}
+	if p.consume("e") || p.consume("E") {
+		if !p.consume("-") {
+			p.consume("+")
+		}
+		if !isDigit(rune(p.peek())) {
+			return nil, p.errorf("expected a digit")
+		}
+		for isDigit(rune(p.peek())) {
+			p.pos++
+		}
+	}
+	r, ok := numberOf(p.input[start:p.pos])
+	if !ok {
+		return nil, p.errorf("invalid number %s", p.input[start:p.pos])
+	}
+	return literal{r}, nil
+}
+
+func (p *parser) function(name string, start int) (any, error) {
+	fn, ok := functions[name]
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("unknown function %s()", name)
+	}
+	p.pos++ // (
+	expr := &functionExpr{fn: fn}
+	p.blank()
+	for i := 0; !p.consume(")"); i++ {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			p.blank()
+		}
+		if i >= len(fn.params) {
+			return nil, p.errorf("too many arguments to %s()", name)
+		}
+		arg, err := p.argument(fn.params[i])
+		if err != nil {
+			return nil, err
+		}
+		expr.args = append(expr.args, arg)
+		p.blank()
+	}
+	if len(expr.args) < len(fn.params) {
+		return nil, p.errorf("not enough arguments to %s()", name)
+	}
+	return expr, nil
+}
+
+func (p *parser) argument(param paramType) (any, error) {
+	if param == logicalType {
+		return p.logicalOr()
+	}
+	operand, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch operand := operand.(type) {
+	case literal:
+		if param == valueType {
+			return operand, nil
+		}
+	case *filterQuery:
+		if param == nodesType || operand.singular() {
+			return operand, nil
+		}
+	case *functionExpr:
+		if operand.fn.result == param || param == logicalType && operand.fn.result == nodesType {
+			return operand, nil
+		}
+	}
+	return nil, p.errorf("argument of the wrong type")
+}
+
+// singular reports whether the query selects at most one node.
+func (q *filterQuery) singular() bool {
+	for _, seg := range q.segments {
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		switch seg.selectors[0].(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
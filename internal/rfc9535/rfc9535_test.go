@@ -0,0 +1,254 @@
+package rfc9535_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/rfc9535"
+)
+
+// store is the example from RFC 9535, section 1.5.
+const store = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+func query(t *testing.T, input, path string) []string {
+	t.Helper()
+	root, err := jsonx.Parse([]byte(input))
+	require.NoError(t, err)
+	q, err := rfc9535.Parse(path)
+	require.NoError(t, err)
+	out := []string{}
+	for _, n := range q.Select(root) {
+		out = append(out, jsonx.Compact(n))
+	}
+	return out
+}
+
+func TestSelect_Store(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`$.store.book[*].author`, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`, `"J. R. R. Tolkien"`}},
+		{`$..author`, []string{`"Nigel Rees"`, `"Evelyn Waugh"`, `"Herman Melville"`, `"J. R. R. Tolkien"`}},
+		{`$.store..price`, []string{`8.95`, `12.99`, `8.99`, `22.99`, `399`}},
+		{`$..book[2].author`, []string{`"Herman Melville"`}},
+		{`$..book[2].publisher`, []string{}},
+		{`$..book[-1].title`, []string{`"The Lord of the Rings"`}},
+		{`$..book[0,1].title`, []string{`"Sayings of the Century"`, `"Sword of Honour"`}},
+		{`$..book[:2].title`, []string{`"Sayings of the Century"`, `"Sword of Honour"`}},
+		{`$..book[?@.isbn].title`, []string{`"Moby Dick"`, `"The Lord of the Rings"`}},
+		{`$..book[?@.price<10].title`, []string{`"Sayings of the Century"`, `"Moby Dick"`}},
+		{`$..book[?@.price < 10 && @.category == 'fiction'].title`, []string{`"Moby Dick"`}},
+		{`$..book[?!(@.price < 10)].price`, []string{`12.99`, `22.99`}},
+		{`$..book[?length(@.title) > 15].title`, []string{`"Sayings of the Century"`, `"The Lord of the Rings"`}},
+		{`$..book[?match(@.author, 'J.*')].author`, []string{`"J. R. R. Tolkien"`}},
+		{`$..book[?search(@.author, 'Mel')].author`, []string{`"Herman Melville"`}},
+		{`$.store[?@.color == "red"]`, []string{`{"color":"red","price":399}`}},
+		{`$.store.bicycle[?@ == 'red']`, []string{`"red"`}},
+		{`$.store[?count(@.*) == 2].price`, []string{`399`}},
+		{`$..book[?@.price == $.store.book[0].price].title`, []string{`"Sayings of the Century"`}},
+		{`$['store']["bicycle"].color`, []string{`"red"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, query(t, store, tt.path))
+		})
+	}
+}
+
+func TestSelect_Slices(t *testing.T) {
+	const input = `["a", "b", "c", "d", "e", "f", "g"]`
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`$[1:3]`, []string{`"b"`, `"c"`}},
+		{`$[5:]`, []string{`"f"`, `"g"`}},
+		{`$[1:5:2]`, []string{`"b"`, `"d"`}},
+		{`$[5:1:-2]`, []string{`"f"`, `"d"`}},
+		{`$[::-1]`, []string{`"g"`, `"f"`, `"e"`, `"d"`, `"c"`, `"b"`, `"a"`}},
+		{`$[-2:]`, []string{`"f"`, `"g"`}},
+		{`$[::0]`, []string{}},
+		{`$[100:]`, []string{}},
+		{`$[ 0 , -1 ]`, []string{`"a"`, `"g"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, query(t, input, tt.path))
+		})
+	}
+}
+
+func TestSelect_Filters(t *testing.T) {
+	const input = `{"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}], "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}, "e": "f"}`
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`$.a[?@.b == 'kilo']`, []string{`{"b":"kilo"}`}},
+		{`$.a[?(@.b == 'kilo')]`, []string{`{"b":"kilo"}`}},
+		{`$.a[?@>3.5]`, []string{`5`, `4`, `6`}},
+		{`$.a[?@.b]`, []string{`{"b":"j"}`, `{"b":"k"}`, `{"b":{}}`, `{"b":"kilo"}`}},
+		{`$[?@.*]`, []string{`[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`, `{"p":1,"q":2,"r":3,"s":5,"t":{"u":6}}`}},
+		{`$[?@[?@.b]]`, []string{`[3,5,1,2,4,6,{"b":"j"},{"b":"k"},{"b":{}},{"b":"kilo"}]`}},
+		{`$.o[?@<3, ?@<3]`, []string{`1`, `2`, `1`, `2`}},
+		{`$.a[?@<2 || @.b == "k"]`, []string{`1`, `{"b":"k"}`}},
+		{`$.a[?match(@.b, "[jk]")]`, []string{`{"b":"j"}`, `{"b":"k"}`}},
+		{`$.a[?search(@.b, "[jk]")]`, []string{`{"b":"j"}`, `{"b":"k"}`, `{"b":"kilo"}`}},
+		{`$.o[?@>1 && @<4]`, []string{`2`, `3`}},
+		{`$.o[?@.u || @.x]`, []string{`{"u":6}`}},
+		{`$.a[?@.b == $.x]`, []string{`3`, `5`, `1`, `2`, `4`, `6`}},
+		{`$.a[?@ == @]`, []string{`3`, `5`, `1`, `2`, `4`, `6`, `{"b":"j"}`, `{"b":"k"}`, `{"b":{}}`, `{"b":"kilo"}`}},
+		{`$.a[?@.b == {}]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if tt.want == nil {
+				_, err := rfc9535.Parse(tt.path)
+				assert.Error(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, query(t, input, tt.path))
+		})
+	}
+}
+
+func TestSelect_Comparisons(t *testing.T) {
+	const input = `{"obj": {"x": "y"}, "arr": [2, 3], "n": 1.0, "big": 10000000000000000000001}`
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`$[?$.absent1 == $.absent2]`, []string{`{"x":"y"}`, `[2,3]`, `1.0`, `10000000000000000000001`}},
+		{`$[?$.absent1 <= $.absent2]`, []string{`{"x":"y"}`, `[2,3]`, `1.0`, `10000000000000000000001`}},
+		{`$[?$.absent == 'g']`, []string{}},
+		{`$[?$.obj == $.obj]`, []string{`{"x":"y"}`, `[2,3]`, `1.0`, `10000000000000000000001`}},
+		{`$[?$.obj != $.arr]`, []string{`{"x":"y"}`, `[2,3]`, `1.0`, `10000000000000000000001`}},
+		{`$[?$.obj < $.obj]`, []string{}},
+		{`$[?@ == 1]`, []string{`1.0`}},
+		{`$[?@ == 1e22]`, []string{}},
+		{`$[?@ > 10000000000000000000000]`, []string{`10000000000000000000001`}},
+		{`$[?true == true]`, nil},
+		{`$[?@ == true]`, []string{}},
+		{`$[?@ == null]`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if tt.want == nil {
+				_, err := rfc9535.Parse(tt.path)
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.want, query(t, input, tt.path))
+		})
+	}
+}
+
+func TestSelect_Names(t *testing.T) {
+	const input = `{"a": {"b": 1}, "k'": 2, "☺": 3, "𝄞": 4, "0": 5, "a b": 6}`
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{`$.a.b`, []string{`1`}},
+		{`$['k\'']`, []string{`2`}},
+		{`$["k'"]`, []string{`2`}},
+		{`$.☺`, []string{`3`}},
+		{`$['☺']`, []string{`3`}},
+		{`$['𝄞']`, []string{`4`}},
+		{`$['0']`, []string{`5`}},
+		{`$[0]`, []string{}},
+		{`$['a b']`, []string{`6`}},
+		{`$..b`, []string{`1`}},
+		{`$..*`, []string{`{"b":1}`, `2`, `3`, `4`, `5`, `6`, `1`}},
+		{`$ .a [ 'b' ]`, []string{`1`}},
+		{`$`, []string{`{"a":{"b":1},"k'":2,"☺":3,"𝄞":4,"0":5,"a b":6}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, query(t, input, tt.path))
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		``,
+		`store`,
+		`$.`,
+		`$..`,
+		`$.1a`,
+		`$[`,
+		`$[]`,
+		`$['a]`,
+		`$['\x']`,
+		`$['\uD834']`,
+		`$[01]`,
+		`$[-0]`,
+		`$[9007199254740992]`,
+		`$[1:2:3:4]`,
+		`$[?@.a == 1 == 2]`,
+		`$[?1 == 1 &&]`,
+		`$[?@..a == 1]`,
+		`$[?@.* == 1]`,
+		`$[?@[0:1] == 1]`,
+		`$[?1]`,
+		`$[?length(@)]`,
+		`$[?length(@.*) == 1]`,
+		`$[?match(@.a) == true]`,
+		`$[?match(@.a, 'a') == true]`,
+		`$[?foo(@)]`,
+		`$[?(@.a]`,
+		`$.a b`,
+	}
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			_, err := rfc9535.Parse(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSelect_Collapsed(t *testing.T) {
+	root, err := jsonx.Parse([]byte(`{"a": {"b": [1, 2]}}`))
+	require.NoError(t, err)
+	root.CollapseRecursively()
+	q, err := rfc9535.Parse(`$.a.b[1]`)
+	require.NoError(t, err)
+	nodes := q.Select(root)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "2", nodes[0].Value)
+}
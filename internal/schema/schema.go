@@ -0,0 +1,215 @@
+// Package schema validates JSON documents against JSON Schema draft 2020-12 and draft-07.
+// Only local references are resolved, and format is an annotation, not an assertion.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/antonmedv/fx/internal/jsonpath"
+	"github.com/antonmedv/fx/internal/jsonx"
+)
+
+// rootURI is the base URI of a schema without $id.
+const rootURI = "fx:///schema.json"
+
+type Schema struct {
+	root      any
+	draft7    bool                      // Sibling keywords of $ref are ignored and items may be an array.
+	resources map[string]any            // Schemas with $id by their absolute URI.
+	anchors   map[string]any            // Schemas with $anchor by their absolute URI with the anchor as fragment.
+	regexps   map[string]*regexp.Regexp // Compiled pattern and patternProperties.
+}
+
+// Violation is a node not conforming to the schema.
+type Violation struct {
+	Path    []any
+	Node    *jsonx.Node
+	Message string
+}
+
+// Compile parses a schema and checks its references and patterns.
+func Compile(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var root any
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	s := &Schema{
+		root:      root,
+		resources: map[string]any{rootURI: root},
+		anchors:   map[string]any{},
+		regexps:   map[string]*regexp.Regexp{},
+	}
+	if obj, ok := root.(map[string]any); ok {
+		if dialect, ok := obj["$schema"].(string); ok {
+			s.draft7 = strings.Contains(dialect, "draft-07") || strings.Contains(dialect, "draft-06")
+		}
+	}
+
+	base, _ := url.Parse(rootURI)
+	var refs [][2]string
+	if err := s.walk(root, base, &refs); err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if _, _, err := s.resolve(ref[0], ref[1]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Keywords with subschemas: a schema, an array of schemas or an object of schemas.
+var (
+	schemaKeywords = []string{"additionalProperties", "propertyNames", "items", "additionalItems", "contains",
+		"not", "if", "then", "else", "unevaluatedItems", "unevaluatedProperties"}
+	arrayKeywords  = []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"}
+	objectKeywords = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas", "dependencies"}
+)
+
+// walk registers resources and anchors, compiles patterns and collects references with their base URIs.
+func (s *Schema) walk(schema any, base *url.URL, refs *[][2]string) error {
+	obj, ok := schema.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if id, ok := obj["$id"].(string); ok {
+		u, err := base.Parse(id)
+		if err != nil {
+			return fmt.Errorf("schema: invalid $id %q", id)
+		}
+		if strings.HasPrefix(id, "#") {
+			s.anchors[withoutFragment(base)+"#"+u.Fragment] = obj // Draft-07 anchors.
+		} else {
+			u.Fragment = ""
+			base = u
+			s.resources[u.String()] = obj
+		}
+	}
+	for _, key := range []string{"$anchor", "$dynamicAnchor"} {
+		if anchor, ok := obj[key].(string); ok {
+			s.anchors[withoutFragment(base)+"#"+anchor] = obj
+		}
+	}
+	for _, key := range []string{"$ref", "$dynamicRef", "$recursiveRef"} {
+		if ref, ok := obj[key].(string); ok {
+			*refs = append(*refs, [2]string{base.String(), ref})
+		}
+	}
+	if pattern, ok := obj["pattern"].(string); ok {
+		if err := s.compilePattern(pattern); err != nil {
+			return err
+		}
+	}
+	if patterns, ok := obj["patternProperties"].(map[string]any); ok {
+		for pattern := range patterns {
+			if err := s.compilePattern(pattern); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, key := range schemaKeywords {
+		if sub, ok := obj[key].(map[string]any); ok {
+			if err := s.walk(sub, base, refs); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range arrayKeywords {
+		if subs, ok := obj[key].([]any); ok {
+			for _, sub := range subs {
+				if err := s.walk(sub, base, refs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, key := range objectKeywords {
+		if subs, ok := obj[key].(map[string]any); ok {
+			for _, sub := range subs {
+				if err := s.walk(sub, base, refs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) compilePattern(pattern string) error {
+	if _, ok := s.regexps[pattern]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("schema: invalid pattern %q: %w", pattern, err)
+	}
+	s.regexps[pattern] = re
+	return nil
+}
+
+// resolve returns the schema a reference points to, and its base URI.
+func (s *Schema) resolve(base, ref string) (any, string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return nil, "", err
+	}
+	u, err := b.Parse(ref)
+	if err != nil {
+		return nil, "", fmt.Errorf("schema: invalid $ref %q", ref)
+	}
+	fragment := u.EscapedFragment()
+	doc := withoutFragment(u)
+	resource, ok := s.resources[doc]
+	if !ok {
+		return nil, "", fmt.Errorf("schema: can't resolve $ref %q, only local references are supported", ref)
+	}
+	if fragment == "" {
+		return resource, doc, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		if anchor, ok := s.anchors[doc+"#"+u.Fragment]; ok {
+			return anchor, doc, nil
+		}
+		return nil, "", fmt.Errorf("schema: can't resolve $ref %q", ref)
+	}
+	path, ok := jsonpath.ParseSchemaRef("#" + fragment)
+	if !ok {
+		return nil, "", fmt.Errorf("schema: invalid $ref %q", ref)
+	}
+	at := resource
+	for _, key := range path {
+		switch x := at.(type) {
+		case map[string]any:
+			at, ok = x[key.(string)]
+		case []any:
+			var i int
+			i, err = strconv.Atoi(key.(string))
+			ok = err == nil && i >= 0 && i < len(x)
+			if ok {
+				at = x[i]
+			}
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, "", fmt.Errorf("schema: can't resolve $ref %q", ref)
+		}
+	}
+	return at, doc, nil
+}
+
+func withoutFragment(u *url.URL) string {
+	c := *u
+	c.Fragment = ""
+	c.RawFragment = ""
+	return c.String()
+}
@@ -0,0 +1,187 @@
+package schema_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonpath"
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/schema"
+)
+
+func validate(t *testing.T, s, doc string) []string {
+	t.Helper()
+	compiled, err := schema.Compile([]byte(s))
+	require.NoError(t, err)
+	node, err := jsonx.Parse([]byte(doc))
+	require.NoError(t, err)
+	out := []string{}
+	for _, v := range compiled.Validate(node) {
+		out = append(out, jsonpath.Join(v.Path)+": "+v.Message)
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			schema: `{"type": "object", "properties": {"a": {"type": "integer"}}, "required": ["a"]}`,
+			doc:    `{"a": 1.0}`,
+			want:   []string{},
+		},
+		{
+			name:   "type",
+			schema: `{"properties": {"a": {"type": ["string", "null"]}, "b": {"type": "integer"}}}`,
+			doc:    `{"a": 1, "b": 1.5}`,
+			want:   []string{".a: expected string or null, got number", ".b: expected integer, got number"},
+		},
+		{
+			name:   "required and additionalProperties",
+			schema: `{"properties": {"a": true}, "required": ["a", "b"], "additionalProperties": false}`,
+			doc:    `{"a": 1, "c": 2}`,
+			want:   []string{`: missing required property "b"`, `.c: property "c" is not allowed`},
+		},
+		{
+			name:   "numbers",
+			schema: `{"items": {"minimum": 0, "exclusiveMaximum": 10, "multipleOf": 0.1}}`,
+			doc:    `[-1, 10, 0.3, 0.25]`,
+			want:   []string{"[0]: must be >= 0", "[1]: must be < 10", "[3]: must be a multiple of 0.1"},
+		},
+		{
+			name:   "strings",
+			schema: `{"items": {"minLength": 2, "maxLength": 3, "pattern": "^[a-zé]+$"}}`,
+			doc:    `["é", "abcd", "ab1", "éé"]`,
+			want: []string{
+				"[0]: must be at least 2 characters long",
+				"[1]: must be at most 3 characters long",
+				`[2]: must match pattern "^[a-zé]+$"`,
+			},
+		},
+		{
+			name:   "enum and const",
+			schema: `{"properties": {"a": {"enum": [1, "x", {"b": null}]}, "b": {"const": 2}}}`,
+			doc:    `{"a": {"b": null}, "b": 2.0}`,
+			want:   []string{},
+		},
+		{
+			name:   "enum mismatch",
+			schema: `{"enum": [1, "x"]}`,
+			doc:    `true`,
+			want:   []string{`: must be one of: 1, "x"`},
+		},
+		{
+			name:   "arrays",
+			schema: `{"prefixItems": [{"type": "string"}], "items": {"type": "number"}, "minItems": 4, "uniqueItems": true}`,
+			doc:    `["a", 1, "b", 1]`,
+			want:   []string{": items at 1 and 3 are equal", "[2]: expected number, got string"},
+		},
+		{
+			name:   "contains",
+			schema: `{"contains": {"type": "string"}, "maxContains": 1}`,
+			doc:    `["a", "b", 1]`,
+			want:   []string{": must contain at most 1 items matching contains"},
+		},
+		{
+			name:   "combinators",
+			schema: `{"properties": {"a": {"anyOf": [{"type": "string"}, {"type": "null"}]}, "b": {"oneOf": [{"type": "number"}, {"type": "integer"}]}, "c": {"not": {"type": "boolean"}}}}`,
+			doc:    `{"a": 1, "b": 1, "c": true}`,
+			want: []string{
+				".a: must match at least one schema in anyOf",
+				".b: must match exactly one schema in oneOf, but matches 2",
+				".c: must not match the schema in not",
+			},
+		},
+		{
+			name:   "if then else",
+			schema: `{"if": {"properties": {"kind": {"const": "a"}}}, "then": {"required": ["a"]}, "else": {"required": ["b"]}}`,
+			doc:    `[{"kind": "a"}, {"kind": "b"}]`,
+			want:   []string{},
+		},
+		{
+			name:   "refs",
+			schema: `{"$defs": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}}, "required": ["name"]}}, "$ref": "#/$defs/node"}`,
+			doc:    `{"name": "root", "children": [{"name": "a"}, {"children": [{}]}]}`,
+			want: []string{
+				`.children[1]: missing required property "name"`,
+				`.children[1].children[0]: missing required property "name"`,
+			},
+		},
+		{
+			name:   "anchors and ids",
+			schema: `{"$id": "https://example.com/root.json", "properties": {"a": {"$ref": "item.json"}, "b": {"$ref": "#positive"}}, "$defs": {"item": {"$id": "item.json", "type": "string"}, "positive": {"$anchor": "positive", "minimum": 1}}}`,
+			doc:    `{"a": 1, "b": 0}`,
+			want:   []string{".a: expected string, got number", ".b: must be >= 1"},
+		},
+		{
+			name:   "unevaluatedProperties",
+			schema: `{"allOf": [{"properties": {"a": true}}], "properties": {"b": true}, "unevaluatedProperties": false}`,
+			doc:    `{"a": 1, "b": 2, "c": 3}`,
+			want:   []string{`.c: property "c" is not allowed`},
+		},
+		{
+			name:   "dependencies and propertyNames",
+			schema: `{"dependentRequired": {"a": ["b"]}, "propertyNames": {"maxLength": 1}}`,
+			doc:    `{"a": 1, "long": 2}`,
+			want:   []string{`: property "a" requires property "b"`, `.long: invalid property name "long": must be at most 1 characters long`},
+		},
+		{
+			name:   "draft-07",
+			schema: `{"$schema": "http://json-schema.org/draft-07/schema#", "definitions": {"s": {"type": "string"}}, "properties": {"a": {"$ref": "#/definitions/s", "type": "number"}, "b": {"items": [{"type": "string"}], "additionalItems": false}}}`,
+			doc:    `{"a": "x", "b": ["x", 1]}`,
+			want:   []string{".b[1]: item is not allowed"},
+		},
+		{
+			name:   "false schema",
+			schema: `{"properties": {"a": false}}`,
+			doc:    `{"a": 1}`,
+			want:   []string{".a: no value is allowed here"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, validate(t, tt.schema, tt.doc))
+		})
+	}
+}
+
+func TestValidate_Node(t *testing.T) {
+	compiled, err := schema.Compile([]byte(`{"items": {"type": "string"}}`))
+	require.NoError(t, err)
+	node, err := jsonx.Parse([]byte(`["a", 1]`))
+	require.NoError(t, err)
+	violations := compiled.Validate(node)
+	require.Len(t, violations, 1)
+	require.Equal(t, "1", violations[0].Node.Value)
+	require.Equal(t, []any{1}, violations[0].Path)
+}
+
+func TestValidate_Recursive(t *testing.T) {
+	out := validate(t, `{"$ref": "#"}`, `1`)
+	require.Equal(t, []string{": schema is nested too deeply"}, out[:1])
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    string
+	}{
+		{`{"$ref": "other.json"}`, `schema: can't resolve $ref "other.json", only local references are supported`},
+		{`{"$ref": "#/$defs/missing"}`, `schema: can't resolve $ref "#/$defs/missing"`},
+		{`{"$ref": "#missing"}`, `schema: can't resolve $ref "#missing"`},
+		{`{"pattern": "("}`, "schema: invalid pattern \"(\": error parsing regexp: missing closing ): `(`"},
+		{`{`, "schema: unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			_, err := schema.Compile([]byte(tt.schema))
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
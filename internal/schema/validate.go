@@ -0,0 +1,636 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/utils"
+)
+
+// maxDepth limits nested schemas, so that recursive references without progress end.
+const maxDepth = 512
+
+// Validate returns the violations of the schema in a document, in document order.
+func (s *Schema) Validate(n *jsonx.Node) []Violation {
+	v := &validator{schema: s}
+	v.check(s.root, rootURI, n, nil)
+	order := map[*jsonx.Node]int{}
+	var number func(n *jsonx.Node)
+	number = func(n *jsonx.Node) {
+		order[n] = len(order)
+		for _, child := range n.Elements() {
+			number(child)
+		}
+	}
+	number(n)
+	sort.SliceStable(v.out, func(i, j int) bool {
+		return order[v.out[i].Node] < order[v.out[j].Node]
+	})
+	return v.out
+}
+
+type validator struct {
+	schema *Schema
+	out    []Violation
+	depth  int
+}
+
+// evaluated are the properties and items checked by a schema and its valid
+// subschemas, for unevaluatedProperties and unevaluatedItems.
+type evaluated struct {
+	props    map[string]bool
+	items    int          // Number of leading items.
+	allItems bool         // All items.
+	indexes  map[int]bool // Items matched by contains.
+}
+
+func (e *evaluated) prop(key string) {
+	if e.props == nil {
+		e.props = map[string]bool{}
+	}
+	e.props[key] = true
+}
+
+func (e *evaluated) merge(o evaluated) {
+	for key := range o.props {
+		e.prop(key)
+	}
+	e.items = max(e.items, o.items)
+	e.allItems = e.allItems || o.allItems
+	for i := range o.indexes {
+		if e.indexes == nil {
+			e.indexes = map[int]bool{}
+		}
+		e.indexes[i] = true
+	}
+}
+
+func (e *evaluated) item(i int) bool {
+	return e.allItems || i < e.items || e.indexes[i]
+}
+
+func (v *validator) fail(n *jsonx.Node, path []any, format string, args ...any) {
+	v.out = append(v.out, Violation{Path: path, Node: n, Message: fmt.Sprintf(format, args...)})
+}
+
+// try checks a schema without reporting its violations.
+func (v *validator) try(schema any, base string, n *jsonx.Node, path []any) (bool, evaluated, []Violation) {
+	sub := &validator{schema: v.schema, depth: v.depth}
+	ok, e := sub.check(schema, base, n, path)
+	return ok, e, sub.out
+}
+
+func (v *validator) check(schema any, base string, n *jsonx.Node, path []any) (bool, evaluated) {
+	var e evaluated
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			v.fail(n, path, "no value is allowed here")
+		}
+		return schema, e
+	case map[string]any:
+		v.depth++
+		defer func() { v.depth-- }()
+		if v.depth > maxDepth {
+			v.fail(n, path, "schema is nested too deeply")
+			return false, e
+		}
+		before := len(v.out)
+		v.keywords(schema, base, n, path, &e)
+		return len(v.out) == before, e
+	default:
+		return true, e
+	}
+}
+
+func (v *validator) keywords(s map[string]any, base string, n *jsonx.Node, path []any, e *evaluated) {
+	if id, ok := s["$id"].(string); ok && !strings.HasPrefix(id, "#") {
+		if b, err := url.Parse(base); err == nil {
+			if u, err := b.Parse(id); err == nil {
+				base = withoutFragment(u)
+			}
+		}
+	}
+
+	for _, key := range []string{"$ref", "$dynamicRef", "$recursiveRef"} {
+		ref, ok := s[key].(string)
+		if !ok {
+			continue
+		}
+		target, targetBase, err := v.schema.resolve(base, ref)
+		if err != nil {
+			v.fail(n, path, "%s", strings.TrimPrefix(err.Error(), "schema: "))
+			continue
+		}
+		_, sub := v.check(target, targetBase, n, path)
+		e.merge(sub)
+		if v.schema.draft7 {
+			return
+		}
+	}
+
+	if t, ok := s["type"]; ok {
+		v.checkType(t, n, path)
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		value := valueOf(n)
+		found := false
+		for _, x := range enum {
+			if equal(value, x) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values := make([]string, len(enum))
+			for i, x := range enum {
+				values[i] = stringify(x)
+			}
+			v.fail(n, path, "must be one of: %s", strings.Join(values, ", "))
+		}
+	}
+	if c, ok := s["const"]; ok && !equal(valueOf(n), c) {
+		v.fail(n, path, "must be equal to %s", stringify(c))
+	}
+
+	switch n.Kind {
+	case jsonx.Number:
+		v.number(s, n, path)
+	case jsonx.String:
+		v.string(s, n, path)
+	case jsonx.Array:
+		v.array(s, base, n, path, e)
+	case jsonx.Object:
+		v.object(s, base, n, path, e)
+	}
+
+	if all, ok := s["allOf"].([]any); ok {
+		for _, sub := range all {
+			_, se := v.check(sub, base, n, path)
+			e.merge(se)
+		}
+	}
+	if any, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range any {
+			if ok, se, _ := v.try(sub, base, n, path); ok {
+				matched = true
+				e.merge(se)
+			}
+		}
+		if !matched {
+			v.fail(n, path, "must match at least one schema in anyOf")
+		}
+	}
+	if one, ok := s["oneOf"].([]any); ok {
+		matched := 0
+		var matchedEvaluated evaluated
+		for _, sub := range one {
+			if ok, se, _ := v.try(sub, base, n, path); ok {
+				matched++
+				matchedEvaluated = se
+			}
+		}
+		if matched == 1 {
+			e.merge(matchedEvaluated)
+		} else {
+			v.fail(n, path, "must match exactly one schema in oneOf, but matches %d", matched)
+		}
+	}
+	if not, ok := s["not"]; ok {
+		if ok, _, _ := v.try(not, base, n, path); ok {
+			v.fail(n, path, "must not match the schema in not")
+		}
+	}
+	if cond, ok := s["if"]; ok {
+		if ok, se, _ := v.try(cond, base, n, path); ok {
+			e.merge(se)
+			if then, ok := s["then"]; ok {
+				_, se := v.check(then, base, n, path)
+				e.merge(se)
+			}
+		} else if els, ok := s["else"]; ok {
+			_, se := v.check(els, base, n, path)
+			e.merge(se)
+		}
+	}
+
+	switch n.Kind {
+	case jsonx.Array:
+		if unevaluated, ok := s["unevaluatedItems"]; ok {
+			for i, item := range n.Elements() {
+				if e.item(i) {
+					continue
+				}
+				if unevaluated == false {
+					v.fail(item, with(path, i), "item is not allowed")
+				} else {
+					v.check(unevaluated, base, item, with(path, i))
+				}
+			}
+			e.allItems = true
+		}
+	case jsonx.Object:
+		if unevaluated, ok := s["unevaluatedProperties"]; ok {
+			keys, values := entries(n)
+			for i, key := range keys {
+				if e.props[key] {
+					continue
+				}
+				if unevaluated == false {
+					v.fail(values[i], with(path, key), "property %q is not allowed", key)
+				} else {
+					v.check(unevaluated, base, values[i], with(path, key))
+				}
+				e.prop(key)
+			}
+		}
+	}
+}
+
+func (v *validator) checkType(t any, n *jsonx.Node, path []any) {
+	var types []string
+	switch t := t.(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	actual := typeOf(n)
+	for _, expected := range types {
+		if expected == actual || expected == "number" && actual == "integer" {
+			return
+		}
+		if expected == "integer" && actual == "number" {
+			if r, ok := ratOf(n.Value); ok && r.IsInt() {
+				return
+			}
+		}
+	}
+	if actual == "integer" {
+		actual = "number"
+	}
+	v.fail(n, path, "expected %s, got %s", strings.Join(types, " or "), actual)
+}
+
+func (v *validator) number(s map[string]any, n *jsonx.Node, path []any) {
+	x, ok := ratOf(n.Value)
+	if !ok {
+		return
+	}
+	bounds := []struct {
+		keyword string
+		op      string
+		ok      func(cmp int) bool
+	}{
+		{"minimum", ">=", func(cmp int) bool { return cmp >= 0 }},
+		{"exclusiveMinimum", ">", func(cmp int) bool { return cmp > 0 }},
+		{"maximum", "<=", func(cmp int) bool { return cmp <= 0 }},
+		{"exclusiveMaximum", "<", func(cmp int) bool { return cmp < 0 }},
+	}
+	for _, b := range bounds {
+		limit, ok := s[b.keyword].(json.Number)
+		if !ok {
+			continue
+		}
+		if r, ok := ratOf(limit.String()); ok && !b.ok(x.Cmp(r)) {
+			v.fail(n, path, "must be %s %s", b.op, limit)
+		}
+	}
+	if m, ok := s["multipleOf"].(json.Number); ok {
+		if r, ok := ratOf(m.String()); ok && r.Sign() > 0 && !new(big.Rat).Quo(x, r).IsInt() {
+			v.fail(n, path, "must be a multiple of %s", m)
+		}
+	}
+}
+
+func (v *validator) string(s map[string]any, n *jsonx.Node, path []any) {
+	str, err := utils.Unquote(n.Value)
+	if err != nil {
+		return
+	}
+	length := utf8.RuneCountInString(str)
+	if min, ok := intOf(s["minLength"]); ok && length < min {
+		v.fail(n, path, "must be at least %d characters long", min)
+	}
+	if max, ok := intOf(s["maxLength"]); ok && length > max {
+		v.fail(n, path, "must be at most %d characters long", max)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re := v.schema.regexps[pattern]; re != nil && !re.MatchString(str) {
+			v.fail(n, path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *validator) array(s map[string]any, base string, n *jsonx.Node, path []any, e *evaluated) {
+	items := n.Elements()
+
+	prefix, isTuple := s["prefixItems"].([]any)
+	rest, hasRest := s["items"]
+	if tuple, ok := rest.([]any); ok {
+		// Draft-07 items as an array, the rest is checked by additionalItems.
+		prefix, isTuple = tuple, true
+		rest, hasRest = s["additionalItems"]
+	}
+	if isTuple {
+		for i := 0; i < len(prefix) && i < len(items); i++ {
+			v.check(prefix[i], base, items[i], with(path, i))
+		}
+		e.items = max(e.items, min(len(prefix), len(items)))
+	}
+	if hasRest {
+		for i := len(prefix); i < len(items); i++ {
+			if rest == false {
+				v.fail(items[i], with(path, i), "item is not allowed")
+			} else {
+				v.check(rest, base, items[i], with(path, i))
+			}
+		}
+		e.allItems = true
+	}
+
+	if contains, ok := s["contains"]; ok {
+		matched := 0
+		for i, item := range items {
+			if ok, _, _ := v.try(contains, base, item, with(path, i)); ok {
+				matched++
+				if e.indexes == nil {
+					e.indexes = map[int]bool{}
+				}
+				e.indexes[i] = true
+			}
+		}
+		minContains, ok := intOf(s["minContains"])
+		if !ok {
+			minContains = 1
+		}
+		if matched < minContains {
+			if minContains == 1 {
+				v.fail(n, path, "must contain an item matching contains")
+			} else {
+				v.fail(n, path, "must contain at least %d items matching contains", minContains)
+			}
+		}
+		if maxContains, ok := intOf(s["maxContains"]); ok && matched > maxContains {
+			v.fail(n, path, "must contain at most %d items matching contains", maxContains)
+		}
+	}
+
+	if min, ok := intOf(s["minItems"]); ok && len(items) < min {
+		v.fail(n, path, "must have at least %d items", min)
+	}
+	if max, ok := intOf(s["maxItems"]); ok && len(items) > max {
+		v.fail(n, path, "must have at most %d items", max)
+	}
+	if s["uniqueItems"] == true {
+		values := make([]any, len(items))
+		for i, item := range items {
+			values[i] = valueOf(item)
+		}
+	unique:
+		for i := range values {
+			for j := i + 1; j < len(values); j++ {
+				if equal(values[i], values[j]) {
+					v.fail(n, path, "items at %d and %d are equal", i, j)
+					break unique
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) object(s map[string]any, base string, n *jsonx.Node, path []any, e *evaluated) {
+	keys, values := entries(n)
+	index := make(map[string]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	patterns, _ := s["patternProperties"].(map[string]any)
+	additional, hasAdditional := s["additionalProperties"]
+	for i, key := range keys {
+		known := false
+		if sub, ok := properties[key]; ok {
+			v.check(sub, base, values[i], with(path, key))
+			known = true
+		}
+		for pattern, sub := range patterns {
+			if re := v.schema.regexps[pattern]; re != nil && re.MatchString(key) {
+				v.check(sub, base, values[i], with(path, key))
+				known = true
+			}
+		}
+		if !known && hasAdditional {
+			if additional == false {
+				v.fail(values[i], with(path, key), "property %q is not allowed", key)
+			} else {
+				v.check(additional, base, values[i], with(path, key))
+			}
+			known = true
+		}
+		if known {
+			e.prop(key)
+		}
+	}
+
+	if names, ok := s["propertyNames"]; ok {
+		for i, key := range keys {
+			name := &jsonx.Node{Kind: jsonx.String, Value: strconv.Quote(key)}
+			if ok, _, out := v.try(names, base, name, with(path, key)); !ok {
+				v.fail(values[i], with(path, key), "invalid property name %q: %s", key, out[0].Message)
+			}
+		}
+	}
+
+	if required, ok := s["required"].([]any); ok {
+		for _, r := range required {
+			if key, ok := r.(string); ok {
+				if _, ok := index[key]; !ok {
+					v.fail(n, path, "missing required property %q", key)
+				}
+			}
+		}
+	}
+
+	dependentRequired, _ := s["dependentRequired"].(map[string]any)
+	dependentSchemas, _ := s["dependentSchemas"].(map[string]any)
+	if dependencies, ok := s["dependencies"].(map[string]any); ok {
+		for key, dep := range dependencies {
+			if _, ok := dep.([]any); ok {
+				dependentRequired = merged(dependentRequired, key, dep)
+			} else {
+				dependentSchemas = merged(dependentSchemas, key, dep)
+			}
+		}
+	}
+	for _, key := range keys {
+		if deps, ok := dependentRequired[key].([]any); ok {
+			for _, d := range deps {
+				if dep, ok := d.(string); ok {
+					if _, ok := index[dep]; !ok {
+						v.fail(n, path, "property %q requires property %q", key, dep)
+					}
+				}
+			}
+		}
+		if sub, ok := dependentSchemas[key]; ok {
+			_, se := v.check(sub, base, n, path)
+			e.merge(se)
+		}
+	}
+
+	if min, ok := intOf(s["minProperties"]); ok && len(keys) < min {
+		v.fail(n, path, "must have at least %d properties", min)
+	}
+	if max, ok := intOf(s["maxProperties"]); ok && len(keys) > max {
+		v.fail(n, path, "must have at most %d properties", max)
+	}
+}
+
+func merged(m map[string]any, key string, value any) map[string]any {
+	out := make(map[string]any, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	out[key] = value
+	return out
+}
+
+func typeOf(n *jsonx.Node) string {
+	switch n.Kind {
+	case jsonx.Null:
+		return "null"
+	case jsonx.Bool:
+		return "boolean"
+	case jsonx.Number:
+		if r, ok := ratOf(n.Value); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case jsonx.String:
+		return "string"
+	case jsonx.Object:
+		return "object"
+	case jsonx.Array:
+		return "array"
+	default:
+		return "invalid JSON"
+	}
+}
+
+// entries returns the unquoted keys and the values of an object. Of duplicate keys,
+// the last one wins, like in JSON.parse.
+func entries(n *jsonx.Node) ([]string, []*jsonx.Node) {
+	var keys []string
+	var values []*jsonx.Node
+	seen := map[string]int{}
+	for _, child := range n.Elements() {
+		key, err := utils.Unquote(child.Key)
+		if err != nil {
+			key = child.Key
+		}
+		if i, ok := seen[key]; ok {
+			values[i] = child
+			continue
+		}
+		seen[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, child)
+	}
+	return keys, values
+}
+
+// valueOf decodes a node like a schema, with json.Number for numbers.
+func valueOf(n *jsonx.Node) any {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(jsonx.Compact(n))))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return jsonx.Compact(n)
+	}
+	return value
+}
+
+// equal compares decoded values, numbers by value.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, xOk := ratOf(a.String())
+		y, yOk := ratOf(b.String())
+		return xOk && yOk && x.Cmp(y) == 0
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, x := range a {
+			y, ok := b[key]
+			if !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func stringify(x any) string {
+	b, err := json.Marshal(x)
+	if err != nil {
+		return fmt.Sprint(x)
+	}
+	return string(b)
+}
+
+func ratOf(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(s)
+}
+
+func intOf(x any) (int, bool) {
+	n, ok := x.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	r, ok := ratOf(n.String())
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return int(r.Num().Int64()), true
+}
+
+func with(path []any, part any) []any {
+	out := make([]any, len(path), len(path)+1)
+	copy(out, path)
+	return append(out, part)
+}
@@ -28,6 +28,9 @@ type Theme struct {
 	Ref        Color
 	LineNumber Color
 	Error      Color
+	Added      Color
+	Removed    Color
+	Changed    Color
 }
 
 type Color func(s string) string
@@ -67,20 +70,24 @@ func init() {
 	}
 	sort.Strings(themeNames)
 
-	themeId, ok := os.LookupEnv("FX_THEME")
-	if !ok {
-		themeId = "1"
-	}
+	_ = Set("1")
+}
 
-	CurrentTheme, ok = themes[themeId]
+// Names returns the sorted names of all themes.
+func Names() []string {
+	return themeNames
+}
+
+// Set changes the current theme. Terminals without colors always use the theme "0".
+func Set(name string) error {
+	t, ok := themes[name]
 	if !ok {
-		_, _ = fmt.Fprintf(os.Stderr, "fx: unknown theme %q, available themes: %v\n", themeId, themeNames)
-		os.Exit(1)
+		return fmt.Errorf("unknown theme %q, available themes: %v", name, themeNames)
 	}
-
 	if TermOutput.ColorProfile() == termenv.Ascii {
-		CurrentTheme = themes["0"]
+		t = themes["0"]
 	}
+	CurrentTheme = t
 
 	Colon = CurrentTheme.Syntax(": ")
 	ColonPreview = CurrentTheme.Preview(":")
@@ -90,6 +97,7 @@ func init() {
 	Dot3 = CurrentTheme.Preview("…")
 	CloseCurlyBracket = CurrentTheme.Syntax("}")
 	CloseSquareBracket = CurrentTheme.Syntax("]")
+	return nil
 }
 
 var (
@@ -106,6 +114,9 @@ var (
 	defaultSize       = toColor(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render)
 	defaultLineNumber = toColor(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render)
 	defaultError      = toColor(lipgloss.NewStyle().Background(lipgloss.Color("196")).Foreground(lipgloss.Color("255")).Render)
+	defaultAdded      = fg("2")
+	defaultRemoved    = fg("1")
+	defaultChanged    = fg("3")
 )
 
 var (
@@ -134,6 +145,9 @@ var NoColor = Theme{
 	Ref:        noColor,
 	LineNumber: defaultLineNumber,
 	Error:      defaultError,
+	Added:      noColor,
+	Removed:    noColor,
+	Changed:    noColor,
 }
 
 var themes = map[string]Theme{
@@ -153,6 +167,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("2"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"2": {
 		Cursor:     defaultCursor,
@@ -169,6 +186,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("4"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"3": {
 		Cursor:     defaultCursor,
@@ -185,6 +205,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("11"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"4": {
 		Cursor:     defaultCursor,
@@ -201,6 +224,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("#00BBF9"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"5": {
 		Cursor:     defaultCursor,
@@ -217,6 +243,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("#f4d35e"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"6": {
 		Cursor:     defaultCursor,
@@ -233,6 +262,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("#6BCB77"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"7": {
 		Cursor:     defaultCursor,
@@ -249,6 +281,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("213"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"8": {
 		Cursor:     defaultCursor,
@@ -265,6 +300,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("195"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"9": {
 		Cursor:     defaultCursor,
@@ -281,6 +319,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("49"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"🔵": {
 		Cursor: toColor(lipgloss.NewStyle().
@@ -300,6 +341,9 @@ var themes = map[string]Theme{
 		Ref:        underline,
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"🥝": {
 		Cursor:     defaultCursor,
@@ -316,6 +360,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("82"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"🔥": {
 		Cursor:     defaultCursor,
@@ -332,6 +379,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("214"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 	"🟣": {
 		Cursor:     defaultCursor,
@@ -348,6 +398,9 @@ var themes = map[string]Theme{
 		Ref:        underlineFg("183"),
 		LineNumber: defaultLineNumber,
 		Error:      defaultError,
+		Added:      defaultAdded,
+		Removed:    defaultRemoved,
+		Changed:    defaultChanged,
 	},
 }
 
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data.json")
+
+	require.NoError(t, WriteFileAtomic(name, []byte("1")))
+	require.NoError(t, os.Chmod(name, 0600))
+	require.NoError(t, WriteFileAtomic(name, []byte("2")))
+
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(b))
+
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file is left behind")
+}
+
+func TestWriteFileAtomic_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data.json")
+	link := filepath.Join(dir, "link.json")
+	require.NoError(t, os.WriteFile(name, []byte("1"), 0644))
+	require.NoError(t, os.Symlink(name, link))
+
+	require.NoError(t, WriteFileAtomic(link, []byte("2")))
+
+	b, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(b))
+
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+}
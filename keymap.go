@@ -1,6 +1,16 @@
 package main
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+
+	"github.com/antonmedv/fx/internal/config"
+)
 
 type KeyMap struct {
 	Up                  key.Binding `category:"Navigation"`
@@ -27,6 +37,13 @@ type KeyMap struct {
 	GotoRef             key.Binding `category:"Search"`
 	Yank                key.Binding `category:"Actions"`
 	Delete              key.Binding `category:"Actions"`
+	EditValue           key.Binding `category:"Actions"`
+	RenameKey           key.Binding `category:"Actions"`
+	Insert              key.Binding `category:"Actions"`
+	Duplicate           key.Binding `category:"Actions"`
+	Undo                key.Binding `category:"Actions"`
+	Redo                key.Binding `category:"Actions"`
+	Query               key.Binding `category:"Actions"`
 	Preview             key.Binding `category:"Actions"`
 	Print               key.Binding `category:"Actions"`
 	Open                key.Binding `category:"Actions"`
@@ -38,6 +55,12 @@ type KeyMap struct {
 	CommandLine         key.Binding `category:"Other"`
 	Quit                key.Binding `category:"Other"`
 	Suspend             key.Binding `category:"Other"`
+	YankValue           key.Binding `category:"Yank"`
+	YankPath            key.Binding `category:"Yank"`
+	YankKey             key.Binding `category:"Yank"`
+	YankKeyValue        key.Binding `category:"Yank"`
+	ShowSizes           key.Binding `category:"Show"`
+	ShowLineNumbers     key.Binding `category:"Show"`
 }
 
 var keyMap KeyMap
@@ -148,6 +171,34 @@ func init() {
 			key.WithKeys("d"),
 			key.WithHelp("", "delete node"),
 		),
+		EditValue: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("", "edit value"),
+		),
+		RenameKey: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("", "rename key"),
+		),
+		Insert: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("", "insert after"),
+		),
+		Duplicate: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("", "duplicate node"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("", "redo"),
+		),
+		Query: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp("", "live query"),
+		),
 		CommandLine: key.NewBinding(
 			key.WithKeys(":"),
 			key.WithHelp("", "open command line"),
@@ -158,11 +209,11 @@ func init() {
 		),
 		SearchNext: key.NewBinding(
 			key.WithKeys("n"),
-			key.WithHelp("", "next search result"),
+			key.WithHelp("", "next search result or schema error"),
 		),
 		SearchPrev: key.NewBinding(
 			key.WithKeys("N"),
-			key.WithHelp("", "prev search result"),
+			key.WithHelp("", "prev search result or schema error"),
 		),
 		Preview: key.NewBinding(
 			key.WithKeys("p"),
@@ -184,17 +235,136 @@ func init() {
 			key.WithKeys("]"),
 			key.WithHelp("", "go forward"),
 		),
+		YankValue: key.NewBinding(
+			key.WithKeys("y", "v"),
+			key.WithHelp("", "value"),
+		),
+		YankPath: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("", "path"),
+		),
+		YankKey: key.NewBinding(
+			key.WithKeys("k"),
+			key.WithHelp("", "key"),
+		),
+		YankKeyValue: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("", "key+value"),
+		),
+		ShowSizes: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("", "sizes"),
+		),
+		ShowLineNumbers: key.NewBinding(
+			key.WithKeys("l"),
+			key.WithHelp("", "line numbers"),
+		),
 	}
 }
 
 var (
-	yankValueY      = key.NewBinding(key.WithKeys("y"))
-	yankValueV      = key.NewBinding(key.WithKeys("v"))
-	yankKey         = key.NewBinding(key.WithKeys("k"))
-	yankPath        = key.NewBinding(key.WithKeys("p"))
-	yankKeyValue    = key.NewBinding(key.WithKeys("b"))
-	arrowUp         = key.NewBinding(key.WithKeys("up"))
-	arrowDown       = key.NewBinding(key.WithKeys("down"))
-	showSizes       = key.NewBinding(key.WithKeys("s"))
-	showLineNumbers = key.NewBinding(key.WithKeys("l"))
+	arrowUp   = key.NewBinding(key.WithKeys("up"))
+	arrowDown = key.NewBinding(key.WithKeys("down"))
 )
+
+// loadKeyMap rebinds actions of km from the keys section of the config,
+// which maps action names to a key or a list of keys:
+//
+//	[keys]
+//	GotoRef = "ctrl+]"
+//	yank_path = ["p", "P"]
+//	Suspend = []  # unbind
+func loadKeyMap(km *KeyMap, bindings map[string]config.Setting[any]) []error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(bindings)) {
+		setting := bindings[name]
+		binding := km.binding(name)
+		if binding == nil {
+			errs = append(errs, fmt.Errorf("%s: unknown action %q", setting.Source, name))
+			continue
+		}
+		keys, ok := keysOf(setting.Value)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s: expected a key or a list of keys", setting.Source, name))
+			continue
+		}
+		binding.SetKeys(keys...)
+		binding.SetEnabled(len(keys) > 0)
+	}
+	for _, err := range km.conflicts() {
+		errs = append(errs, fmt.Errorf("keys: %w", err))
+	}
+	return errs
+}
+
+func keysOf(v any) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, true
+	case []any:
+		keys := make([]string, 0, len(v))
+		for _, k := range v {
+			s, ok := k.(string)
+			if !ok {
+				return nil, false
+			}
+			keys = append(keys, s)
+		}
+		return keys, true
+	}
+	return nil, false
+}
+
+// binding finds an action by its field name, ignoring case, underscores and dashes.
+func (km *KeyMap) binding(name string) *key.Binding {
+	normalize := strings.NewReplacer("_", "", "-", "")
+	name = normalize.Replace(name)
+	v := reflect.ValueOf(km).Elem()
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		if field.Tag.Get("category") != "" && strings.EqualFold(field.Name, name) {
+			return v.FieldByIndex(field.Index).Addr().Interface().(*key.Binding)
+		}
+	}
+	return nil
+}
+
+// conflicts reports keys bound to several actions. The yank and show
+// selector menus have keys of their own, so they are checked separately.
+func (km *KeyMap) conflicts() []error {
+	var errs []error
+	seen := map[string]string{}
+	v := reflect.ValueOf(*km)
+	for _, field := range reflect.VisibleFields(v.Type()) {
+		category := field.Tag.Get("category")
+		if category == "" {
+			continue
+		}
+		scope := ""
+		if category == "Yank" || category == "Show" {
+			scope = category
+		}
+		binding := v.FieldByIndex(field.Index).Interface().(key.Binding)
+		if !binding.Enabled() {
+			continue
+		}
+		for _, k := range binding.Keys() {
+			if other, ok := seen[scope+" "+k]; ok {
+				errs = append(errs, fmt.Errorf("key %q is bound to both %s and %s", k, other, field.Name))
+				continue
+			}
+			seen[scope+" "+k] = field.Name
+		}
+	}
+	return errs
+}
+
+// menu renders bindings as "(y)value  (p)path".
+func menu(bindings ...key.Binding) string {
+	var items []string
+	for _, b := range bindings {
+		if b.Enabled() {
+			items = append(items, fmt.Sprintf("(%s)%s", b.Keys()[0], b.Help().Desc))
+		}
+	}
+	return strings.Join(items, "  ")
+}
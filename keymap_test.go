@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/config"
+)
+
+func keysConfig(t *testing.T, content string) map[string]config.Setting[any] {
+	cfg := config.New()
+	require.NoError(t, cfg.Parse([]byte(content), "fx.toml"))
+	return cfg.Keys
+}
+
+func TestKeyMap_NoConflicts(t *testing.T) {
+	km := keyMap
+	assert.Empty(t, km.conflicts())
+}
+
+func TestLoadKeyMap(t *testing.T) {
+	km := keyMap
+	keys := keysConfig(t, `
+[keys]
+GotoRef = "ctrl+]"
+yank_path = ["P", "x"]
+Suspend = []
+`)
+	require.Empty(t, loadKeyMap(&km, keys))
+
+	assert.Equal(t, []string{"ctrl+]"}, km.GotoRef.Keys())
+	assert.Equal(t, []string{"P", "x"}, km.YankPath.Keys())
+	assert.False(t, km.Suspend.Enabled())
+	assert.False(t, key.Matches(tea.KeyMsg{Type: tea.KeyCtrlZ}, km.Suspend))
+	assert.Equal(t, []string{"ctrl+g"}, keyMap.GotoRef.Keys(), "defaults are not changed")
+}
+
+func TestLoadKeyMap_Errors(t *testing.T) {
+	km := keyMap
+	keys := keysConfig(t, `
+[keys]
+Bogus = "x"
+Up = 1
+Down = "k"
+`)
+	errs := loadKeyMap(&km, keys)
+	require.Len(t, errs, 3)
+	assert.EqualError(t, errs[0], `fx.toml: unknown action "Bogus"`)
+	assert.EqualError(t, errs[1], `fx.toml: Up: expected a key or a list of keys`)
+	assert.EqualError(t, errs[2], `keys: key "k" is bound to both Up and Down`)
+}
+
+func TestMenu(t *testing.T) {
+	km := keyMap
+	km.YankKey.SetEnabled(false)
+	assert.Equal(t, "(y)value  (p)path  (b)key+value", menu(km.YankValue, km.YankPath, km.YankKey, km.YankKeyValue))
+}
+
+func TestEditorCommand(t *testing.T) {
+	assert.Equal(t, []string{"vim", "+3", "a.json"}, editorCommand("vim", "a.json", 3))
+	assert.Equal(t, []string{"vim", "a.json"}, editorCommand("vim", "a.json", 0))
+	assert.Equal(t, []string{"nano", "-w", "a.json"}, editorCommand("nano -w", "a.json", 3))
+	assert.Equal(t, []string{"code", "--goto", "a.json:3"}, editorCommand("code --goto {file}:{line}", "a.json", 3))
+}
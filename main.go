@@ -12,8 +12,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime/pprof"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/antonmedv/clipboard"
 	"github.com/charmbracelet/bubbles/key"
@@ -25,23 +28,49 @@ import (
 	"github.com/mattn/go-isatty"
 
 	"github.com/antonmedv/fx/internal/complete"
+	"github.com/antonmedv/fx/internal/compress"
+	"github.com/antonmedv/fx/internal/config"
+	"github.com/antonmedv/fx/internal/diff"
 	"github.com/antonmedv/fx/internal/engine"
 	"github.com/antonmedv/fx/internal/fuzzy"
+	"github.com/antonmedv/fx/internal/ident"
 	"github.com/antonmedv/fx/internal/jsonpath"
 	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/rfc9535"
+	"github.com/antonmedv/fx/internal/schema"
 	"github.com/antonmedv/fx/internal/theme"
 	"github.com/antonmedv/fx/internal/toml"
 	"github.com/antonmedv/fx/internal/utils"
 )
 
 var (
-	flagYaml     bool
-	flagToml     bool
-	flagRaw      bool
-	flagSlurp    bool
-	flagComp     bool
-	flagStrict   bool
-	flagNoInline bool
+	flagYaml          bool
+	flagToml          bool
+	flagCsv           bool
+	flagTsv           bool
+	flagXml           bool
+	flagNoHeader      bool
+	flagInferTypes    bool
+	flagDelimiter     string
+	flagTo            string
+	flagRaw           bool
+	flagSlurp         bool
+	flagReduce        *engine.Reduce
+	flagReduceEvery   int
+	flagParallel      int
+	flagUnordered     bool
+	flagWarnPrecision bool
+	flagJSONPath      *rfc9535.Query
+	flagJQ            string
+	flagPatch         string
+	flagSchema        *schema.Schema
+	flagInfer         string
+	flagFlatten       bool
+	flagUnflatten     bool
+	flagComp          bool
+	flagStrict        bool
+	flagNoInline      bool
+	flagPrintConfig   bool
 )
 
 var flags = []string{
@@ -52,14 +81,57 @@ var flags = []string{
 	"--version",
 	"--yaml",
 	"--toml",
+	"--csv",
+	"--tsv",
+	"--xml",
+	"--no-header",
+	"--infer-types",
+	"--delimiter",
+	"--to",
 	"--strict",
 	"--no-inline",
+	"--print-config",
+	"--arg",
+	"--argjson",
+	"--rawfile",
+	"--slurpfile",
+	"--reduce",
+	"--reduce-every",
+	"--parallel",
+	"--unordered",
+	"--warn-precision",
+	"--jsonpath",
+	"--jq",
+	"--patch",
+	"--schema",
+	"--infer",
+	"--flatten",
+	"--unflatten",
+}
+
+// flagOperands is the number of values following flags which take them.
+var flagOperands = map[string]int{
+	"--delimiter":    1,
+	"--to":           1,
+	"--arg":          2,
+	"--argjson":      2,
+	"--rawfile":      2,
+	"--slurpfile":    2,
+	"--reduce":       2,
+	"--reduce-every": 1,
+	"--parallel":     1,
+	"--jsonpath":     1,
+	"--jq":           1,
+	"--patch":        1,
+	"--schema":       1,
+	"--infer":        1,
 }
 
 func init() {
 	for _, name := range flags {
-		complete.Flags = append(complete.Flags, complete.Reply{name, name, "flag"})
+		complete.Flags = append(complete.Flags, complete.Reply{Display: name, Value: name, Type: "flag"})
 	}
+	complete.FlagOperands = flagOperands
 }
 
 func main() {
@@ -88,7 +160,8 @@ func main() {
 	}
 
 	var args []string
-	for _, arg := range os.Args[1:] {
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
 		if strings.HasPrefix(arg, "--comp") {
 			flagComp = true
 			continue
@@ -110,6 +183,126 @@ func main() {
 			flagYaml = true
 		case "--toml":
 			flagToml = true
+		case "--csv":
+			flagCsv = true
+		case "--tsv":
+			flagTsv = true
+		case "--xml":
+			flagXml = true
+		case "--no-header":
+			flagNoHeader = true
+		case "--infer-types":
+			flagInferTypes = true
+		case "--delimiter":
+			if i+1 >= len(os.Args) {
+				println("Error: --delimiter requires a value")
+				os.Exit(1)
+			}
+			i++
+			flagDelimiter = os.Args[i]
+		case "--to":
+			if i+1 >= len(os.Args) {
+				println("Error: --to requires a value")
+				os.Exit(1)
+			}
+			i++
+			flagTo = os.Args[i]
+		case "--arg", "--argjson", "--rawfile", "--slurpfile":
+			if i+2 >= len(os.Args) {
+				println("Error: " + arg + " requires a name and a value")
+				os.Exit(1)
+			}
+			v, err := engine.NewVar(arg, os.Args[i+1], os.Args[i+2])
+			if err != nil {
+				println("Error: " + err.Error())
+				os.Exit(1)
+			}
+			engine.Vars = append(engine.Vars, v)
+			i += 2
+		case "--reduce":
+			if i+2 >= len(os.Args) {
+				println("Error: --reduce requires an initial value and a function")
+				os.Exit(1)
+			}
+			flagReduce = &engine.Reduce{Init: os.Args[i+1], Fn: os.Args[i+2]}
+			i += 2
+		case "--reduce-every":
+			if i+1 >= len(os.Args) {
+				println("Error: --reduce-every requires a value")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(os.Args[i])
+			if err != nil || n <= 0 {
+				println("Error: --reduce-every must be a positive number")
+				os.Exit(1)
+			}
+			flagReduceEvery = n
+		case "--parallel":
+			if i+1 >= len(os.Args) {
+				println("Error: --parallel requires a value")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.Atoi(os.Args[i])
+			if err != nil || n <= 0 {
+				println("Error: --parallel must be a positive number")
+				os.Exit(1)
+			}
+			flagParallel = n
+		case "--jsonpath":
+			if i+1 >= len(os.Args) {
+				println("Error: --jsonpath requires a query")
+				os.Exit(1)
+			}
+			i++
+			q, err := rfc9535.Parse(os.Args[i])
+			if err != nil {
+				println("Error: " + err.Error())
+				os.Exit(1)
+			}
+			flagJSONPath = q
+		case "--jq":
+			if i+1 >= len(os.Args) {
+				println("Error: --jq requires a filter")
+				os.Exit(1)
+			}
+			i++
+			flagJQ = os.Args[i]
+		case "--patch":
+			if i+1 >= len(os.Args) {
+				println("Error: --patch requires a file")
+				os.Exit(1)
+			}
+			i++
+			flagPatch = os.Args[i]
+		case "--schema":
+			if i+1 >= len(os.Args) {
+				println("Error: --schema requires a file")
+				os.Exit(1)
+			}
+			i++
+			s, err := loadSchema(os.Args[i])
+			if err != nil {
+				println("Error: --schema " + os.Args[i] + ": " + err.Error())
+				os.Exit(1)
+			}
+			flagSchema = s
+		case "--infer":
+			if i+1 >= len(os.Args) {
+				println("Error: --infer requires a format")
+				os.Exit(1)
+			}
+			i++
+			flagInfer = os.Args[i]
+		case "--flatten":
+			flagFlatten = true
+		case "--unflatten":
+			flagUnflatten = true
+		case "--unordered":
+			flagUnordered = true
+		case "--warn-precision":
+			flagWarnPrecision = true
 		case "--raw", "-r":
 			flagRaw = true
 		case "--slurp", "-s":
@@ -121,6 +314,8 @@ func main() {
 			flagStrict = true
 		case "--no-inline":
 			flagNoInline = true
+		case "--print-config":
+			flagPrintConfig = true
 		case "--game-of-life":
 			utils.GameOfLife()
 			return
@@ -137,6 +332,73 @@ func main() {
 		println("Error: can't use both --yaml and --toml flags together")
 		os.Exit(1)
 	}
+	if (flagCsv || flagTsv) && (flagYaml || flagToml || flagRaw) {
+		println("Error: can't use --csv/--tsv with --yaml, --toml or --raw flags")
+		os.Exit(1)
+	}
+	if flagXml && (flagYaml || flagToml || flagCsv || flagTsv || flagRaw) {
+		println("Error: can't use --xml with --yaml, --toml, --csv, --tsv or --raw flags")
+		os.Exit(1)
+	}
+	if flagTo != "" && !slices.Contains(engine.OutputFormats, flagTo) {
+		println("Error: --to must be one of: " + strings.Join(engine.OutputFormats, ", "))
+		os.Exit(1)
+	}
+	if flagReduce != nil && flagSlurp {
+		println("Error: can't use --reduce and --slurp flags together")
+		os.Exit(1)
+	}
+	if flagReduceEvery > 0 {
+		if flagReduce == nil {
+			println("Error: --reduce-every requires --reduce")
+			os.Exit(1)
+		}
+		flagReduce.Every = flagReduceEvery
+	}
+	if flagReduce != nil && flagParallel > 1 {
+		println("Error: can't use --reduce and --parallel flags together")
+		os.Exit(1)
+	}
+	if flagJSONPath != nil && (flagReduce != nil || flagParallel > 1) {
+		println("Error: can't use --jsonpath with --reduce or --parallel flags")
+		os.Exit(1)
+	}
+	if flagJQ != "" && (flagReduce != nil || flagJSONPath != nil) {
+		println("Error: can't use --jq with --reduce or --jsonpath flags")
+		os.Exit(1)
+	}
+	if flagPatch != "" && (flagReduce != nil || flagJSONPath != nil || flagJQ != "") {
+		println("Error: can't use --patch with --reduce, --jsonpath or --jq flags")
+		os.Exit(1)
+	}
+	if flagSchema != nil && (flagSlurp || flagReduce != nil || flagJSONPath != nil || flagJQ != "" || flagPatch != "") {
+		println("Error: can't use --schema with --slurp, --reduce, --jsonpath, --jq or --patch flags")
+		os.Exit(1)
+	}
+	if flagInfer != "" && !slices.Contains(inferFormats, flagInfer) {
+		println("Error: --infer must be one of: " + strings.Join(inferFormats, ", "))
+		os.Exit(1)
+	}
+	if flagInfer != "" && (flagReduce != nil || flagJSONPath != nil || flagJQ != "" || flagPatch != "" || flagSchema != nil || flagTo != "") {
+		println("Error: can't use --infer with --reduce, --jsonpath, --jq, --patch, --schema or --to flags")
+		os.Exit(1)
+	}
+	if flagFlatten && (flagTo != "" || flagInfer != "" || flagSchema != nil) {
+		println("Error: can't use --flatten with --to, --infer or --schema flags")
+		os.Exit(1)
+	}
+	if flagUnflatten && (flagYaml || flagToml || flagCsv || flagTsv || flagXml || flagRaw) {
+		println("Error: can't use --unflatten with --yaml, --toml, --csv, --tsv, --xml or --raw flags")
+		os.Exit(1)
+	}
+	if flagUnordered && flagParallel == 0 {
+		println("Error: --unordered requires --parallel")
+		os.Exit(1)
+	}
+	if utf8.RuneCountInString(flagDelimiter) > 1 {
+		println("Error: --delimiter must be a single character")
+		os.Exit(1)
+	}
 
 	if flagComp {
 		shell := flag.String("comp", "", "")
@@ -154,6 +416,33 @@ func main() {
 		return
 	}
 
+	cfg, err := config.Load()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "fx: %s\n", err)
+		os.Exit(1)
+	}
+	if flagTo != "" {
+		cfg.Output.Set(flagTo, "flag --to")
+	}
+	for format, set := range map[string]bool{"yaml": flagYaml, "toml": flagToml, "csv": flagCsv, "tsv": flagTsv, "xml": flagXml, "raw": flagRaw} {
+		if set {
+			cfg.Input.Set(format, "flag --"+format)
+		}
+	}
+	if flagPrintConfig {
+		_ = cfg.Print(os.Stdout)
+		return
+	}
+	if err := theme.Set(cfg.Theme.Value); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "fx: %s: %s\n", cfg.Theme.Source, err)
+		os.Exit(1)
+	}
+	ident.Set(cfg.Indent.Value)
+
+	if len(args) > 0 && args[0] == "diff" {
+		os.Exit(runDiff(args[1:], cfg))
+	}
+
 	fd := os.Stdin.Fd()
 	stdinIsTty := isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
 
@@ -168,19 +457,78 @@ func main() {
 		} else {
 			// $ fx file.json arg*
 			filePath := args[0]
-			src = open(filePath, &flagYaml, &flagToml)
+			hasInputFlag := flagYaml || flagToml || flagCsv || flagTsv || flagXml || flagRaw || flagUnflatten
+			src, engine.FileCompression = open(filePath, hasInputFlag, &flagYaml, &flagToml, &flagCsv, &flagTsv, &flagXml)
 			engine.FilePath = filePath
 			fileName = filepath.Base(filePath)
 			args = args[1:]
 		}
 	} else {
 		// cat file.json | fx arg*
-		src = os.Stdin
+		var err error
+		src, _, err = compress.Detect(os.Stdin)
+		if err != nil {
+			println(err.Error())
+			os.Exit(1)
+		}
+	}
+
+	if flagJSONPath != nil && len(args) > 0 {
+		println("Error: can't use --jsonpath with JS arguments")
+		os.Exit(1)
+	}
+	if flagJQ != "" && len(args) > 0 {
+		println("Error: can't use --jq with JS arguments")
+		os.Exit(1)
+	}
+	if flagSchema != nil && len(args) > 0 {
+		println("Error: can't use --schema with JS arguments")
+		os.Exit(1)
+	}
+	if flagInfer != "" && len(args) > 0 {
+		println("Error: can't use --infer with JS arguments")
+		os.Exit(1)
+	}
+	var patch *engine.Patch
+	if flagPatch != "" {
+		b, err := os.ReadFile(flagPatch)
+		if err != nil {
+			println("Error: " + err.Error())
+			os.Exit(1)
+		}
+		ops, err := ParseValue(string(b))
+		if err != nil {
+			println("Error: --patch " + flagPatch + ": " + err.Error())
+			os.Exit(1)
+		}
+		patch = &engine.Patch{File: flagPatch, Ops: ops}
+	}
+
+	if !flagYaml && !flagToml && !flagCsv && !flagTsv && !flagXml && !flagRaw && !flagUnflatten && formatOf(engine.FilePath) == "" {
+		switch cfg.Input.Value {
+		case "yaml":
+			flagYaml = true
+		case "toml":
+			flagToml = true
+		case "csv":
+			flagCsv = true
+		case "tsv":
+			flagTsv = true
+		case "xml":
+			flagXml = true
+		case "raw":
+			flagRaw = true
+		}
 	}
 
 	var parser engine.Parser
 
-	if flagYaml {
+	if flagUnflatten {
+		parser = NewFlatParser(src)
+		if fd := os.Stdout.Fd(); len(args) == 0 && !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+			args = []string{"."} // Print the rebuilt documents, as in fx --flatten | grep | fx --unflatten.
+		}
+	} else if flagYaml {
 		b, err := io.ReadAll(src)
 		if err != nil {
 			panic(err)
@@ -204,16 +552,57 @@ func main() {
 			return
 		}
 		parser = NewJsonParser(bytes.NewReader(jsonBytes), flagStrict)
+	} else if flagCsv || flagTsv {
+		opts := CsvOptions{
+			NoHeader:   flagNoHeader,
+			InferTypes: flagInferTypes,
+		}
+		if flagTsv {
+			opts.Comma = '\t'
+		}
+		if flagDelimiter != "" {
+			opts.Comma, _ = utf8.DecodeRuneInString(flagDelimiter)
+		}
+		parser = NewCsvParser(src, opts)
+	} else if flagXml {
+		parser = NewXmlParser(src)
 	} else if flagRaw {
 		parser = NewLineParser(src)
 	} else {
 		parser = NewJsonParser(src, flagStrict)
 	}
 
-	if len(args) > 0 || flagSlurp {
+	if flagWarnPrecision {
+		var warned sync.Map
+		PrecisionLoss = func(text string, f float64) {
+			if _, ok := warned.LoadOrStore(text, true); !ok {
+				_, _ = fmt.Fprintf(os.Stderr, "fx: warning: %s loses precision as %v\n", text, f)
+			}
+		}
+	}
+
+	if flagInfer != "" {
+		os.Exit(runInfer(parser, flagInfer, flagSlurp, os.Stdout))
+	}
+
+	if flagSchema != nil {
+		if fd := os.Stdout.Fd(); !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+			os.Exit(validateDocuments(parser, flagSchema, os.Stdout))
+		}
+	}
+
+	if len(args) > 0 || flagSlurp || flagReduce != nil || flagJSONPath != nil || flagJQ != "" || patch != nil || flagTo != "" || flagFlatten {
 		opts := engine.Options{
 			Slurp:      flagSlurp,
+			Reduce:     flagReduce,
+			Parallel:   flagParallel,
+			Unordered:  flagUnordered,
+			JSONPath:   flagJSONPath,
+			JQ:         flagJQ,
+			Patch:      patch,
+			Flatten:    flagFlatten,
 			WithInline: !flagNoInline,
+			Output:     cfg.Output.Value,
 			WriteOut:   func(s string) { fmt.Println(s) },
 			WriteErr:   func(s string) { fmt.Fprintln(os.Stderr, s) },
 		}
@@ -224,8 +613,35 @@ func main() {
 		return
 	}
 
-	commandInput := textinput.New()
-	commandInput.Prompt = ":"
+	format := "json"
+	switch {
+	case flagYaml:
+		format = "yaml"
+	case flagToml:
+		format = "toml"
+	case flagCsv:
+		format = "csv"
+	case flagTsv:
+		format = "tsv"
+	case flagXml:
+		format = "xml"
+	case formatOf(engine.FilePath) == "ndjson":
+		format = "ndjson"
+	}
+
+	runViewer(parser, fileName, format, cfg, nil)
+}
+
+// runViewer shows the documents from parser in the interactive viewer. Nodes of
+// marks are shown as added, removed or changed by fx diff, and nodes violating
+// --schema are highlighted.
+func runViewer(parser engine.Parser, fileName, format string, cfg *config.Config, marks map[*Node]diff.Kind) {
+	if errs := loadKeyMap(&keyMap, cfg.Keys); len(errs) > 0 {
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(os.Stderr, "fx: %s\n", err)
+		}
+		os.Exit(1)
+	}
 
 	searchInput := textinput.New()
 	searchInput.Prompt = "/"
@@ -239,44 +655,32 @@ func main() {
 	spinnerModel := spinner.New()
 	spinnerModel.Spinner = spinner.MiniDot
 
-	collapsed := false
-	if _, ok := os.LookupEnv("FX_COLLAPSED"); ok {
-		collapsed = true
-	}
-
-	showLineNumbers := false
-	if _, ok := os.LookupEnv("FX_LINE_NUMBERS"); ok {
-		showLineNumbers = true
-	}
-
-	showSizes := false
-	showSizesValue, ok := os.LookupEnv("FX_SHOW_SIZE")
-	if ok {
-		showSizesValue := strings.ToLower(showSizesValue)
-		showSizes = showSizesValue == "true" || showSizesValue == "yes" || showSizesValue == "on" || showSizesValue == "1"
-	}
-
 	m := &model{
 		suspending:          false,
 		showCursor:          true,
 		wrap:                true,
-		collapsed:           collapsed,
-		showSizes:           showSizes,
-		showLineNumbers:     showLineNumbers,
+		collapsed:           cfg.Collapsed.Value,
+		showSizes:           cfg.ShowSizes.Value,
+		showLineNumbers:     cfg.LineNumbers.Value,
+		editor:              cfg.Editor.Value,
 		fileName:            fileName,
+		format:              format,
 		gotoSymbolInput:     gotoSymbolInput,
-		commandInput:        commandInput,
+		commandInput:        newCommandInput(),
 		searchInput:         searchInput,
 		search:              newSearch(),
 		previewSearchInput:  previewSearchInput,
 		previewSearchCursor: -1,
 		spinner:             spinnerModel,
+		diff:                marks,
+		schema:              flagSchema,
+		violationCursor:     -1,
 	}
 
 	lipgloss.SetColorProfile(theme.TermOutput.ColorProfile())
 
 	withMouse := tea.WithMouseCellMotion()
-	if _, ok := os.LookupEnv("FX_NO_MOUSE"); ok {
+	if !cfg.Mouse.Value {
 		withMouse = tea.WithAltScreen()
 	}
 
@@ -324,6 +728,16 @@ func main() {
 	} else {
 		exit()
 	}
+	if len(m.queryArgs) > 0 {
+		command := []string{"fx"}
+		if engine.FilePath != "" {
+			command = append(command, shellQuote(engine.FilePath))
+		}
+		for _, arg := range m.queryArgs {
+			command = append(command, shellQuote(arg))
+		}
+		_, _ = fmt.Fprintln(os.Stderr, strings.Join(command, " "))
+	}
 }
 
 type model struct {
@@ -340,8 +754,13 @@ type model struct {
 	showLineNumbers       bool
 	totalLines            int
 	fileName              string
+	format                string // Format to write the document back in.
+	editor                string // Editor command, see editorCommand.
+	message               string // Shown below the status bar until the next key press.
 	gotoSymbolInput       textinput.Model
 	commandInput          textinput.Model
+	commandHistory        []string
+	commandHistoryIndex   int // position in commandHistory while browsing it
 	searchInput           textinput.Model
 	search                *search
 	searching             bool          // search in progress
@@ -365,6 +784,17 @@ type model struct {
 	keysIndexNodes        []*Node
 	fuzzyMatch            *fuzzy.Match
 	deletePending         bool
+	edit                  edit
+	history               History
+	query                 *query
+	queryArgs             []string // Committed query expressions, printed on exit.
+	diff                  map[*Node]diff.Kind
+	schema                *schema.Schema
+	violations            []schema.Violation
+	violationMessages     map[*Node]string
+	violationDocs         map[*Node]int // Document numbers of violationMessages, from 1.
+	violationCursor       int
+	validatedDocs         int
 }
 
 type location struct {
@@ -424,6 +854,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			msg.node.CollapseRecursively()
 		}
 		m.totalLines = msg.node.Bottom().LineNumber
+		m.validate(msg.node)
 
 		if m.head == nil {
 			m.head = msg.node
@@ -445,7 +876,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case spinner.TickMsg:
-		if !m.eof || m.searching {
+		if !m.eof || m.searching || (m.query != nil && m.query.running) {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
@@ -471,6 +902,10 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.searchCancel = nil
 		return m, nil
 
+	case queryResultMsg:
+		m.handleQueryResult(msg)
+		return m, nil
+
 	case tea.ResumeMsg:
 		m.suspending = false
 		return m, nil
@@ -530,8 +965,15 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
+		m.message = ""
+		if m.edit.mode != editNone {
+			return m.handleEditKey(msg)
+		}
+		if m.query != nil {
+			return m.handleQueryKey(msg)
+		}
 		if m.commandInput.Focused() {
-			return m.handleGotoLineKey(msg)
+			return m.handleCommandKey(msg)
 		}
 		if m.searchInput.Focused() {
 			return m.handleSearchKey(msg)
@@ -562,26 +1004,6 @@ func (m *model) handleHelpKey(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *model) handleGotoLineKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-	switch {
-	case msg.Type == tea.KeyEscape:
-		m.commandInput.Blur()
-		m.commandInput.SetValue("")
-		m.showCursor = true
-
-	case msg.Type == tea.KeyEnter:
-		m.commandInput.Blur()
-		command := m.commandInput.Value()
-		m.commandInput.SetValue("")
-		return m.runCommand(command)
-
-	default:
-		m.commandInput, cmd = m.commandInput.Update(msg)
-	}
-	return m, cmd
-}
-
 func (m *model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch {
@@ -635,13 +1057,13 @@ func (m *model) handleGotoSymbolKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m *model) handleYankKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
-	case key.Matches(msg, yankPath):
+	case key.Matches(msg, keyMap.YankPath):
 		_ = clipboard.WriteAll(m.cursorPath())
-	case key.Matches(msg, yankKey):
+	case key.Matches(msg, keyMap.YankKey):
 		_ = clipboard.WriteAll(m.cursorKey())
-	case key.Matches(msg, yankValueY, yankValueV):
+	case key.Matches(msg, keyMap.YankValue):
 		_ = clipboard.WriteAll(m.cursorValue())
-	case key.Matches(msg, yankKeyValue):
+	case key.Matches(msg, keyMap.YankKeyValue):
 		k := m.cursorKey()
 		v := m.cursorValue()
 		keyValue := k + ": " + v
@@ -653,16 +1075,20 @@ func (m *model) handleYankKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m *model) handleShowSelectorKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
-	case key.Matches(msg, showSizes):
+	case key.Matches(msg, keyMap.ShowSizes):
 		m.showSizes = !m.showSizes
-	case key.Matches(msg, showLineNumbers):
-		m.showLineNumbers = !m.showLineNumbers
-		Wrap(m.top, m.viewWidth())
+	case key.Matches(msg, keyMap.ShowLineNumbers):
+		m.setLineNumbers(!m.showLineNumbers)
 	}
 	m.showShowSelector = false
 	return m, nil
 }
 
+func (m *model) setLineNumbers(show bool) {
+	m.showLineNumbers = show
+	Wrap(m.top, m.viewWidth())
+}
+
 func (m *model) handlePendingDelete(msg tea.Msg) {
 	// Handle potential 'dd' sequence for delete
 	if m.deletePending {
@@ -863,21 +1289,7 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 
 	case key.Matches(msg, keyMap.ToggleWrap):
-		at, ok := m.cursorPointsTo()
-		if !ok {
-			return m, nil
-		}
-		m.wrap = !m.wrap
-		if m.wrap {
-			Wrap(m.top, m.viewWidth())
-		} else {
-			DropWrapAll(m.top)
-		}
-		if at.Chunk != "" && at.Value == "" {
-			at = at.Parent
-		}
-		m.redoSearch()
-		m.selectNode(at)
+		m.setWrap(!m.wrap)
 
 	case key.Matches(msg, keyMap.ShowSelector):
 		m.showShowSelector = true
@@ -935,6 +1347,7 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.commandInput.CursorEnd()
 		m.commandInput.Width = m.termWidth - 2 // -1 for the prompt, -1 for the cursor
 		m.commandInput.Focus()
+		m.commandHistoryIndex = len(m.commandHistory)
 
 	case key.Matches(msg, keyMap.Search):
 		m.searchInput.CursorEnd()
@@ -942,11 +1355,19 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.searchInput.Focus()
 
 	case key.Matches(msg, keyMap.SearchNext):
-		m.selectSearchResult(m.search.cursor + 1)
+		if len(m.search.results) == 0 && len(m.violations) > 0 {
+			m.selectViolation(m.violationCursor + 1)
+		} else {
+			m.selectSearchResult(m.search.cursor + 1)
+		}
 		m.recordHistory()
 
 	case key.Matches(msg, keyMap.SearchPrev):
-		m.selectSearchResult(m.search.cursor - 1)
+		if len(m.search.results) == 0 && len(m.violations) > 0 {
+			m.selectViolation(m.violationCursor - 1)
+		} else {
+			m.selectSearchResult(m.search.cursor - 1)
+		}
 		m.recordHistory()
 
 	case key.Matches(msg, keyMap.GoBack):
@@ -976,6 +1397,31 @@ func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case key.Matches(msg, keyMap.Delete):
 		m.deletePending = true
+
+	case key.Matches(msg, keyMap.EditValue):
+		m.startEdit(editValue)
+
+	case key.Matches(msg, keyMap.RenameKey):
+		m.startEdit(editKey)
+
+	case key.Matches(msg, keyMap.Insert):
+		m.startEdit(insertValue)
+
+	case key.Matches(msg, keyMap.Duplicate):
+		m.duplicateAtCursor()
+
+	case key.Matches(msg, keyMap.Undo):
+		if n, ok := m.history.Undo(); ok {
+			m.edited(n)
+		}
+
+	case key.Matches(msg, keyMap.Redo):
+		if n, ok := m.history.Redo(); ok {
+			m.edited(n)
+		}
+
+	case key.Matches(msg, keyMap.Query):
+		return m, m.startQuery(".")
 	}
 	return m, nil
 }
@@ -1099,6 +1545,12 @@ func (m *model) prettyKey(node *Node, selected bool) []byte {
 	b := node.Key
 
 	style := theme.CurrentTheme.Key
+	if kind := m.diffKind(node); kind != diff.Equal {
+		style = diffColor(kind)
+	}
+	if _, ok := m.violation(node); ok {
+		style = theme.CurrentTheme.Error
+	}
 	if selected {
 		style = theme.CurrentTheme.Cursor
 	}
@@ -1140,6 +1592,10 @@ func (m *model) prettyPrint(node *Node, isSelected, isRef bool) string {
 
 	if isSelected {
 		style = theme.CurrentTheme.Cursor
+	} else if _, ok := m.violation(node); ok {
+		style = theme.CurrentTheme.Error
+	} else if kind := m.diffKind(node); kind == diff.Added || kind == diff.Removed {
+		style = diffColor(kind)
 	} else {
 		style = theme.Value(node.Kind)
 	}
@@ -1165,16 +1621,43 @@ func (m *model) prettyPrint(node *Node, isSelected, isRef bool) string {
 	}
 }
 
+func (m *model) setWrap(wrap bool) {
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return
+	}
+	m.wrap = wrap
+	if m.wrap {
+		Wrap(m.top, m.viewWidth())
+	} else {
+		DropWrapAll(m.top)
+	}
+	if at.Chunk != "" && at.Value == "" {
+		at = at.Parent
+	}
+	m.redoSearch()
+	m.selectNode(at)
+}
+
 func (m *model) viewWidth() int {
 	width := m.termWidth
 	if m.showLineNumbers {
 		width -= len(strconv.Itoa(m.totalLines))
 		width -= 2 // For margin between line numbers and JSON.
 	}
+	if m.diff != nil {
+		width -= 2 // For diff markers.
+	}
 	return width
 }
 
 func (m *model) viewHeight() int {
+	if m.edit.mode != editNone {
+		return m.termHeight - 2
+	}
+	if m.query != nil {
+		return m.termHeight - 2
+	}
 	if m.gotoSymbolInput.Focused() {
 		return m.termHeight - 2
 	}
@@ -1190,6 +1673,9 @@ func (m *model) viewHeight() int {
 	if m.showShowSelector {
 		return m.termHeight - 2
 	}
+	if m.message != "" {
+		return m.termHeight - 2
+	}
 	return m.termHeight - 1
 }
 
@@ -1443,18 +1929,11 @@ func (m *model) open() tea.Cmd {
 	if engine.FilePath == "" {
 		return nil
 	}
-	command := append(
-		strings.Split(lookup([]string{"FX_EDITOR", "EDITOR"}, "vim"), " "),
-		engine.FilePath,
-	)
-	if command[0] == "vi" || command[0] == "vim" || command[0] == "hx" {
-		at, ok := m.cursorPointsTo()
-		if ok {
-			tail := command[1:]
-			command = append([]string{command[0]}, fmt.Sprintf("+%d", at.LineNumber))
-			command = append(command, tail...)
-		}
+	line := 0
+	if at, ok := m.cursorPointsTo(); ok {
+		line = at.LineNumber
 	}
+	command := editorCommand(m.editor, engine.FilePath, line)
 	execCmd := exec.Command(command[0], command[1:]...)
 	return tea.ExecProcess(execCmd, func(err error) tea.Msg {
 		return nil
@@ -1467,7 +1946,8 @@ func (m *model) deleteAtCursor() {
 	if !ok || at == nil {
 		return
 	}
-	if next, ok := DeleteNode(at); ok {
+	if next, ok := m.history.DeleteNode(at); ok {
+		m.revalidate()
 		m.selectNode(next)
 		m.recordHistory()
 	}
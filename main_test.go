@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -115,3 +116,19 @@ func TestCollapseRecursiveWithSizes(t *testing.T) {
 	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
 	tm.WaitFinished(t, teatest.WithFinalTimeout(time.Second))
 }
+
+func TestOpen_FormatByExtension(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "data.csv")
+	require.NoError(t, os.WriteFile(filePath, []byte("a,b\n"), 0o644))
+
+	var isYaml, isToml, isCsv, isTsv, isXml bool
+	_, _ = open(filePath, false, &isYaml, &isToml, &isCsv, &isTsv, &isXml)
+	require.True(t, isCsv)
+
+	// An input format flag wins over the extension.
+	isCsv = false
+	isXml = true
+	_, _ = open(filePath, true, &isYaml, &isToml, &isCsv, &isTsv, &isXml)
+	require.False(t, isCsv)
+	require.True(t, isXml)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/antonmedv/fx/internal/engine"
+	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/theme"
+)
+
+// queryTimeout limits a single evaluation of the live query.
+const queryTimeout = 5 * time.Second
+
+// query is the live query mode: the result of the expression replaces
+// the document in the view until it is committed or cancelled.
+type query struct {
+	input             textinput.Model
+	id                uint64
+	cancel            context.CancelFunc
+	running           bool
+	commit            bool // Commit the first successful result, used by :filter.
+	err               error
+	docs              []*Node // Documents the expression is evaluated against.
+	top, head, bottom *Node   // View state of the original document, restored on cancel.
+	cursor            int
+	totalLines        int
+}
+
+type queryResultMsg struct {
+	id      uint64
+	results []*Node
+	err     error
+}
+
+func (m *model) startQuery(expr string) tea.Cmd {
+	if !m.eof {
+		m.message = "queries are available once the input is fully loaded"
+		return nil
+	}
+
+	input := textinput.New()
+	input.Prompt = "fx "
+	input.Width = m.termWidth - len(input.Prompt) - 1
+	input.SetValue(expr)
+	input.CursorEnd()
+	input.Focus()
+
+	var docs []*Node
+	for _, doc := range roots(m.top) {
+		if doc.Kind != Err {
+			docs = append(docs, doc)
+		}
+	}
+
+	m.query = &query{
+		input:      input,
+		docs:       docs,
+		top:        m.top,
+		head:       m.head,
+		bottom:     m.bottom,
+		cursor:     m.cursor,
+		totalLines: m.totalLines,
+	}
+	return m.runQuery()
+}
+
+func (m *model) runQuery() tea.Cmd {
+	q := m.query
+	if q.cancel != nil {
+		q.cancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	q.id++
+	q.cancel = cancel
+	q.running = true
+
+	id := q.id
+	docs := q.docs
+	var args []string
+	if expr := strings.TrimSpace(q.input.Value()); expr != "" {
+		args = []string{expr}
+	}
+
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		defer cancel()
+		results, err := engine.Query(ctx, docs, args)
+		return queryResultMsg{id: id, results: results, err: err}
+	})
+}
+
+func (m *model) handleQueryKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	q := m.query
+	switch msg.Type {
+	case tea.KeyEscape:
+		q.cancel()
+		m.top, m.head, m.bottom = q.top, q.head, q.bottom
+		m.cursor = q.cursor
+		m.totalLines = q.totalLines
+		m.query = nil
+
+	case tea.KeyEnter:
+		if !q.running && q.err == nil {
+			m.commitQuery()
+		}
+
+	case tea.KeyUp:
+		m.up()
+
+	case tea.KeyDown:
+		m.down()
+
+	default:
+		value := q.input.Value()
+		var cmd tea.Cmd
+		q.input, cmd = q.input.Update(msg)
+		if q.input.Value() != value {
+			return m, tea.Batch(cmd, m.runQuery())
+		}
+		return m, cmd
+	}
+	return m, nil
+}
+
+// commitQuery makes the result of the query the new document.
+func (m *model) commitQuery() {
+	m.query.cancel()
+	m.queryArgs = append(m.queryArgs, strings.TrimSpace(m.query.input.Value()))
+	m.query = nil
+	m.history = History{}
+	m.search = newSearch()
+	m.searchInput.SetValue("")
+	m.locationHistory = nil
+	m.locationIndex = 0
+	m.revalidate()
+	m.recordHistory()
+}
+
+func (m *model) handleQueryResult(msg queryResultMsg) {
+	q := m.query
+	if q == nil || msg.id != q.id {
+		return
+	}
+	q.running = false
+	q.err = msg.err
+	if msg.err != nil {
+		return
+	}
+	if len(msg.results) == 0 {
+		q.err = errors.New("no results")
+		return
+	}
+
+	top := msg.results[0]
+	for i := 1; i < len(msg.results); i++ {
+		msg.results[i].Index = -1 // Same as for streamed documents.
+		msg.results[i-1].Adjacent(msg.results[i])
+	}
+	if m.wrap {
+		Wrap(top, m.viewWidth())
+	}
+	if m.collapsed {
+		for _, n := range msg.results {
+			n.CollapseRecursively()
+		}
+	}
+
+	bottom := msg.results[len(msg.results)-1]
+	m.top, m.head, m.bottom = top, top, bottom
+	m.cursor = 0
+	m.totalLines = bottom.Bottom().LineNumber
+	if q.commit {
+		m.commitQuery()
+	}
+}
+
+func (m *model) queryView() string {
+	q := m.query
+	view := q.input.View()
+	var status string
+	if q.running {
+		status = m.spinner.View()
+	} else if q.err != nil {
+		// Syntax errors come with a snippet, the message is on the last line.
+		message := strings.TrimSpace(q.err.Error())
+		lines := strings.Split(message, "\n")
+		status = theme.CurrentTheme.Error(lines[len(lines)-1])
+	}
+	return view + "  " + status
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeQuery types keys into the query input and waits for the query to finish.
+func typeQuery(m *model, keys ...string) {
+	for _, k := range keys {
+		var cmd tea.Cmd
+		switch k {
+		case "backspace":
+			_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+		default:
+			_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		}
+		runQueryCmd(m, cmd)
+	}
+}
+
+func runQueryCmd(m *model, cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			runQueryCmd(m, c)
+		}
+	case queryResultMsg:
+		m.Update(msg)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	m := newEditModel(t, `{"items":[{"name":"a"},{"name":"b"}]}`)
+
+	typeQuery(m, ".")
+	require.NotNil(t, m.query)
+	assert.Equal(t, `{"items":[{"name":"a"},{"name":"b"}]}`, m.top.String())
+
+	typeQuery(m, "items[].name")
+	assert.NoError(t, m.query.err)
+	assert.Equal(t, `["a","b"]`, m.top.String())
+
+	send(m, "enter")
+	assert.Nil(t, m.query)
+	assert.Equal(t, []string{".items[].name"}, m.queryArgs)
+	assert.Equal(t, `["a","b"]`, m.top.String())
+}
+
+func TestQuery_Error(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	typeQuery(m, ".", "a", "(")
+	require.NotNil(t, m.query)
+	assert.Error(t, m.query.err)
+	assert.Equal(t, `1`, m.top.String(), "keeps the last result")
+
+	send(m, "enter")
+	assert.NotNil(t, m.query, "can't commit an error")
+
+	typeQuery(m, "backspace")
+	assert.NoError(t, m.query.err)
+	assert.Equal(t, `1`, m.top.String())
+}
+
+func TestQuery_Cancel(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	typeQuery(m, ".", "a")
+	assert.Equal(t, `1`, m.top.String())
+
+	send(m, "esc")
+	assert.Nil(t, m.query)
+	assert.Empty(t, m.queryArgs)
+	assert.Equal(t, `{"a":1}`, m.top.String())
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "file.json", shellQuote("file.json"))
+	assert.Equal(t, "'.items[].name'", shellQuote(".items[].name"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+	assert.Equal(t, "''", shellQuote(""))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonpath"
+	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/schema"
+)
+
+// loadSchema compiles the schema of --schema.
+func loadSchema(filePath string) (*schema.Schema, error) {
+	b, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return schema.Compile(b)
+}
+
+// validateDocuments prints violations of all documents as "path: message" and
+// returns 1 if there are any. With several documents, lines start with
+// "doc N: ", where N counts documents from 1.
+func validateDocuments(parser engine.Parser, s *schema.Schema, w io.Writer) int {
+	exitCode := 0
+	several := false
+	node, err := parser.Parse()
+	for doc := 1; ; doc++ {
+		if err == io.EOF {
+			return exitCode
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(w, err.Error())
+			return 2
+		}
+		// Read ahead to know if there are several documents.
+		next, nextErr := parser.Parse()
+		several = several || nextErr != io.EOF
+		for _, v := range s.Validate(node) {
+			line := violationLine(v)
+			if several {
+				line = fmt.Sprintf("doc %d: %s", doc, line)
+			}
+			_, _ = fmt.Fprintln(w, line)
+			exitCode = 1
+		}
+		node, err = next, nextErr
+	}
+}
+
+func violationLine(v schema.Violation) string {
+	path := jsonpath.Join(v.Path)
+	if path == "" {
+		path = "."
+	}
+	return path + ": " + v.Message
+}
+
+// validate records violations of a document arrived in the viewer.
+func (m *model) validate(doc *Node) {
+	if m.schema == nil {
+		return
+	}
+	m.validatedDocs++
+	for _, v := range m.schema.Validate(doc) {
+		m.violations = append(m.violations, v)
+		if m.violationMessages == nil {
+			m.violationMessages = map[*Node]string{}
+			m.violationDocs = map[*Node]int{}
+		}
+		m.violationDocs[v.Node] = m.validatedDocs
+		if message, ok := m.violationMessages[v.Node]; ok {
+			m.violationMessages[v.Node] = message + "; " + v.Message
+		} else {
+			m.violationMessages[v.Node] = v.Message
+		}
+	}
+}
+
+// revalidate recomputes violations of all documents after they changed,
+// e.g. by an edit, undo or a committed query.
+func (m *model) revalidate() {
+	if m.schema == nil {
+		return
+	}
+	m.violations = nil
+	m.violationMessages = nil
+	m.violationDocs = nil
+	m.validatedDocs = 0
+	m.violationCursor = -1
+	for _, doc := range roots(m.top) {
+		m.validate(doc)
+	}
+}
+
+// violation returns the violation messages of a node, including of the string
+// a wrapped chunk belongs to and of the container a closing bracket belongs to.
+func (m *model) violation(n *Node) (string, bool) {
+	if m.violationMessages == nil || n == nil {
+		return "", false
+	}
+	message, ok := m.violationMessages[violationNode(n)]
+	return message, ok
+}
+
+func violationNode(n *Node) *Node {
+	if n.Chunk != "" && n.Value == "" || n.Parent != nil && n.Parent.End == n {
+		return n.Parent
+	}
+	return n
+}
+
+func (m *model) selectViolation(i int) {
+	if len(m.violations) == 0 {
+		return
+	}
+	if i < 0 {
+		i = len(m.violations) - 1
+	}
+	if i >= len(m.violations) {
+		i = 0
+	}
+	m.violationCursor = i
+	m.selectNode(m.violations[i].Node)
+}
+
+// statusBarPath returns the cursor path, with the violation message if the cursor
+// is on an invalid node, and the document number if there are several documents.
+func (m *model) statusBarPath() string {
+	path := m.cursorPath()
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return path
+	}
+	message, ok := m.violation(at)
+	if !ok {
+		return path
+	}
+	if path != "" {
+		message = path + ": " + message
+	}
+	if m.top != m.bottom {
+		message = fmt.Sprintf("doc %d: %s", m.violationDocs[violationNode(at)], message)
+	}
+	return message
+}
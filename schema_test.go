@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/schema"
+)
+
+const testSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string"},
+    "tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+  },
+  "required": ["version"],
+  "$defs": {"tag": {"type": "string", "maxLength": 4}}
+}`
+
+func TestSchemaView(t *testing.T) {
+	s, err := schema.Compile([]byte(testSchema))
+	require.NoError(t, err)
+	doc, err := jsonx.Parse([]byte(`{"name": 1, "tags": ["json", "terminal"]}`))
+	require.NoError(t, err)
+
+	m := &model{
+		top:             doc,
+		head:            doc,
+		bottom:          doc,
+		totalLines:      doc.Bottom().LineNumber,
+		eof:             true,
+		wrap:            true,
+		showCursor:      true,
+		searchInput:     textinput.New(),
+		search:          newSearch(),
+		commandInput:    textinput.New(),
+		schema:          s,
+		violationCursor: -1,
+	}
+	m.validate(doc)
+	require.Len(t, m.violations, 3)
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(80, 20))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	teatest.WaitFor(t, tm.Output(), func(b []byte) bool {
+		return bytes.Contains(b, []byte(`.tags[1]: must be at most 4 characters long`))
+	}, teatest.WithDuration(time.Second))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("N")})
+	teatest.WaitFor(t, tm.Output(), func(b []byte) bool {
+		return bytes.Contains(b, []byte(`.name: expected string, got number`))
+	}, teatest.WithDuration(time.Second))
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	tm.WaitFinished(t, teatest.WithFinalTimeout(time.Second))
+}
+
+func TestValidateDocuments(t *testing.T) {
+	s, err := schema.Compile([]byte(testSchema))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	parser := jsonx.NewJsonParser(strings.NewReader(`{"version": 1} {"tags": ["json", "terminal"]}`), false)
+	assert.Equal(t, 1, validateDocuments(parser, s, &out))
+	assert.Equal(t, `doc 2: .: missing required property "version"
+doc 2: .tags[1]: must be at most 4 characters long
+`, out.String())
+
+	out.Reset()
+	parser = jsonx.NewJsonParser(strings.NewReader(`{"name": 1} {"version": 1} {"version": 2, "name": 3}`), false)
+	assert.Equal(t, 1, validateDocuments(parser, s, &out))
+	assert.Equal(t, `doc 1: .: missing required property "version"
+doc 1: .name: expected string, got number
+doc 3: .name: expected string, got number
+`, out.String())
+
+	out.Reset()
+	parser = jsonx.NewJsonParser(strings.NewReader(`{"tags": ["terminal"]}`), false)
+	assert.Equal(t, 1, validateDocuments(parser, s, &out))
+	assert.Equal(t, `.: missing required property "version"
+.tags[0]: must be at most 4 characters long
+`, out.String())
+
+	out.Reset()
+	parser = jsonx.NewJsonParser(strings.NewReader(`{"version": 1}`), false)
+	assert.Equal(t, 0, validateDocuments(parser, s, &out))
+	assert.Empty(t, out.String())
+}
+
+func TestSchemaView_Edit(t *testing.T) {
+	s, err := schema.Compile([]byte(testSchema))
+	require.NoError(t, err)
+	m := newEditModel(t, `{"name": 1, "tags": ["json", "terminal"]}`)
+	m.schema = s
+	m.violationCursor = -1
+	m.validate(m.top)
+	require.Len(t, m.violations, 3)
+
+	send(m, "down", "i", "ctrl+u", `"fx"`, "enter")
+	require.Len(t, m.violations, 2)
+	send(m, "n", "n")
+	assert.Equal(t, ".tags[1]: must be at most 4 characters long", m.statusBarPath())
+
+	send(m, "u")
+	require.Len(t, m.violations, 3)
+	send(m, "n", "n")
+	assert.Equal(t, ".name: expected string, got number", m.statusBarPath())
+	at, ok := m.cursorPointsTo()
+	require.True(t, ok)
+	assert.Same(t, m.top.Next, at)
+}
+
+func TestSchemaView_Documents(t *testing.T) {
+	s, err := schema.Compile([]byte(testSchema))
+	require.NoError(t, err)
+	m := newEditModel(t, `{"version": 1}`)
+	m.schema = s
+	m.violationCursor = -1
+	m.validate(m.top)
+	require.Empty(t, m.violations)
+
+	doc, err := jsonx.Parse([]byte(`{"version": 2, "name": 1}`))
+	require.NoError(t, err)
+	m.Update(nodeMsg{node: doc})
+	require.Len(t, m.violations, 1)
+
+	send(m, "n")
+	assert.Equal(t, "doc 2: .name: expected string, got number", m.statusBarPath())
+}
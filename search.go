@@ -2,10 +2,12 @@ package main
 
 import (
 	"regexp"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/rfc9535"
 )
 
 func (m *model) doSearch(s string) tea.Cmd {
@@ -108,7 +110,12 @@ type piece struct {
 
 // executeSearch performs the core search logic and returns the results.
 // It can be cancelled via the cancel channel (pass nil for non-cancellable search).
+// Queries starting with $ are JSONPath, as a regexp can't match anything before the end.
 func executeSearch(top *Node, s string, cancel <-chan struct{}) (*search, error) {
+	if strings.HasPrefix(s, "$") {
+		return executePathSearch(top, s)
+	}
+
 	code, ci := regexCase(s)
 	if ci {
 		code = "(?i)" + code
@@ -185,6 +192,37 @@ func executeSearch(top *Node, s string, cancel <-chan struct{}) (*search, error)
 	return result, nil
 }
 
+// executePathSearch finds the nodes a JSONPath query selects in each document,
+// highlighting their keys, or their values if they have no key.
+func executePathSearch(top *Node, s string) (*search, error) {
+	q, err := rfc9535.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newSearch()
+	for _, doc := range roots(top) {
+		for _, n := range q.Select(doc) {
+			index := len(result.results)
+			result.results = append(result.results, n)
+			switch {
+			case n.Key != "":
+				result.keys[n] = append(result.keys[n], match{start: 0, end: len(n.Key), index: index})
+			case n.Chunk != "":
+				for it := n; it != nil; it = it.Next {
+					result.values[it] = append(result.values[it], match{start: 0, end: len(it.Chunk), index: index})
+					if it == n.ChunkEnd || n.ChunkEnd == nil {
+						break
+					}
+				}
+			default:
+				result.values[n] = append(result.values[n], match{start: 0, end: len(n.Value), index: index})
+			}
+		}
+	}
+	return result, nil
+}
+
 func splitByIndexes(s string, indexes []match) []piece {
 	out := make([]piece, 0, 1)
 	pos := 0
@@ -710,3 +710,38 @@ func TestSearchRegexAcrossChunks(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONPathSearch(t *testing.T) {
+	head, err := Parse([]byte(`{"books": [{"title": "a long title that wraps", "price": 8}, {"title": "b", "price": 12}], "tags": ["x", "y"]}`))
+	require.NoError(t, err)
+	second, err := Parse([]byte(`{"books": [{"title": "c", "price": 5}]}`))
+	require.NoError(t, err)
+	head.Adjacent(second)
+	Wrap(head, 10)
+
+	m := &model{
+		top:       head,
+		head:      head,
+		search:    newSearch(),
+		wrap:      true,
+		termWidth: 10,
+	}
+
+	doSearch(m, `$.books[?@.price < 10].title`)
+	require.Nil(t, m.search.err)
+	require.Len(t, m.search.results, 2)
+	assert.Equal(t, `"title"`, m.search.results[0].Key)
+	assert.Equal(t, []match{{start: 0, end: len(`"title"`), index: 0}}, m.search.keys[m.search.results[0]])
+	assert.Equal(t, second.Next.Next.Next, m.search.results[1])
+
+	doSearch(m, `$.tags[1]`)
+	require.Len(t, m.search.results, 1)
+	assert.Equal(t, `"y"`, m.search.results[0].Value)
+	assert.Equal(t, []match{{start: 0, end: 3, index: 0}}, m.search.values[m.search.results[0]])
+
+	doSearch(m, `$..books[0].title`)
+	require.Len(t, m.search.results, 2)
+
+	doSearch(m, `$.books[`)
+	assert.Error(t, m.search.err)
+}
@@ -3,31 +3,43 @@ package main
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path"
-	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/goccy/go-yaml"
 
+	"github.com/antonmedv/fx/internal/compress"
 	"github.com/antonmedv/fx/internal/jsonpath"
 	"github.com/antonmedv/fx/internal/jsonx"
 )
 
-func lookup(names []string, defaultEditor string) string {
-	for _, name := range names {
-		env, ok := os.LookupEnv(name)
-		if ok && env != "" {
-			return env
+// editorCommand builds the command to open file at line (0 if unknown).
+// The editor may be a template with {file} and {line} placeholders,
+// otherwise the file is appended and vi, vim and hx get +line.
+func editorCommand(editor, file string, line int) []string {
+	command := strings.Fields(editor)
+	if strings.Contains(editor, "{file}") {
+		r := strings.NewReplacer("{file}", file, "{line}", strconv.Itoa(max(line, 1)))
+		for i, arg := range command {
+			command[i] = r.Replace(arg)
 		}
+		return command
 	}
-	return defaultEditor
+	if line > 0 && (command[0] == "vi" || command[0] == "vim" || command[0] == "hx") {
+		command = slices.Insert(command, 1, fmt.Sprintf("+%d", line))
+	}
+	return append(command, file)
 }
 
-func open(filePath string, flagYaml, flagToml *bool) *os.File {
+// open opens a file, decompressing it if needed. Unless hasInputFlag, the input
+// format is set by the file extension.
+func open(filePath string, hasInputFlag bool, flagYaml, flagToml, flagCsv, flagTsv, flagXml *bool) (io.Reader, compress.Kind) {
 	f, err := os.Open(filePath)
 	if err != nil {
 		var pathError *fs.PathError
@@ -38,16 +50,74 @@ func open(filePath string, flagYaml, flagToml *bool) *os.File {
 			panic(err)
 		}
 	}
-	fileName := path.Base(filePath)
-	hasYamlExt, _ := regexp.MatchString(`(?i)\.ya?ml$`, fileName)
-	hasTomlExt, _ := regexp.MatchString(`(?i)\.toml$`, fileName)
-	if !*flagYaml && hasYamlExt {
-		*flagYaml = true
+	src, kind, err := compress.Detect(f)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	if hasInputFlag {
+		return src, kind
 	}
-	if !*flagToml && hasTomlExt {
+	switch formatOf(filePath) {
+	case "yaml":
+		*flagYaml = true
+	case "toml":
 		*flagToml = true
+	case "csv":
+		*flagCsv = true
+	case "tsv":
+		*flagTsv = true
+	case "xml":
+		*flagXml = true
+	}
+	return src, kind
+}
+
+// formatOf returns the data format of a file by its extension, or "" if unknown.
+func formatOf(filePath string) string {
+	fileName := compress.TrimExt(path.Base(filePath))
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".json", ".json5":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	case ".csv":
+		return "csv"
+	case ".tsv":
+		return "tsv"
+	case ".xml":
+		return "xml"
+	}
+	return ""
+}
+
+// roots returns the top-level documents starting from top.
+func roots(top *jsonx.Node) []*jsonx.Node {
+	var docs []*jsonx.Node
+	for it := top; it != nil; {
+		docs = append(docs, it)
+		switch {
+		case it.End != nil:
+			it = it.End.Next
+		case it.ChunkEnd != nil:
+			it = it.ChunkEnd.Next
+		default:
+			it = it.Next
+		}
+	}
+	return docs
+}
+
+// shellQuote quotes s for a POSIX shell, leaving simple words as is.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=/.,:@%") == "" {
+		return s
 	}
-	return f
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func regexCase(code string) (string, bool) {
@@ -52,6 +52,10 @@ func (m *model) View() string {
 			screen = append(screen, ' ', ' ')
 		}
 
+		if m.diff != nil {
+			screen = append(screen, m.diffMarker(n)...)
+		}
+
 		for i := 0; i < int(n.Depth); i++ {
 			screen = append(screen, ident.IdentBytes...)
 		}
@@ -131,8 +135,8 @@ func (m *model) View() string {
 			screen = append(screen, theme.CurrentTheme.Size(fmt.Sprintf(" (%d %s)", n.Size, w))...)
 		}
 
-		if isRefSelected {
-			screen = append(screen, theme.CurrentTheme.Preview("  ctrl+g goto")...)
+		if isRefSelected && keyMap.GotoRef.Enabled() {
+			screen = append(screen, theme.CurrentTheme.Preview("  "+keyMap.GotoRef.Keys()[0]+" goto")...)
 		}
 
 		screen = append(screen, '\n')
@@ -177,16 +181,22 @@ func (m *model) View() string {
 		}
 
 		info := fmt.Sprintf("%s %s", indicator, m.fileName)
-		statusBar := flex(statusBarWidth, m.cursorPath(), info)
+		statusBar := flex(statusBarWidth, m.statusBarPath(), info)
 		screen = append(screen, theme.CurrentTheme.StatusBar(statusBar)...)
 	}
 
-	if m.yank {
+	if m.edit.mode != editNone {
+		screen = append(screen, '\n')
+		screen = append(screen, m.editView()...)
+	} else if m.query != nil {
 		screen = append(screen, '\n')
-		screen = append(screen, []byte("(y)value  (p)path  (k)key  (b)key+value")...)
+		screen = append(screen, m.queryView()...)
+	} else if m.yank {
+		screen = append(screen, '\n')
+		screen = append(screen, menu(keyMap.YankValue, keyMap.YankPath, keyMap.YankKey, keyMap.YankKeyValue)...)
 	} else if m.showShowSelector {
 		screen = append(screen, '\n')
-		screen = append(screen, []byte("(s)sizes  (l)line numbers")...)
+		screen = append(screen, menu(keyMap.ShowSizes, keyMap.ShowLineNumbers)...)
 	} else if m.gotoSymbolInput.Focused() {
 		screen = append(screen, '\n')
 		screen = append(screen, m.gotoSymbolInput.View()...)
@@ -214,6 +224,9 @@ func (m *model) View() string {
 			cursor := fmt.Sprintf("found: [%v/%v]", m.search.cursor+1, len(m.search.results))
 			screen = append(screen, flex(m.termWidth, re, cursor)...)
 		}
+	} else if m.message != "" {
+		screen = append(screen, '\n')
+		screen = append(screen, m.message...)
 	}
 
 	return string(screen)
@@ -1,24 +1,373 @@
 package main
 
 import (
+	"fmt"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
+	"github.com/antonmedv/clipboard"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/antonmedv/fx/internal/compress"
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonpath"
 	. "github.com/antonmedv/fx/internal/jsonx"
+	"github.com/antonmedv/fx/internal/theme"
+	"github.com/antonmedv/fx/internal/utils"
 )
 
+// command describes a command of the command line.
+type command struct {
+	name  string
+	usage string
+	help  string
+	args  []string // Completions of the argument.
+}
+
+var setOptions = []string{"wrap", "nowrap", "sizes", "nosizes", "linenumbers", "nolinenumbers"}
+
+var commands = []command{
+	{name: "path", usage: "path <path>", help: "jump to a path like .a.b[3]"},
+	{name: "set", usage: "set wrap|nowrap|sizes|nosizes|linenumbers|nolinenumbers|theme=<name>", help: "change view options", args: setArgs()},
+	{name: "collapse", usage: "collapse <depth>", help: "collapse all documents below the depth"},
+	{name: "sort", usage: "sort keys", help: "sort keys of all objects", args: []string{"keys"}},
+	{name: "filter", usage: "filter <js>", help: "replace the document with the result of an expression"},
+	{name: "yank", usage: "yank [" + strings.Join(engine.OutputFormats, "|") + "]", help: "copy the value under the cursor", args: engine.OutputFormats},
+	{name: "w", usage: "w[!] [file]", help: "write the document"},
+	{name: "wq", usage: "wq[!] [file]", help: "write the document and quit"},
+	{name: "x", usage: "x[!] [file]", help: "write the document and quit"},
+	{name: "saveas", usage: "saveas[!] <file>", help: "write the document to a new file and switch to it"},
+	{name: "q", usage: "q", help: "quit"},
+	{name: "help", usage: "help [command]", help: "show help for a command"},
+}
+
+func setArgs() []string {
+	args := slices.Clone(setOptions)
+	for _, name := range theme.Names() {
+		args = append(args, "theme="+name)
+	}
+	return args
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func newCommandInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.ShowSuggestions = true
+	// Up and down browse the command history.
+	input.KeyMap.NextSuggestion = key.NewBinding(key.WithKeys("ctrl+n"))
+	input.KeyMap.PrevSuggestion = key.NewBinding(key.WithKeys("ctrl+p"))
+	var suggestions []string
+	for _, c := range commands {
+		suggestions = append(suggestions, c.name)
+		for _, arg := range c.args {
+			suggestions = append(suggestions, c.name+" "+arg)
+		}
+	}
+	for _, c := range commands {
+		suggestions = append(suggestions, "help "+c.name)
+	}
+	input.SetSuggestions(suggestions)
+	return input
+}
+
+func (m *model) handleCommandKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch {
+	case msg.Type == tea.KeyEscape:
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+		m.showCursor = true
+
+	case msg.Type == tea.KeyEnter:
+		m.commandInput.Blur()
+		command := m.commandInput.Value()
+		m.commandInput.SetValue("")
+		if strings.TrimSpace(command) != "" {
+			if len(m.commandHistory) == 0 || m.commandHistory[len(m.commandHistory)-1] != command {
+				m.commandHistory = append(m.commandHistory, command)
+			}
+		}
+		return m.runCommand(command)
+
+	case msg.Type == tea.KeyUp:
+		if m.commandHistoryIndex > 0 {
+			m.commandHistoryIndex--
+			m.commandInput.SetValue(m.commandHistory[m.commandHistoryIndex])
+			m.commandInput.CursorEnd()
+		}
+
+	case msg.Type == tea.KeyDown:
+		if m.commandHistoryIndex < len(m.commandHistory) {
+			m.commandHistoryIndex++
+			if m.commandHistoryIndex == len(m.commandHistory) {
+				m.commandInput.SetValue("")
+			} else {
+				m.commandInput.SetValue(m.commandHistory[m.commandHistoryIndex])
+			}
+			m.commandInput.CursorEnd()
+		}
+
+	default:
+		m.commandInput, cmd = m.commandInput.Update(msg)
+	}
+	return m, cmd
+}
+
 func (m *model) runCommand(s string) (tea.Model, tea.Cmd) {
 	num, err := strconv.Atoi(s)
 	if err == nil {
 		gotoLine(m, num)
 		return m, nil
-	} else if s == "q" {
+	}
+
+	name, arg, _ := strings.Cut(strings.TrimSpace(s), " ")
+	arg = strings.TrimSpace(arg)
+	force := strings.HasSuffix(name, "!")
+	name = strings.TrimSuffix(name, "!")
+
+	switch name {
+	case "":
+
+	case "q":
 		return m, tea.Quit
+
+	case "w":
+		m.write(arg, force)
+
+	case "wq", "x":
+		if m.write(arg, force) {
+			return m, tea.Quit
+		}
+
+	case "saveas":
+		if arg == "" {
+			m.message = "saveas: file name is required"
+		} else if m.write(arg, force) {
+			engine.FilePath = arg
+			engine.FileCompression = compress.ByExt(arg)
+			if format := formatOf(arg); format != "" {
+				m.format = format
+			}
+			m.fileName = filepath.Base(arg)
+		}
+
+	case "path":
+		path, ok := jsonpath.Split(arg)
+		if !ok {
+			m.message = fmt.Sprintf("path: invalid path %q", arg)
+			return m, nil
+		}
+		n := m.findByPath(path)
+		if n == nil {
+			m.message = fmt.Sprintf("path: %s not found", arg)
+			return m, nil
+		}
+		m.selectNode(n)
+		m.recordHistory()
+
+	case "set":
+		for _, option := range strings.Fields(arg) {
+			if err := m.set(option); err != nil {
+				m.message = "set: " + err.Error()
+				return m, nil
+			}
+		}
+
+	case "collapse":
+		depth, err := strconv.Atoi(arg)
+		if err != nil || depth < 0 {
+			m.message = "collapse: depth must be a non-negative number"
+			return m, nil
+		}
+		m.collapseToDepth(depth)
+
+	case "sort":
+		if arg != "keys" {
+			m.message = "usage: sort keys"
+			return m, nil
+		}
+		var docs []*Node
+		for _, doc := range roots(m.top) {
+			if doc.Kind != Err {
+				docs = append(docs, doc)
+			}
+		}
+		m.history.SortKeys(docs...)
+		m.revalidate()
+		if at, ok := m.cursorPointsTo(); ok {
+			m.selectNode(at)
+		}
+
+	case "filter":
+		if arg == "" {
+			m.message = "filter: expression is required"
+			return m, nil
+		}
+		if cmd := m.startQuery(arg); cmd != nil {
+			m.query.commit = true
+			return m, cmd
+		}
+
+	case "yank":
+		m.yankAs(arg)
+
+	case "help":
+		if arg == "" {
+			var names []string
+			for _, c := range commands {
+				names = append(names, c.name)
+			}
+			m.message = "commands: " + strings.Join(names, ", ") + ", <line>"
+		} else if c, ok := findCommand(arg); ok {
+			m.message = fmt.Sprintf(":%s  %s", c.usage, c.help)
+		} else {
+			m.message = fmt.Sprintf("help: unknown command %q", arg)
+		}
+
+	default:
+		m.message = fmt.Sprintf("unknown command %q, see :help", name)
 	}
 	return m, nil
 }
 
+func (m *model) set(option string) error {
+	if name, ok := strings.CutPrefix(option, "theme="); ok {
+		return theme.Set(name)
+	}
+	switch option {
+	case "wrap", "nowrap":
+		m.setWrap(option == "wrap")
+	case "sizes", "nosizes":
+		m.showSizes = option == "sizes"
+	case "linenumbers", "nolinenumbers":
+		m.setLineNumbers(option == "linenumbers")
+	default:
+		return fmt.Errorf("unknown option %q", option)
+	}
+	return nil
+}
+
+// collapseToDepth collapses all documents, leaving depth levels expanded.
+func (m *model) collapseToDepth(depth int) {
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return
+	}
+	for _, doc := range roots(m.top) {
+		if doc.Kind == Err {
+			continue
+		}
+		doc.CollapseRecursively()
+		if depth == 0 {
+			doc.Collapse()
+		} else {
+			doc.ExpandRecursively(0, depth)
+		}
+	}
+	// Select the outermost collapsed node hiding the cursor.
+	for p := at.Parent; p != nil; p = p.Parent {
+		if p.IsCollapsed() {
+			at = p
+		}
+	}
+	m.head = at
+	m.cursor = 0
+	m.selectNode(at)
+	m.recordHistory()
+}
+
+func (m *model) yankAs(format string) {
+	if format == "" {
+		format = "json"
+	}
+	if !slices.Contains(engine.OutputFormats, format) {
+		m.message = fmt.Sprintf("yank: unknown format %q", format)
+		return
+	}
+	at, ok := m.cursorPointsTo()
+	if !ok {
+		return
+	}
+	if at.IsWrap() || at.Parent != nil && at.Parent.End == at {
+		at = at.Parent
+	}
+	out, err := engine.Encode(format, []*Node{at})
+	if err != nil {
+		m.message = "yank: " + err.Error()
+		return
+	}
+	_ = clipboard.WriteAll(strings.TrimSuffix(out, "\n"))
+	m.message = "yanked as " + format
+}
+
+// write saves all documents to filePath, or to the opened file if filePath is empty.
+// Other files are written in the format and compression matching their extension.
+func (m *model) write(filePath string, force bool) bool {
+	format, kind := m.format, engine.FileCompression
+	if format == "" {
+		format = "json"
+	}
+	if filePath == "" {
+		filePath = engine.FilePath
+	} else if filePath != engine.FilePath {
+		if f := formatOf(filePath); f != "" {
+			format = f
+		}
+		kind = compress.ByExt(filePath)
+	}
+	if filePath == "" {
+		m.message = "no file name, use :w <file>"
+		return false
+	}
+
+	var docs []*Node
+	invalid := 0
+	for _, it := range roots(m.top) {
+		if it.Kind == Err {
+			invalid++
+			if format == "json" {
+				// Written as is with :w!, other formats can't hold them.
+				docs = append(docs, it)
+			}
+		} else {
+			docs = append(docs, it)
+		}
+	}
+	if invalid > 0 && !force {
+		m.message = fmt.Sprintf("document has %d invalid part(s), use :w! to write anyway", invalid)
+		return false
+	}
+
+	out, err := engine.Encode(format, docs)
+	if err != nil {
+		m.message = fmt.Sprintf("cannot write %s: %s", filePath, err)
+		return false
+	}
+	data, err := compress.Compress(kind, []byte(out))
+	if err == nil {
+		err = utils.WriteFileAtomic(filePath, data)
+	}
+	if err != nil {
+		m.message = fmt.Sprintf("cannot write %s: %s", filePath, err)
+		return false
+	}
+	m.message = fmt.Sprintf("%q %dB written", filePath, len(data))
+	return true
+}
+
 func gotoLine(m *model, num int) {
 	m.selectNode(findNode(m, num))
 
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -8,6 +10,11 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/exp/teatest"
 	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/antonmedv/fx/internal/engine"
+	"github.com/antonmedv/fx/internal/jsonx"
 )
 
 func init() {
@@ -111,3 +118,165 @@ func TestGotoLineKeepsHistory(t *testing.T) {
 	tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
 	tm.WaitFinished(t, teatest.WithFinalTimeout(time.Second))
 }
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"a":1,"b":2}`), 0644))
+
+	engine.FilePath = filePath
+	defer func() { engine.FilePath = "" }()
+
+	m := newEditModel(t, `{"a":1,"b":2}`)
+	m.format = "json"
+	send(m, "down", "d", "d", ":", "w", "enter")
+	assert.Contains(t, m.message, "written")
+
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"b\": 2\n}\n", string(b))
+
+	send(m, ":", "w "+filepath.Join(dir, "data.yaml"), "enter")
+	b, err = os.ReadFile(filepath.Join(dir, "data.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "b: 2\n", string(b))
+	assert.Equal(t, filePath, engine.FilePath)
+
+	send(m, ":", "saveas "+filepath.Join(dir, "new.toml"), "enter")
+	assert.Equal(t, filepath.Join(dir, "new.toml"), engine.FilePath)
+	assert.Equal(t, "toml", m.format)
+	assert.Equal(t, "new.toml", m.fileName)
+}
+
+func TestWrite_NoFileName(t *testing.T) {
+	m := newEditModel(t, `{}`)
+	_, cmd := m.runCommand("wq")
+	assert.Nil(t, cmd)
+	assert.Contains(t, m.message, "no file name")
+}
+
+func TestWrite_Invalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "data.json")
+
+	m := newEditModel(t, `{}`)
+	m.top.Adjacent(&jsonx.Node{Kind: jsonx.Err, Value: "oops", Index: -1})
+
+	m.runCommand("w " + filePath)
+	assert.Contains(t, m.message, "invalid")
+	assert.NoFileExists(t, filePath)
+
+	m.runCommand("w! " + filePath)
+	b, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\noops\n", string(b))
+}
+
+func TestCommand_Path(t *testing.T) {
+	m := newEditModel(t, `{"a":{"b":[1,2,{"c":3}]}}`)
+
+	send(m, ":", "path .a.b[2].c", "enter")
+	at, ok := m.cursorPointsTo()
+	require.True(t, ok)
+	assert.Equal(t, `"c"`, at.Key)
+
+	send(m, ":", "path .x", "enter")
+	assert.Contains(t, m.message, "not found")
+}
+
+func TestCommand_Set(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	send(m, ":", "set sizes linenumbers nowrap", "enter")
+	assert.True(t, m.showSizes)
+	assert.True(t, m.showLineNumbers)
+	assert.False(t, m.wrap)
+	assert.Empty(t, m.message)
+
+	send(m, ":", "set bogus", "enter")
+	assert.Contains(t, m.message, "unknown option")
+}
+
+func TestCommand_Collapse(t *testing.T) {
+	m := newEditModel(t, `{"a":{"b":{"c":1}},"d":[1]}`)
+
+	send(m, ":", "collapse 1", "enter")
+	assert.False(t, m.top.IsCollapsed())
+	assert.True(t, m.top.FindByPath([]any{"a"}).IsCollapsed())
+	assert.True(t, m.top.FindByPath([]any{"d"}).IsCollapsed())
+
+	send(m, ":", "collapse 0", "enter")
+	assert.True(t, m.top.IsCollapsed())
+}
+
+func TestCommand_SortKeys(t *testing.T) {
+	m := newEditModel(t, `{"b":1,"a":{"d":2,"c":3}}`)
+
+	send(m, ":", "sort keys", "enter")
+	assert.Equal(t, `{"a":{"c":3,"d":2},"b":1}`, m.top.String())
+
+	send(m, "u")
+	assert.Equal(t, `{"b":1,"a":{"d":2,"c":3}}`, m.top.String())
+}
+
+func TestCommand_SortKeys_Documents(t *testing.T) {
+	m := newEditModel(t, `{"b":1,"a":2}`)
+	for _, doc := range []string{`{"d":3,"c":4}`, `[{"f":5,"e":6}]`} {
+		node, err := jsonx.Parse([]byte(doc))
+		require.NoError(t, err)
+		m.Update(nodeMsg{node: node})
+	}
+	docs := func() []string {
+		var out []string
+		for _, doc := range roots(m.top) {
+			out = append(out, jsonx.Compact(doc))
+		}
+		return out
+	}
+
+	send(m, ":", "sort keys", "enter")
+	assert.Equal(t, []string{`{"a":2,"b":1}`, `{"c":4,"d":3}`, `[{"e":6,"f":5}]`}, docs())
+
+	send(m, "u")
+	assert.Equal(t, []string{`{"b":1,"a":2}`, `{"d":3,"c":4}`, `[{"f":5,"e":6}]`}, docs())
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	assert.Equal(t, []string{`{"a":2,"b":1}`, `{"c":4,"d":3}`, `[{"e":6,"f":5}]`}, docs())
+}
+
+func TestCommand_Filter(t *testing.T) {
+	m := newEditModel(t, `{"items":[1,2,3]}`)
+
+	_, cmd := m.runCommand("filter .items.filter(x => x > 1)")
+	runQueryCmd(m, cmd)
+	assert.Nil(t, m.query)
+	assert.Equal(t, `[2,3]`, m.top.String())
+	assert.Equal(t, []string{".items.filter(x => x > 1)"}, m.queryArgs)
+}
+
+func TestCommand_Unknown(t *testing.T) {
+	m := newEditModel(t, `{}`)
+
+	send(m, ":", "bogus", "enter")
+	assert.Contains(t, m.message, `unknown command "bogus"`)
+
+	send(m, ":", "help sort", "enter")
+	assert.Equal(t, ":sort keys  sort keys of all objects", m.message)
+}
+
+func TestCommand_CompletionAndHistory(t *testing.T) {
+	m := newEditModel(t, `{"a":1}`)
+
+	send(m, ":", "col", "tab")
+	assert.Equal(t, "collapse", m.commandInput.Value())
+	send(m, " 0", "enter")
+
+	send(m, ":", "set siz", "tab", "enter")
+	assert.True(t, m.showSizes)
+
+	send(m, ":", "up")
+	assert.Equal(t, "set sizes", m.commandInput.Value())
+	send(m, "up")
+	assert.Equal(t, "collapse 0", m.commandInput.Value())
+	send(m, "down", "down")
+	assert.Equal(t, "", m.commandInput.Value())
+}